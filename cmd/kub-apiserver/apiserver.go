@@ -20,6 +20,12 @@ func main() {
 	s := options.NewServerRunOptions()
 	s.AddFlags(pflag.CommandLine)
 
+	run := app.Run
+	if len(os.Args) > 1 && os.Args[1] == app.MigrateStorageCommand {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		run = app.RunStorageMigration
+	}
+
 	flag.InitFlags()
 	logs.InitLogs()
 	defer logs.FlushLogs()
@@ -27,7 +33,7 @@ func main() {
 	verflag.PrintAndExitIfRequested()
 
 	stopCh := server.SetupSignalHandler()
-	if err := app.Run(s, stopCh); err != nil {
+	if err := run(s, stopCh); err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
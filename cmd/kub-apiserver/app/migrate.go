@@ -0,0 +1,31 @@
+package app
+
+import (
+	"github.com/golang/glog"
+
+	genericapiserver "github.com/HuZhou/apiserver/pkg/server"
+	"github.com/mqshen/HuZhou/cmd/kub-apiserver/app/options"
+)
+
+// MigrateStorageCommand is the subcommand running a one-shot storage migration.
+const MigrateStorageCommand = "migrate-storage"
+
+// RunStorageMigration rewrites all stored objects with the current storage
+// version and encryption configuration, then returns.
+func RunStorageMigration(runOptions *options.ServerRunOptions, stopCh <-chan struct{}) error {
+	// the server is never started, so there is no need to run the migration after start
+	runOptions.EnableStorageMigration = false
+	kubeAPIServerConfig, _, err := CreateKubeAPIServerConfig(runOptions, nil, nil)
+	if err != nil {
+		return err
+	}
+	kubeAPIServer, err := CreateKubeAPIServer(kubeAPIServerConfig, genericapiserver.EmptyDelegate)
+	if err != nil {
+		return err
+	}
+
+	progress, err := kubeAPIServer.StorageMigrator().Run(stopCh)
+	glog.Infof("Storage migration: %d resources done, %d objects visited, %d migrated",
+		progress.Resources, progress.Visited, progress.Migrated)
+	return err
+}
@@ -6,22 +6,77 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
 	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/master"
+	"time"
 )
 
 type ServerRunOptions struct {
+	GenericServerRunOptions *genericoptions.ServerRunOptions
 	Etcd                    *genericoptions.EtcdOptions
 	InsecureServing         *kubeoptions.InsecureServingOptions
+	Authentication          *kubeoptions.BuiltInAuthenticationOptions
 	SSHUser                 string
+	EventTTL                time.Duration
+
+	EnableStorageMigration         bool
+	StorageMigrationCheckpointFile string
+
+	ConcurrentNamespaceSyncs int
+	NamespaceSyncPeriod      time.Duration
+
+	ServiceAccountSigningKeyFile       string
+	RootCAFile                         string
+	ConcurrentServiceAccountTokenSyncs int
+
+	ConcurrentGCSyncs int
 }
 
 func NewServerRunOptions() *ServerRunOptions {
 	s := ServerRunOptions{
+		GenericServerRunOptions: genericoptions.NewServerRunOptions(),
 		Etcd:                 genericoptions.NewEtcdOptions(storagebackend.NewDefaultConfig(kubeoptions.DefaultEtcdPathPrefix, api.Scheme, nil)),
 		InsecureServing:      kubeoptions.NewInsecureServingOptions(),
+		Authentication:       kubeoptions.NewBuiltInAuthenticationOptions().WithServiceAccounts(),
+		EventTTL:             master.DefaultEventTTL,
+
+		ConcurrentNamespaceSyncs: 10,
+		NamespaceSyncPeriod:      master.DefaultNamespaceSyncPeriod,
+
+		ConcurrentServiceAccountTokenSyncs: master.DefaultConcurrentServiceAccountTokenSyncs,
+
+		ConcurrentGCSyncs: master.DefaultConcurrentGCSyncs,
 	}
+	s.Etcd.StorageSerializer = api.Codecs
+	s.Etcd.StorageVersions = kubeoptions.DefaultStorageVersions()
+	s.Etcd.StorageScheme = api.Scheme
 	return &s
 }
 
 func (s *ServerRunOptions) AddFlags(fs *pflag.FlagSet) {
+	s.GenericServerRunOptions.AddUniversalFlags(fs)
+	s.Etcd.AddFlags(fs)
+	s.Authentication.AddFlags(fs)
+
+	fs.DurationVar(&s.EventTTL, "event-ttl", s.EventTTL,
+		"Amount of time to retain events.")
+
+	fs.BoolVar(&s.EnableStorageMigration, "storage-migration", s.EnableStorageMigration,
+		"If true, rewrite all stored objects with the current storage version and encryption key once the server started.")
+	fs.StringVar(&s.StorageMigrationCheckpointFile, "storage-migration-checkpoint-file", s.StorageMigrationCheckpointFile,
+		"File the storage migration records its progress in, so that an interrupted migration resumes where it stopped.")
+
+	fs.IntVar(&s.ConcurrentNamespaceSyncs, "concurrent-namespace-syncs", s.ConcurrentNamespaceSyncs,
+		"The number of namespace objects that are allowed to sync concurrently. Larger number = more responsive namespace termination, but more CPU (and network) load. Zero disables the namespace controller.")
+	fs.DurationVar(&s.NamespaceSyncPeriod, "namespace-sync-period", s.NamespaceSyncPeriod,
+		"The period for syncing namespace life-cycle updates.")
+
+	fs.StringVar(&s.ServiceAccountSigningKeyFile, "service-account-private-key-file", s.ServiceAccountSigningKeyFile,
+		"Filename containing a PEM-encoded private RSA or ECDSA key used to sign service account tokens. If unset, no service account token secrets are created.")
+	fs.StringVar(&s.RootCAFile, "root-ca-file", s.RootCAFile,
+		"If set, this root certificate authority will be included in service account's token secret. This must be a valid PEM-encoded CA bundle.")
+	fs.IntVar(&s.ConcurrentServiceAccountTokenSyncs, "concurrent-serviceaccount-token-syncs", s.ConcurrentServiceAccountTokenSyncs,
+		"The number of service account token objects that are allowed to sync concurrently. Larger number = more responsive token generation, but more CPU (and network) load.")
 
+	fs.IntVar(&s.ConcurrentGCSyncs, "concurrent-gc-syncs", s.ConcurrentGCSyncs,
+		"The number of garbage collector workers that are allowed to sync concurrently.")
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package options
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	genericoptions "github.com/HuZhou/apiserver/pkg/server/options"
+	"github.com/mqshen/HuZhou/pkg/api"
+	_ "github.com/mqshen/HuZhou/pkg/api/install"
+)
+
+// servedObjects are objects of every resource kube-apiserver keeps in storage.
+var servedObjects = map[string]runtime.Object{
+	"namespaces": &api.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"team": "a"}},
+		Spec:       api.NamespaceSpec{Finalizers: []api.FinalizerName{"kubernetes"}},
+		Status:     api.NamespaceStatus{Phase: api.NamespaceActive},
+	},
+	"secrets": &api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+		Data:       map[string][]byte{"key": []byte("value")},
+		Type:       api.SecretTypeOpaque,
+	},
+	"configmaps": &api.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+		Data:       map[string]string{"key": "value"},
+	},
+	"serviceaccounts": &api.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+		Secrets:    []api.ObjectReference{{Name: "foo-token"}},
+	},
+	"events": &api.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "foo.1", Namespace: "ns"},
+		InvolvedObject: api.ObjectReference{Kind: "Secret", Namespace: "ns", Name: "foo"},
+		Reason:         "Created",
+		Message:        "created the secret",
+		Source:         api.EventSource{Component: "test"},
+		FirstTimestamp: metav1.NewTime(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC)),
+		LastTimestamp:  metav1.NewTime(time.Date(2017, 9, 1, 0, 0, 0, 0, time.UTC)),
+		Count:          1,
+		Type:           api.EventTypeNormal,
+	},
+}
+
+func TestStorageCodecRoundTrip(t *testing.T) {
+	s := NewServerRunOptions()
+	factory := &genericoptions.SimpleRestOptionsFactory{Options: *s.Etcd}
+
+	for resource, obj := range servedObjects {
+		restOptions, err := factory.GetRESTOptions(api.Resource(resource))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", resource, err)
+			continue
+		}
+		codec := restOptions.StorageConfig.Codec
+
+		data, err := runtime.Encode(codec, obj)
+		if err != nil {
+			t.Errorf("%s: failed to encode: %v", resource, err)
+			continue
+		}
+		if !strings.Contains(string(data), `"apiVersion":"v1"`) {
+			t.Errorf("%s: expected the object to be stored at v1, got %s", resource, data)
+		}
+		decoded, err := runtime.Decode(codec, data)
+		if err != nil {
+			t.Errorf("%s: failed to decode: %v", resource, err)
+			continue
+		}
+		if !apiequality.Semantic.DeepEqual(obj, decoded) {
+			t.Errorf("%s: expected %#v, got %#v", resource, obj, decoded)
+		}
+	}
+}
+
+func TestProtobufStorageMediaType(t *testing.T) {
+	s := NewServerRunOptions()
+	s.Etcd.StorageConfig.ServerList = []string{"http://127.0.0.1:2379"}
+	s.Etcd.DefaultStorageMediaType = "application/vnd.kubernetes.protobuf"
+
+	errs := s.Etcd.Validate()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "no protobuf serialization") {
+		t.Errorf("expected protobuf storage to be rejected, got %v", errs)
+	}
+
+	factory := &genericoptions.SimpleRestOptionsFactory{Options: *s.Etcd}
+	for resource := range servedObjects {
+		if _, err := factory.GetRESTOptions(api.Resource(resource)); err == nil {
+			t.Errorf("%s: expected an error storing as protobuf", resource)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/mqshen/HuZhou/pkg/api"
 	genericapiserver "github.com/HuZhou/apiserver/pkg/server"
+	genericoptions "github.com/HuZhou/apiserver/pkg/server/options"
 	"github.com/mqshen/HuZhou/pkg/master"
 	"net/http"
 	"github.com/mqshen/HuZhou/pkg/master/tunneler"
@@ -14,6 +15,13 @@ import (
 	kubeserver "github.com/mqshen/HuZhou/pkg/kubeapiserver/server"
 	"crypto/tls"
 	"fmt"
+	"io/ioutil"
+	certutil "k8s.io/client-go/util/cert"
+	"github.com/mqshen/HuZhou/pkg/serviceaccount"
+	serviceaccountcontroller "github.com/mqshen/HuZhou/pkg/controller/serviceaccount"
+	"github.com/HuZhou/apiserver/pkg/authentication/authenticator"
+	clientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
 )
 
 // Run runs the specified APIServer.  This should never exit.
@@ -81,20 +89,86 @@ func CreateKubeAPIServerConfig(s *options.ServerRunOptions, nodeTunneler tunnele
 		return nil, nil, err
 	}
 	config := &master.Config{
-		GenericConfig: genericConfig,
+		GenericConfig:                  genericConfig,
+		EventTTL:                       s.EventTTL,
+		EnableStorageMigration:         s.EnableStorageMigration,
+		StorageMigrationCheckpointFile: s.StorageMigrationCheckpointFile,
+		ConcurrentNamespaceSyncs:       s.ConcurrentNamespaceSyncs,
+		NamespaceSyncPeriod:            s.NamespaceSyncPeriod,
+
+		ConcurrentServiceAccountTokenSyncs: s.ConcurrentServiceAccountTokenSyncs,
+
+		EnableGarbageCollector: s.Etcd.EnableGarbageCollection,
+		ConcurrentGCSyncs:      s.ConcurrentGCSyncs,
+	}
+	if len(s.ServiceAccountSigningKeyFile) > 0 {
+		privateKey, err := serviceaccount.ReadPrivateKey(s.ServiceAccountSigningKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading key for service account token controller: %v", err)
+		}
+		config.ServiceAccountTokenGenerator = serviceaccount.JWTTokenGenerator(privateKey)
+	} else {
+		glog.Warningf("the service account tokens controller is disabled because there is no private key")
+	}
+	if len(s.RootCAFile) > 0 {
+		rootCA, err := ioutil.ReadFile(s.RootCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading root-ca-file at %s: %v", s.RootCAFile, err)
+		}
+		if _, err := certutil.ParseCertsPEM(rootCA); err != nil {
+			return nil, nil, fmt.Errorf("error parsing root-ca-file at %s: %v", s.RootCAFile, err)
+		}
+		config.ServiceAccountRootCA = rootCA
 	}
 	return config, insecureServingOptions, nil
 }
 
 func BuildGenericConfig(s *options.ServerRunOptions) (*genericapiserver.Config, *kubeserver.InsecureServingInfo, error) {
 	genericConfig := genericapiserver.NewConfig(api.Codecs)
+	if err := s.GenericServerRunOptions.ApplyTo(genericConfig); err != nil {
+		return nil, nil, err
+	}
 	insecureServingOptions, err := s.InsecureServing.ApplyTo(genericConfig)
 	if err != nil {
 		return nil, nil, err
 	}
+	if s.Etcd.EnableWatchCache {
+		sizes, err := genericoptions.ParseWatchCacheSizes(s.GenericServerRunOptions.WatchCacheSizes)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.Etcd.WatchCacheSizes = sizes
+	}
+	if err := s.Etcd.ApplyTo(genericConfig); err != nil {
+		return nil, nil, err
+	}
+	genericConfig.Authenticator, err = BuildAuthenticator(s, genericConfig.LoopbackClientConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid authentication config: %v", err)
+	}
 	return genericConfig, insecureServingOptions, nil
 }
 
+// BuildAuthenticator constructs the authenticator
+func BuildAuthenticator(s *options.ServerRunOptions, clientConfig *restclient.Config) (authenticator.Request, error) {
+	// the tokens signed by this server are verified with the signing key unless other keys are given
+	if s.Authentication.ServiceAccounts != nil && len(s.Authentication.ServiceAccounts.KeyFiles) == 0 && len(s.ServiceAccountSigningKeyFile) > 0 {
+		s.Authentication.ServiceAccounts.KeyFiles = []string{s.ServiceAccountSigningKeyFile}
+	}
+	authenticatorConfig := s.Authentication.ToAuthenticationConfig()
+	if authenticatorConfig.ServiceAccountLookup && len(authenticatorConfig.ServiceAccountKeyFiles) > 0 {
+		if clientConfig == nil {
+			return nil, fmt.Errorf("service account lookup requires a loopback client configuration")
+		}
+		client, err := clientset.NewForConfig(clientConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the service account lookup client: %v", err)
+		}
+		authenticatorConfig.ServiceAccountTokenGetter = serviceaccountcontroller.NewGetterFromClient(client)
+	}
+	return authenticatorConfig.New()
+}
+
 // CreateNodeDialer creates the dialer infrastructure to connect to the nodes.
 func CreateNodeDialer(s *options.ServerRunOptions) (tunneler.Tunneler, *http.Transport, error) {
 
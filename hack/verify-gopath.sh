@@ -0,0 +1,135 @@
+#!/usr/bin/env bash
+
+# Builds, vets and tests the tree in GOPATH mode, the way CI does.
+#
+# Only k8s.io/* and glog are vendored. Every other dependency is fetched at the
+# revision listed below into a GOPATH under _output/gopath, next to a symlink
+# to this checkout at github.com/mqshen/HuZhou. The staging repositories
+# resolve through the vendor/github.com/HuZhou symlinks.
+#
+# The vendored generated codecs need the ugorji revision pinned below, whose
+# base64 alphabet is rejected by Go 1.22 and later, so the tree is built with
+# GO_VERSION, which the go command downloads when it is not installed.
+#
+# Usage: hack/verify-gopath.sh [package...]
+# Set GOPATH_DIR to reuse a GOPATH populated by an earlier run.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+GOPATH_DIR=${GOPATH_DIR:-${ROOT}/_output/gopath}
+GO_VERSION=${GO_VERSION:-go1.21.13}
+
+# import path, module and version of every dependency outside vendor/
+DEPS=(
+  "bitbucket.org/ww/goautoneg github.com/munnerz/goautoneg v0.0.0-20120707110453-a547fc61f48d"
+  "github.com/NYTimes/gziphandler - v1.0.1"
+  "github.com/PuerkitoBio/purell - v1.1.1"
+  "github.com/PuerkitoBio/urlesc - v0.0.0-20170810143723-de5bf2ad4578"
+  "github.com/beorn7/perks - v1.0.1"
+  "github.com/coreos/bbolt - v1.3.3"
+  "github.com/coreos/etcd - v3.3.25+incompatible"
+  "github.com/coreos/go-semver - v0.2.0"
+  "github.com/coreos/go-systemd - v0.0.0-20180202092358-40e2722dffea"
+  "github.com/coreos/pkg - v0.0.0-20180108230652-97fdf19511ea"
+  "github.com/davecgh/go-spew - v1.1.1"
+  "github.com/dgrijalva/jwt-go - v3.0.0+incompatible"
+  "github.com/dustin/go-humanize - v1.0.0"
+  "github.com/emicklei/go-restful - v2.9.6+incompatible"
+  "github.com/emicklei/go-restful-swagger12 - v0.0.0-20170926063155-7524189396c6"
+  "github.com/evanphx/json-patch - v4.1.0+incompatible"
+  "github.com/ghodss/yaml - v1.0.1-0.20190212211648-25d852aebe32"
+  "github.com/go-openapi/jsonpointer - v0.19.5"
+  "github.com/go-openapi/jsonreference - v0.19.4"
+  "github.com/go-openapi/spec - v0.19.4"
+  "github.com/go-openapi/swag - v0.19.8"
+  "github.com/gogo/protobuf - v1.3.2"
+  "github.com/golang/protobuf - v1.5.2"
+  "github.com/google/btree - v1.0.1"
+  "github.com/google/gofuzz - v1.1.0"
+  "github.com/google/uuid - v1.0.0"
+  "github.com/googleapis/gnostic - v0.2.0"
+  "github.com/gorilla/websocket - v1.2.1-0.20170926233335-4201258b820c"
+  "github.com/gregjones/httpcache - v0.0.0-20190212212710-3befbb6ad0cc"
+  "github.com/grpc-ecosystem/go-grpc-middleware - v1.0.0"
+  "github.com/grpc-ecosystem/go-grpc-prometheus - v0.0.0-20170826090648-0dafe0d496ea"
+  "github.com/grpc-ecosystem/grpc-gateway - v1.3.1"
+  "github.com/hashicorp/golang-lru - v0.5.4"
+  "github.com/jonboulle/clockwork - v0.1.0"
+  "github.com/json-iterator/go - v1.1.12"
+  "github.com/juju/ratelimit - v1.0.2"
+  "github.com/mailru/easyjson - v0.7.1"
+  "github.com/matttproud/golang_protobuf_extensions - v1.0.1"
+  "github.com/modern-go/concurrent - v0.0.0-20180306012644-bacd9c7ef1dd"
+  "github.com/modern-go/reflect2 - v1.0.2"
+  "github.com/pborman/uuid - v0.0.0-20170612153648-e790cca94e6c"
+  "github.com/peterbourgon/diskv - v2.0.1+incompatible"
+  "github.com/prometheus/client_golang - v0.9.0"
+  "github.com/prometheus/client_model - v0.0.0-20170216185247-6f3806018612"
+  "github.com/prometheus/common - v0.0.0-20181020173914-7e9e6cabbd39"
+  "github.com/prometheus/procfs - v0.0.0-20181204211112-1dc9a6cbc91a"
+  "github.com/sirupsen/logrus - v1.0.3"
+  "github.com/soheilhy/cmux - v0.1.4"
+  "github.com/spf13/pflag - v1.0.10"
+  "github.com/tmc/grpc-websocket-proxy - v0.0.0-20170815181823-89b8d40f7ca8"
+  "github.com/ugorji/go - v0.0.0-20170107133203-ded73eae5db7"
+  "github.com/xiang90/probing - v0.0.0-20160813154853-07dd2e8dfe18"
+  "go.uber.org/atomic - v1.6.0"
+  "go.uber.org/multierr - v1.5.0"
+  "go.uber.org/zap - v1.10.0"
+  "golang.org/x/crypto - v0.0.0-20220214200702-86341886e292"
+  "golang.org/x/net - v0.11.0"
+  "golang.org/x/sys - v0.10.0"
+  "golang.org/x/term - v0.1.0"
+  "golang.org/x/text - v0.13.0"
+  "golang.org/x/time - v0.0.0-20170420181420-c06e80d9300e"
+  "google.golang.org/genproto - v0.0.0-20230410155749-daa745c078e1"
+  "google.golang.org/grpc - v1.23.0"
+  "google.golang.org/protobuf - v1.27.1"
+  "gopkg.in/inf.v0 - v0.9.1"
+  "gopkg.in/yaml.v2 - v2.4.0"
+  "sigs.k8s.io/yaml - v1.1.0"
+)
+
+fetch() {
+  local scratch
+  scratch=$(mktemp -d)
+  trap 'rm -rf "${scratch}"' RETURN
+  (cd "${scratch}" && GO111MODULE=on go mod init scratch >/dev/null 2>&1)
+
+  local dep path module version dir
+  for dep in "${DEPS[@]}"; do
+    read -r path module version <<<"${dep}"
+    [[ "${module}" == "-" ]] && module=${path}
+    [[ -d "${GOPATH_DIR}/src/${path}" ]] && continue
+    dir=$(cd "${scratch}" && GO111MODULE=on GOFLAGS=-mod=mod go mod download -json "${module}@${version}" | sed -n 's/^\t"Dir": "\(.*\)",$/\1/p')
+    mkdir -p "$(dirname "${GOPATH_DIR}/src/${path}")"
+    cp -r "${dir}" "${GOPATH_DIR}/src/${path}"
+    chmod -R u+w "${GOPATH_DIR}/src/${path}"
+  done
+
+  mkdir -p "${GOPATH_DIR}/src/github.com/mqshen"
+  ln -sfn "${ROOT}" "${GOPATH_DIR}/src/github.com/mqshen/HuZhou"
+}
+
+fetch
+
+# toolchain switching only happens in module mode, so resolve it before
+# leaving module mode
+GO="$(cd "${GOPATH_DIR}" && GO111MODULE=on GOTOOLCHAIN=${GO_VERSION} go env GOROOT)/bin/go"
+
+cd "${GOPATH_DIR}/src/github.com/mqshen/HuZhou"
+export GOPATH=${GOPATH_DIR} GO111MODULE=off GOTOOLCHAIN=local
+
+# the staging repositories are built through their vendor/ import paths; "..."
+# does not descend into the symlinks, so each one is listed by itself
+packages=("$@")
+if [[ ${#packages[@]} -eq 0 ]]; then
+  packages=($("${GO}" list ./... ./vendor/github.com/HuZhou/{api,apiserver,apiextensions-apiserver,kube-aggregator}/... | grep -v '/staging/'))
+fi
+
+"${GO}" build "${packages[@]}"
+"${GO}" vet "${packages[@]}"
+"${GO}" test "${packages[@]}"
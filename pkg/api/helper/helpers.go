@@ -0,0 +1,21 @@
+// Package helper contains helpers working on the internal types of the core API group.
+package helper
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+var standardFinalizers = sets.NewString(
+	string(api.FinalizerKubernetes),
+	metav1.FinalizerOrphanDependents,
+	metav1.FinalizerDeleteDependents,
+)
+
+// IsStandardFinalizerName returns true if the finalizer is one of the
+// finalizers defined by the core API and therefore needs no qualifying domain.
+func IsStandardFinalizerName(str string) bool {
+	return standardFinalizers.Has(str)
+}
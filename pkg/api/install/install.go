@@ -0,0 +1,24 @@
+// Package install installs the v1 monolithic api, making it available as an
+// option to all of the API encoding/decoding machinery.
+package install
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/api/v1"
+)
+
+func init() {
+	Install(api.Scheme)
+}
+
+// Install registers the API group and adds types to a scheme
+func Install(scheme *runtime.Scheme) {
+	if err := api.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+	if err := v1.AddToScheme(scheme); err != nil {
+		panic(err)
+	}
+}
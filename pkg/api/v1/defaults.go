@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"github.com/HuZhou/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return RegisterDefaults(scheme)
+}
+
+func SetDefaults_Secret(obj *v1.Secret) {
+	if obj.Type == "" {
+		obj.Type = v1.SecretTypeOpaque
+	}
+}
+
+func SetDefaults_NamespaceStatus(obj *v1.NamespaceStatus) {
+	if obj.Phase == "" {
+		obj.Phase = v1.NamespaceActive
+	}
+}
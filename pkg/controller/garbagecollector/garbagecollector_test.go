@@ -0,0 +1,223 @@
+package garbagecollector
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	unstructuredconversion "k8s.io/apimachinery/pkg/conversion/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	core "k8s.io/client-go/testing"
+)
+
+// testRESTMapper maps pods only.
+type testRESTMapper struct {
+	meta.RESTMapper
+}
+
+func (testRESTMapper) Reset() {}
+
+func newTestRESTMapper() testRESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{v1.SchemeGroupVersion}, func(version schema.GroupVersion) (*meta.VersionInterfaces, error) {
+		return &meta.VersionInterfaces{MetadataAccessor: meta.NewAccessor()}, nil
+	})
+	mapper.Add(podKind, meta.RESTScopeNamespace)
+	return testRESTMapper{mapper}
+}
+
+// newTestGarbageCollector returns a garbage collector whose server holds the
+// given pods.
+func newTestGarbageCollector(pods ...*v1.Pod) (*GarbageCollector, *dynamicfake.FakeClientPool) {
+	objects := map[string]*unstructured.Unstructured{}
+	for _, pod := range pods {
+		content, err := unstructuredconversion.DefaultConverter.ToUnstructured(pod)
+		if err != nil {
+			panic(err)
+		}
+		objects[pod.Name] = &unstructured.Unstructured{Object: content}
+	}
+	clientPool := &dynamicfake.FakeClientPool{}
+	clientPool.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		name := action.(core.GetAction).GetName()
+		obj, ok := objects[name]
+		if !ok {
+			return true, nil, errors.NewNotFound(v1.Resource("pods"), name)
+		}
+		return true, obj, nil
+	})
+
+	gb := newTestGraphBuilder()
+	gc := &GarbageCollector{
+		clientPool:             clientPool,
+		restMapper:             newTestRESTMapper(),
+		attemptToDelete:        gb.attemptToDelete,
+		attemptToOrphan:        gb.attemptToOrphan,
+		dependencyGraphBuilder: gb,
+		absentOwnerCache:       gb.absentOwnerCache,
+	}
+	return gc, clientPool
+}
+
+// newNode returns the graph node of pod.
+func newNode(pod *v1.Pod) *node {
+	return &node{
+		identity: objectReference{
+			OwnerReference: metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: pod.Name, UID: pod.UID},
+			Namespace:      pod.Namespace,
+		},
+		dependents:         make(map[*node]struct{}),
+		owners:             pod.OwnerReferences,
+		beingDeleted:       pod.DeletionTimestamp != nil,
+		deletingDependents: pod.DeletionTimestamp != nil && hasDeleteDependentsFinalizer(pod),
+	}
+}
+
+func TestAttemptToDeleteItem(t *testing.T) {
+	child := newPod("child", ownerReference("parent", true))
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		item *v1.Pod
+		// dependents are added to the node of item
+		dependents []*v1.Pod
+		// expectActions are the verbs and names of the requests made
+		expectActions      []string
+		expectVirtualEvent bool
+	}{
+		{
+			name:               "missing item",
+			item:               child,
+			expectActions:      []string{"get child"},
+			expectVirtualEvent: true,
+		},
+		{
+			name:               "recreated item",
+			pods:               []*v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: "ns", UID: "other"}}},
+			item:               child,
+			expectActions:      []string{"get child"},
+			expectVirtualEvent: true,
+		},
+		{
+			name:          "item without owners",
+			pods:          []*v1.Pod{newPod("child")},
+			item:          newPod("child"),
+			expectActions: []string{"get child"},
+		},
+		{
+			name: "item being deleted",
+			item: deletingPod(child),
+		},
+		{
+			name:          "existing owner",
+			pods:          []*v1.Pod{newPod("parent"), child},
+			item:          child,
+			expectActions: []string{"get child", "get parent"},
+		},
+		{
+			name:          "missing owner",
+			pods:          []*v1.Pod{child},
+			item:          child,
+			expectActions: []string{"get child", "get parent", "delete child"},
+		},
+		{
+			name:          "recreated owner",
+			pods:          []*v1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "ns", UID: "other"}}, child},
+			item:          child,
+			expectActions: []string{"get child", "get parent", "delete child"},
+		},
+		{
+			name: "existing and missing owners",
+			pods: []*v1.Pod{
+				newPod("parent"),
+				newPod("child", ownerReference("parent", true), ownerReference("missing", true)),
+			},
+			item:          newPod("child", ownerReference("parent", true), ownerReference("missing", true)),
+			expectActions: []string{"get child", "get parent", "get missing", "patch child"},
+		},
+		{
+			name:          "owner deleting dependents",
+			pods:          []*v1.Pod{deletingPod(newPod("parent"), metav1.FinalizerDeleteDependents), child},
+			item:          child,
+			dependents:    []*v1.Pod{newPod("grandchild", ownerReference("child", true))},
+			expectActions: []string{"get child", "get parent", "delete child"},
+		},
+		{
+			name:          "deleting dependents without dependents",
+			pods:          []*v1.Pod{deletingPod(newPod("parent"), metav1.FinalizerDeleteDependents)},
+			item:          deletingPod(newPod("parent"), metav1.FinalizerDeleteDependents),
+			expectActions: []string{"get parent", "get parent", "update parent"},
+		},
+		{
+			name:          "deleting dependents with blocking dependents",
+			pods:          []*v1.Pod{deletingPod(newPod("parent"), metav1.FinalizerDeleteDependents)},
+			item:          deletingPod(newPod("parent"), metav1.FinalizerDeleteDependents),
+			dependents:    []*v1.Pod{child},
+			expectActions: []string{"get parent"},
+		},
+	}
+
+	for _, tt := range tests {
+		gc, clientPool := newTestGarbageCollector(tt.pods...)
+		item := newNode(tt.item)
+		for _, dependent := range tt.dependents {
+			item.addDependent(newNode(dependent))
+		}
+
+		if err := gc.attemptToDeleteItem(item); err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		actions := []string{}
+		for _, action := range clientPool.Actions() {
+			name := ""
+			switch action := action.(type) {
+			case core.GetAction:
+				name = action.GetName()
+			case core.DeleteAction:
+				name = action.GetName()
+			case core.PatchAction:
+				name = action.GetName()
+			case core.UpdateAction:
+				name = action.GetObject().(*unstructured.Unstructured).GetName()
+			}
+			actions = append(actions, action.GetVerb()+" "+name)
+		}
+		if len(tt.expectActions) == 0 {
+			tt.expectActions = []string{}
+		}
+		if !reflect.DeepEqual(actions, tt.expectActions) {
+			t.Errorf("%s: expected actions %v, got %v", tt.name, tt.expectActions, actions)
+		}
+
+		if virtual := gc.dependencyGraphBuilder.graphChanges.Len() == 1; virtual != tt.expectVirtualEvent {
+			t.Errorf("%s: expected a virtual delete event %t, got %t", tt.name, tt.expectVirtualEvent, virtual)
+		}
+	}
+}
+
+func TestIsDanglingCachesAbsentOwners(t *testing.T) {
+	gc, clientPool := newTestGarbageCollector()
+	item := newNode(newPod("child", ownerReference("parent", true)))
+
+	for i := 0; i < 2; i++ {
+		dangling, owner, err := gc.isDangling(ownerReference("parent", true), item)
+		if err != nil || !dangling || owner != nil {
+			t.Fatalf("expected a dangling reference, got %t, %v: %v", dangling, owner, err)
+		}
+	}
+	if actions := clientPool.Actions(); len(actions) != 1 {
+		t.Errorf("expected the absent owner to be looked up once, got %v", actions)
+	}
+	if !gc.absentOwnerCache.Has(types.UID("parent")) {
+		t.Errorf("expected the owner to be cached as absent")
+	}
+}
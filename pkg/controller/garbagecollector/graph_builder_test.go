@@ -0,0 +1,273 @@
+package garbagecollector
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var podKind = v1.SchemeGroupVersion.WithKind("Pod")
+
+func newTestGraphBuilder() *GraphBuilder {
+	return &GraphBuilder{
+		graphChanges:     workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		uidToNode:        &concurrentUIDToNode{uidToNode: make(map[types.UID]*node)},
+		attemptToDelete:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		attemptToOrphan:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		absentOwnerCache: NewUIDCache(10),
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func ownerReference(uid string, block bool) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: uid, UID: types.UID(uid), BlockOwnerDeletion: boolPtr(block)}
+}
+
+// newPod returns a pod whose name is its uid.
+func newPod(uid string, owners ...metav1.OwnerReference) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: uid, Namespace: "ns", UID: types.UID(uid), OwnerReferences: owners}}
+}
+
+// deletingPod returns a pod that is being deleted with the given finalizers.
+func deletingPod(pod *v1.Pod, finalizers ...string) *v1.Pod {
+	pod = pod.DeepCopy()
+	now := metav1.Now()
+	pod.DeletionTimestamp = &now
+	pod.Finalizers = finalizers
+	return pod
+}
+
+func addEventFor(obj interface{}) *event {
+	return &event{eventType: addEvent, obj: obj, gvk: podKind}
+}
+
+func updateEventFor(oldObj, obj interface{}) *event {
+	return &event{eventType: updateEvent, obj: obj, oldObj: oldObj, gvk: podKind}
+}
+
+func deleteEventFor(obj interface{}) *event {
+	return &event{eventType: deleteEvent, obj: obj, gvk: podKind}
+}
+
+// queuedUIDs drains queue and returns the uids of the queued nodes.
+func queuedUIDs(queue workqueue.RateLimitingInterface) []string {
+	uids := []string{}
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		uids = append(uids, string(item.(*node).identity.UID))
+		queue.Done(item)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+func TestProcessGraphChanges(t *testing.T) {
+	parent := newPod("parent")
+	child := newPod("child", ownerReference("parent", true))
+
+	tests := []struct {
+		name   string
+		events []*event
+		// expectGraph maps the uids in the graph to the uids of their dependents
+		expectGraph              map[string][]string
+		expectDelete             []string
+		expectOrphan             []string
+		expectAbsent             []string
+		expectDeletingDependents []string
+	}{
+		{
+			name:         "dependent of a missing owner",
+			events:       []*event{addEventFor(child)},
+			expectGraph:  map[string][]string{"parent": {"child"}, "child": {}},
+			expectDelete: []string{"parent"},
+		},
+		{
+			name:        "owner and dependent",
+			events:      []*event{addEventFor(parent), addEventFor(child)},
+			expectGraph: map[string][]string{"parent": {"child"}, "child": {}},
+		},
+		{
+			name:         "removed blocking reference",
+			events:       []*event{addEventFor(parent), addEventFor(child), updateEventFor(child, newPod("child"))},
+			expectGraph:  map[string][]string{"parent": {}, "child": {}},
+			expectDelete: []string{"parent"},
+		},
+		{
+			name:         "unblocked reference",
+			events:       []*event{addEventFor(parent), addEventFor(child), updateEventFor(child, newPod("child", ownerReference("parent", false)))},
+			expectGraph:  map[string][]string{"parent": {"child"}, "child": {}},
+			expectDelete: []string{"parent"},
+		},
+		{
+			name:        "added reference",
+			events:      []*event{addEventFor(parent), addEventFor(newPod("child")), updateEventFor(newPod("child"), child)},
+			expectGraph: map[string][]string{"parent": {"child"}, "child": {}},
+		},
+		{
+			name:         "deleted owner",
+			events:       []*event{addEventFor(parent), addEventFor(child), deleteEventFor(parent)},
+			expectGraph:  map[string][]string{"child": {}},
+			expectDelete: []string{"child"},
+			expectAbsent: []string{"parent"},
+		},
+		{
+			name:        "deleted dependent",
+			events:      []*event{addEventFor(parent), addEventFor(child), deleteEventFor(child)},
+			expectGraph: map[string][]string{"parent": {}},
+		},
+		{
+			name:        "deleted unknown object",
+			events:      []*event{deleteEventFor(parent)},
+			expectGraph: map[string][]string{},
+		},
+		{
+			name: "foreground deletion",
+			events: []*event{
+				addEventFor(parent),
+				addEventFor(child),
+				updateEventFor(parent, deletingPod(parent, metav1.FinalizerDeleteDependents)),
+			},
+			expectGraph:              map[string][]string{"parent": {"child"}, "child": {}},
+			expectDelete:             []string{"child", "parent"},
+			expectDeletingDependents: []string{"parent"},
+		},
+		{
+			name:                     "created in foreground deletion",
+			events:                   []*event{addEventFor(deletingPod(parent, metav1.FinalizerDeleteDependents))},
+			expectGraph:              map[string][]string{"parent": {}},
+			expectDelete:             []string{"parent"},
+			expectDeletingDependents: []string{"parent"},
+		},
+		{
+			name: "deleted dependent of an owner in foreground deletion",
+			events: []*event{
+				addEventFor(deletingPod(parent, metav1.FinalizerDeleteDependents)),
+				addEventFor(child),
+				deleteEventFor(child),
+			},
+			expectGraph:              map[string][]string{"parent": {}},
+			expectDelete:             []string{"parent"},
+			expectDeletingDependents: []string{"parent"},
+		},
+		{
+			name: "orphan deletion",
+			events: []*event{
+				addEventFor(parent),
+				addEventFor(child),
+				updateEventFor(parent, deletingPod(parent, metav1.FinalizerOrphanDependents)),
+			},
+			expectGraph:  map[string][]string{"parent": {"child"}, "child": {}},
+			expectOrphan: []string{"parent"},
+		},
+		{
+			name: "deletion without finalizers",
+			events: []*event{
+				addEventFor(parent),
+				updateEventFor(parent, deletingPod(parent)),
+			},
+			expectGraph: map[string][]string{"parent": {}},
+		},
+	}
+
+	for _, tt := range tests {
+		gb := newTestGraphBuilder()
+		for _, e := range tt.events {
+			gb.enqueueChanges(e)
+			gb.processGraphChanges()
+		}
+
+		graph := map[string][]string{}
+		deletingDependents := []string{}
+		for uid, n := range gb.uidToNode.uidToNode {
+			dependents := []string{}
+			for _, dependent := range n.getDependents() {
+				dependents = append(dependents, string(dependent.identity.UID))
+			}
+			sort.Strings(dependents)
+			graph[string(uid)] = dependents
+			if n.isDeletingDependents() {
+				deletingDependents = append(deletingDependents, string(uid))
+			}
+		}
+		if !reflect.DeepEqual(graph, tt.expectGraph) {
+			t.Errorf("%s: expected graph %v, got %v", tt.name, tt.expectGraph, graph)
+		}
+		if !sets.NewString(deletingDependents...).Equal(sets.NewString(tt.expectDeletingDependents...)) {
+			t.Errorf("%s: expected %v to be deleting dependents, got %v", tt.name, tt.expectDeletingDependents, deletingDependents)
+		}
+		if queued := queuedUIDs(gb.attemptToDelete); !sets.NewString(queued...).Equal(sets.NewString(tt.expectDelete...)) {
+			t.Errorf("%s: expected %v to be queued for deletion, got %v", tt.name, tt.expectDelete, queued)
+		}
+		if queued := queuedUIDs(gb.attemptToOrphan); !sets.NewString(queued...).Equal(sets.NewString(tt.expectOrphan...)) {
+			t.Errorf("%s: expected %v to be queued for orphaning, got %v", tt.name, tt.expectOrphan, queued)
+		}
+		for _, uid := range []string{"parent", "child"} {
+			expected := sets.NewString(tt.expectAbsent...).Has(uid)
+			if absent := gb.absentOwnerCache.Has(types.UID(uid)); absent != expected {
+				t.Errorf("%s: expected %s absent %t, got %t", tt.name, uid, expected, absent)
+			}
+		}
+	}
+}
+
+func TestReferencesDiffs(t *testing.T) {
+	tests := []struct {
+		name          string
+		old           []metav1.OwnerReference
+		new           []metav1.OwnerReference
+		expectAdded   []string
+		expectRemoved []string
+		expectChanged []string
+	}{
+		{name: "no references"},
+		{name: "same references", old: []metav1.OwnerReference{ownerReference("a", true)}, new: []metav1.OwnerReference{ownerReference("a", true)}},
+		{name: "added", new: []metav1.OwnerReference{ownerReference("a", true)}, expectAdded: []string{"a"}},
+		{name: "removed", old: []metav1.OwnerReference{ownerReference("a", true)}, expectRemoved: []string{"a"}},
+		{name: "changed", old: []metav1.OwnerReference{ownerReference("a", true)}, new: []metav1.OwnerReference{ownerReference("a", false)}, expectChanged: []string{"a"}},
+		{
+			name:          "mixed",
+			old:           []metav1.OwnerReference{ownerReference("a", true), ownerReference("b", true), ownerReference("c", true)},
+			new:           []metav1.OwnerReference{ownerReference("b", true), ownerReference("c", false), ownerReference("d", true)},
+			expectAdded:   []string{"d"},
+			expectRemoved: []string{"a"},
+			expectChanged: []string{"c"},
+		},
+	}
+
+	uids := func(refs []metav1.OwnerReference) sets.String {
+		result := sets.NewString()
+		for _, ref := range refs {
+			result.Insert(string(ref.UID))
+		}
+		return result
+	}
+
+	for _, tt := range tests {
+		added, removed, changed := referencesDiffs(tt.old, tt.new)
+		if !uids(added).Equal(sets.NewString(tt.expectAdded...)) {
+			t.Errorf("%s: expected added %v, got %v", tt.name, tt.expectAdded, added)
+		}
+		if !uids(removed).Equal(sets.NewString(tt.expectRemoved...)) {
+			t.Errorf("%s: expected removed %v, got %v", tt.name, tt.expectRemoved, removed)
+		}
+		changedUIDs := sets.NewString()
+		for _, pair := range changed {
+			if pair.oldRef.UID != pair.newRef.UID {
+				t.Errorf("%s: expected a pair of the same owner, got %v", tt.name, pair)
+			}
+			changedUIDs.Insert(string(pair.newRef.UID))
+		}
+		if !changedUIDs.Equal(sets.NewString(tt.expectChanged...)) {
+			t.Errorf("%s: expected changed %v, got %v", tt.name, tt.expectChanged, changed)
+		}
+	}
+}
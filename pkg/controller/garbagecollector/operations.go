@@ -0,0 +1,134 @@
+package garbagecollector
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+// apiResource consults the REST mapper to translate an <apiVersion, kind,
+// namespace> tuple to a metav1.APIResource struct.
+func (gc *GarbageCollector) apiResource(apiVersion, kind string, namespaced bool) (*metav1.APIResource, error) {
+	fqKind := schema.FromAPIVersionAndKind(apiVersion, kind)
+	mapping, err := gc.restMapper.RESTMapping(fqKind.GroupKind(), fqKind.Version)
+	if err != nil {
+		return nil, newRESTMappingError(kind, apiVersion)
+	}
+	glog.V(5).Infof("map kind %s, version %s to resource %s", kind, apiVersion, mapping.Resource)
+	resource := metav1.APIResource{
+		Name:       mapping.Resource,
+		Namespaced: namespaced,
+		Kind:       kind,
+	}
+	return &resource, nil
+}
+
+// resourceClient returns the dynamic client of the resource item belongs to.
+func (gc *GarbageCollector) resourceClient(item objectReference) (dynamic.ResourceInterface, error) {
+	fqKind := schema.FromAPIVersionAndKind(item.APIVersion, item.Kind)
+	client, err := gc.clientPool.ClientForGroupVersionKind(fqKind)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := gc.apiResource(item.APIVersion, item.Kind, len(item.Namespace) != 0)
+	if err != nil {
+		return nil, err
+	}
+	return client.Resource(resource, item.Namespace), nil
+}
+
+func (gc *GarbageCollector) deleteObject(item objectReference, policy *metav1.DeletionPropagation) error {
+	client, err := gc.resourceClient(item)
+	if err != nil {
+		return err
+	}
+	uid := item.UID
+	preconditions := metav1.Preconditions{UID: &uid}
+	deleteOptions := metav1.DeleteOptions{Preconditions: &preconditions, PropagationPolicy: policy}
+	return client.Delete(item.Name, &deleteOptions)
+}
+
+func (gc *GarbageCollector) getObject(item objectReference) (*unstructured.Unstructured, error) {
+	client, err := gc.resourceClient(item)
+	if err != nil {
+		return nil, err
+	}
+	return client.Get(item.Name, metav1.GetOptions{IncludeUninitialized: true})
+}
+
+func (gc *GarbageCollector) updateObject(item objectReference, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client, err := gc.resourceClient(item)
+	if err != nil {
+		return nil, err
+	}
+	return client.Update(obj)
+}
+
+func (gc *GarbageCollector) patchObject(item objectReference, patch []byte) (*unstructured.Unstructured, error) {
+	client, err := gc.resourceClient(item)
+	if err != nil {
+		return nil, err
+	}
+	return client.Patch(item.Name, types.MergePatchType, patch)
+}
+
+// removeOwnerReferences removes the ownerReferences to the given owners from
+// item, retrying when item is modified concurrently.
+func (gc *GarbageCollector) removeOwnerReferences(item objectReference, ownerUIDs ...types.UID) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest, err := gc.getObject(item)
+		if err != nil {
+			return err
+		}
+		if latest.GetUID() != item.UID {
+			return errors.NewNotFound(schema.GroupResource{Resource: item.Kind}, item.Name)
+		}
+		patch, err := deleteOwnerRefPatch(latest, ownerUIDs...)
+		if err != nil {
+			return err
+		}
+		_, err = gc.patchObject(item, patch)
+		return err
+	})
+}
+
+func (gc *GarbageCollector) removeFinalizer(owner *node, targetFinalizer string) error {
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		ownerObject, err := gc.getObject(owner.identity)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot finalize owner %s, because cannot get it: %v. The garbage collector will retry later.", owner.identity, err)
+		}
+		finalizers := ownerObject.GetFinalizers()
+		var newFinalizers []string
+		found := false
+		for _, f := range finalizers {
+			if f == targetFinalizer {
+				found = true
+				continue
+			}
+			newFinalizers = append(newFinalizers, f)
+		}
+		if !found {
+			glog.V(5).Infof("the %s finalizer is already removed from object %s", targetFinalizer, owner.identity)
+			return nil
+		}
+		ownerObject.SetFinalizers(newFinalizers)
+		_, err = gc.updateObject(owner.identity, ownerObject)
+		return err
+	})
+	if errors.IsConflict(err) {
+		return fmt.Errorf("updateMaxRetries(%d) has reached. The garbage collector will retry later for owner %v.", retry.DefaultBackoff.Steps, owner.identity)
+	}
+	return err
+}
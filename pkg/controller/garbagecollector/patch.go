@@ -0,0 +1,64 @@
+package garbagecollector
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// objectForOwnerRefsPatch is a JSON merge patch replacing the owner references
+// of an object. Merge patches replace lists as a whole, so the patch carries
+// the resourceVersion the new references were computed from: the update fails
+// with a conflict rather than dropping references added in the meantime. The
+// uid makes the patch fail if the object was recreated.
+type objectForOwnerRefsPatch struct {
+	Metadata objectMetaForOwnerRefsPatch `json:"metadata"`
+}
+
+type objectMetaForOwnerRefsPatch struct {
+	UID             types.UID               `json:"uid"`
+	ResourceVersion string                  `json:"resourceVersion"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences"`
+}
+
+func ownerRefsPatch(obj metav1.Object, ownerReferences []metav1.OwnerReference) ([]byte, error) {
+	return json.Marshal(objectForOwnerRefsPatch{
+		Metadata: objectMetaForOwnerRefsPatch{
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+			// a nil list is encoded as null, which removes the field
+			OwnerReferences: ownerReferences,
+		},
+	})
+}
+
+// deleteOwnerRefPatch generates a patch removing the ownerReferences to the
+// given owners from obj.
+func deleteOwnerRefPatch(obj metav1.Object, ownerUIDs ...types.UID) ([]byte, error) {
+	removed := make(map[types.UID]struct{}, len(ownerUIDs))
+	for _, uid := range ownerUIDs {
+		removed[uid] = struct{}{}
+	}
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range obj.GetOwnerReferences() {
+		if _, ok := removed[ref.UID]; !ok {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	return ownerRefsPatch(obj, ownerReferences)
+}
+
+// patchToUnblockOwnerReferences generates a patch that unsets the
+// BlockOwnerDeletion field of all ownerReferences of obj.
+func patchToUnblockOwnerReferences(obj metav1.Object) ([]byte, error) {
+	falseVar := false
+	var ownerReferences []metav1.OwnerReference
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion {
+			ref.BlockOwnerDeletion = &falseVar
+		}
+		ownerReferences = append(ownerReferences, ref)
+	}
+	return ownerRefsPatch(obj, ownerReferences)
+}
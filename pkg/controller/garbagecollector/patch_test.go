@@ -0,0 +1,74 @@
+package garbagecollector
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestDeleteOwnerRefPatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		owners       []metav1.OwnerReference
+		removed      []types.UID
+		expectOwners []metav1.OwnerReference
+	}{
+		{
+			name:         "remove one",
+			owners:       []metav1.OwnerReference{ownerReference("a", true), ownerReference("b", false)},
+			removed:      []types.UID{"a"},
+			expectOwners: []metav1.OwnerReference{ownerReference("b", false)},
+		},
+		{
+			name:    "remove all",
+			owners:  []metav1.OwnerReference{ownerReference("a", true), ownerReference("b", false)},
+			removed: []types.UID{"a", "b"},
+		},
+		{
+			name:         "remove unknown",
+			owners:       []metav1.OwnerReference{ownerReference("a", true)},
+			removed:      []types.UID{"c"},
+			expectOwners: []metav1.OwnerReference{ownerReference("a", true)},
+		},
+	}
+
+	for _, tt := range tests {
+		pod := newPod("child", tt.owners...)
+		pod.ResourceVersion = "42"
+		data, err := deleteOwnerRefPatch(pod, tt.removed...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		checkOwnerRefsPatch(t, tt.name, data, tt.expectOwners)
+	}
+}
+
+func TestPatchToUnblockOwnerReferences(t *testing.T) {
+	pod := newPod("child", ownerReference("a", true), ownerReference("b", false), metav1.OwnerReference{UID: "c"})
+	pod.ResourceVersion = "42"
+	data, err := patchToUnblockOwnerReferences(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkOwnerRefsPatch(t, "unblock", data, []metav1.OwnerReference{ownerReference("a", false), ownerReference("b", false), {UID: "c"}})
+}
+
+// checkOwnerRefsPatch verifies that data replaces the owner references of the
+// pod made by newPod("child") at resourceVersion 42.
+func checkOwnerRefsPatch(t *testing.T, name string, data []byte, expectOwners []metav1.OwnerReference) {
+	patch := objectForOwnerRefsPatch{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		t.Errorf("%s: unexpected error decoding %s: %v", name, data, err)
+		return
+	}
+	expected := objectForOwnerRefsPatch{
+		Metadata: objectMetaForOwnerRefsPatch{UID: "child", ResourceVersion: "42", OwnerReferences: expectOwners},
+	}
+	if !reflect.DeepEqual(patch, expected) {
+		t.Errorf("%s: expected patch %#v, got %s", name, expected, data)
+	}
+}
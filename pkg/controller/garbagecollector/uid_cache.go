@@ -0,0 +1,54 @@
+package garbagecollector
+
+import (
+	"container/list"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// UIDCache is an LRU cache for uid.
+type UIDCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	// entries holds the uids from the most to the least recently used.
+	entries *list.List
+	// elements indexes the entries by uid.
+	elements map[types.UID]*list.Element
+}
+
+// NewUIDCache returns a UIDCache.
+func NewUIDCache(maxCacheEntries int) *UIDCache {
+	return &UIDCache{
+		maxEntries: maxCacheEntries,
+		entries:    list.New(),
+		elements:   make(map[types.UID]*list.Element),
+	}
+}
+
+// Add adds a uid to the cache.
+func (c *UIDCache) Add(uid types.UID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.elements[uid]; ok {
+		c.entries.MoveToFront(e)
+		return
+	}
+	c.elements[uid] = c.entries.PushFront(uid)
+	if c.entries.Len() > c.maxEntries {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.elements, oldest.Value.(types.UID))
+	}
+}
+
+// Has returns if a uid is in the cache.
+func (c *UIDCache) Has(uid types.UID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, found := c.elements[uid]
+	if found {
+		c.entries.MoveToFront(e)
+	}
+	return found
+}
@@ -0,0 +1,44 @@
+package garbagecollector
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestUIDCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// ops are the uids added, or looked up when prefixed with "?"
+		ops          []string
+		expectCached []types.UID
+		expectEvict  []types.UID
+	}{
+		{name: "below capacity", ops: []string{"a", "b"}, expectCached: []types.UID{"a", "b"}},
+		{name: "evicts the oldest", ops: []string{"a", "b", "c"}, expectCached: []types.UID{"b", "c"}, expectEvict: []types.UID{"a"}},
+		{name: "add refreshes", ops: []string{"a", "b", "a", "c"}, expectCached: []types.UID{"a", "c"}, expectEvict: []types.UID{"b"}},
+		{name: "lookup refreshes", ops: []string{"a", "b", "?a", "c"}, expectCached: []types.UID{"a", "c"}, expectEvict: []types.UID{"b"}},
+	}
+
+	for _, tt := range tests {
+		cache := NewUIDCache(2)
+		for _, op := range tt.ops {
+			if op[0] == '?' {
+				cache.Has(types.UID(op[1:]))
+				continue
+			}
+			cache.Add(types.UID(op))
+		}
+		// the evicted uids are checked first, as lookups refresh the entries
+		for _, uid := range tt.expectEvict {
+			if cache.Has(uid) {
+				t.Errorf("%s: expected %s to be evicted", tt.name, uid)
+			}
+		}
+		for _, uid := range tt.expectCached {
+			if !cache.Has(uid) {
+				t.Errorf("%s: expected %s to be cached", tt.name, uid)
+			}
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package deletion
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+// testResources are the resources served to the deleter: secrets can be deleted
+// as a collection, events only one by one and bindings not at all.
+func testResources() ([]*metav1.APIResourceList, error) {
+	return []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: []string{"get", "list", "delete", "deletecollection"}},
+				{Name: "events", Namespaced: true, Kind: "Event", Verbs: []string{"get", "list", "delete"}},
+				{Name: "bindings", Namespaced: true, Kind: "Binding", Verbs: []string{"create"}},
+			},
+		},
+	}, nil
+}
+
+func newNamespace(deleting bool, phase v1.NamespacePhase, finalizers ...v1.FinalizerName) *v1.Namespace {
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "ns", UID: "1", ResourceVersion: "1"},
+		Spec:       v1.NamespaceSpec{Finalizers: finalizers},
+		Status:     v1.NamespaceStatus{Phase: phase},
+	}
+	if deleting {
+		now := metav1.Now()
+		namespace.DeletionTimestamp = &now
+	}
+	return namespace
+}
+
+func newItem(name string, finalizers ...string) unstructured.Unstructured {
+	item := unstructured.Unstructured{}
+	item.SetName(name)
+	item.SetNamespace("ns")
+	item.SetFinalizers(finalizers)
+	return item
+}
+
+// actionStrings returns the verb, resource and subresource of each action.
+func actionStrings(actions []core.Action) sets.String {
+	result := sets.NewString()
+	for _, action := range actions {
+		s := action.GetVerb() + " " + action.GetResource().Resource
+		if len(action.GetSubresource()) > 0 {
+			s += "/" + action.GetSubresource()
+		}
+		result.Insert(s)
+	}
+	return result
+}
+
+func TestDeleteNamespace(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace *v1.Namespace
+		// remaining are the items listed in every resource
+		remaining []unstructured.Unstructured
+		// expectFinalizers are the finalizers of the namespace if it is kept
+		expectFinalizers    []v1.FinalizerName
+		expectDeleted       bool
+		expectErr           bool
+		expectRemainingErr  bool
+		expectKubeActions   []string
+		expectClientActions []string
+	}{
+		{name: "missing namespace", expectKubeActions: []string{"get namespaces"}},
+		{
+			name:              "not deleting",
+			namespace:         newNamespace(false, v1.NamespaceActive, v1.FinalizerKubernetes),
+			expectFinalizers:  []v1.FinalizerName{v1.FinalizerKubernetes},
+			expectKubeActions: []string{"get namespaces"},
+		},
+		{
+			name:                "terminating",
+			namespace:           newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes),
+			expectDeleted:       true,
+			expectKubeActions:   []string{"get namespaces", "create namespaces/finalize", "delete namespaces"},
+			expectClientActions: []string{"delete-collection secrets", "list secrets", "list events"},
+		},
+		{
+			name:                "other finalizer",
+			namespace:           newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes, "example.com/finalizer"),
+			expectFinalizers:    []v1.FinalizerName{"example.com/finalizer"},
+			expectKubeActions:   []string{"get namespaces", "create namespaces/finalize"},
+			expectClientActions: []string{"delete-collection secrets", "list secrets", "list events"},
+		},
+		{
+			name:              "active",
+			namespace:         newNamespace(true, v1.NamespaceActive),
+			expectDeleted:     true,
+			expectKubeActions: []string{"get namespaces", "update namespaces/status", "delete namespaces"},
+		},
+		{
+			name:              "finalized",
+			namespace:         newNamespace(true, v1.NamespaceTerminating),
+			expectDeleted:     true,
+			expectKubeActions: []string{"get namespaces", "delete namespaces"},
+		},
+		{
+			name:                "content with finalizers",
+			namespace:           newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes),
+			remaining:           []unstructured.Unstructured{newItem("foo", "example.com/finalizer")},
+			expectFinalizers:    []v1.FinalizerName{v1.FinalizerKubernetes},
+			expectRemainingErr:  true,
+			expectKubeActions:   []string{"get namespaces"},
+			expectClientActions: []string{"delete-collection secrets", "list secrets", "list events", "delete events"},
+		},
+		{
+			name:                "content without finalizers",
+			namespace:           newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes),
+			remaining:           []unstructured.Unstructured{newItem("foo")},
+			expectFinalizers:    []v1.FinalizerName{v1.FinalizerKubernetes},
+			expectErr:           true,
+			expectKubeActions:   []string{"get namespaces"},
+			expectClientActions: []string{"delete-collection secrets", "list secrets", "list events", "delete events"},
+		},
+	}
+
+	for _, tt := range tests {
+		objects := []runtime.Object{}
+		if tt.namespace != nil {
+			objects = append(objects, tt.namespace)
+		}
+		kubeClient := fake.NewSimpleClientset(objects...)
+		clientPool := &dynamicfake.FakeClientPool{}
+		remaining := tt.remaining
+		clientPool.AddReactor("list", "*", func(action core.Action) (bool, runtime.Object, error) {
+			return true, &unstructured.UnstructuredList{Items: remaining}, nil
+		})
+
+		d := NewNamespacedResourcesDeleter(kubeClient.CoreV1().Namespaces(), clientPool, testResources, v1.FinalizerKubernetes, true)
+		err := d.Delete("ns")
+		_, isRemainingErr := err.(*ResourcesRemainingError)
+		if (err != nil && !isRemainingErr) != tt.expectErr || isRemainingErr != tt.expectRemainingErr {
+			t.Errorf("%s: expected error %t and remaining error %t, got %v", tt.name, tt.expectErr, tt.expectRemainingErr, err)
+		}
+
+		if actions := actionStrings(kubeClient.Actions()); !actions.Equal(sets.NewString(tt.expectKubeActions...)) {
+			t.Errorf("%s: expected namespace actions %v, got %v", tt.name, tt.expectKubeActions, actions.List())
+		}
+		// resources are visited in no particular order and the first failure
+		// stops the deletion of the content
+		expectClientActions := sets.NewString(tt.expectClientActions...)
+		if actions := actionStrings(clientPool.Actions()); !actions.Equal(expectClientActions) && !(tt.expectErr && expectClientActions.IsSuperset(actions)) {
+			t.Errorf("%s: expected content actions %v, got %v", tt.name, tt.expectClientActions, actions.List())
+		}
+
+		if tt.namespace == nil {
+			continue
+		}
+		namespace, err := kubeClient.CoreV1().Namespaces().Get("ns", metav1.GetOptions{})
+		if deleted := errors.IsNotFound(err); deleted != tt.expectDeleted {
+			t.Errorf("%s: expected deleted %t, got %t: %v", tt.name, tt.expectDeleted, deleted, err)
+			continue
+		}
+		if tt.expectDeleted {
+			continue
+		}
+		if !reflect.DeepEqual(namespace.Spec.Finalizers, tt.expectFinalizers) {
+			t.Errorf("%s: expected finalizers %v, got %v", tt.name, tt.expectFinalizers, namespace.Spec.Finalizers)
+		}
+	}
+}
+
+func TestDeleteNamespaceRetriesConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		// changeUID replaces the namespace with a new one on conflict
+		changeUID bool
+		expectErr bool
+	}{
+		{name: "same namespace"},
+		{name: "recreated namespace", changeUID: true, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		kubeClient := fake.NewSimpleClientset(newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes))
+		conflicted := false
+		kubeClient.PrependReactor("create", "namespaces", func(action core.Action) (bool, runtime.Object, error) {
+			if conflicted || action.GetSubresource() != "finalize" {
+				return false, nil, nil
+			}
+			conflicted = true
+			return true, nil, errors.NewConflict(v1.Resource("namespaces"), "ns", fmt.Errorf("conflict"))
+		})
+		changeUID := tt.changeUID
+		kubeClient.PrependReactor("get", "namespaces", func(action core.Action) (bool, runtime.Object, error) {
+			if !conflicted || !changeUID {
+				return false, nil, nil
+			}
+			recreated := newNamespace(true, v1.NamespaceTerminating, v1.FinalizerKubernetes)
+			recreated.UID = "2"
+			return true, recreated, nil
+		})
+
+		d := NewNamespacedResourcesDeleter(kubeClient.CoreV1().Namespaces(), &dynamicfake.FakeClientPool{}, testResources, v1.FinalizerKubernetes, true)
+		if err := d.Delete("ns"); (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+		}
+		if !conflicted {
+			t.Errorf("%s: expected the finalize to conflict", tt.name)
+		}
+	}
+}
+
+func TestInitOpCache(t *testing.T) {
+	d := NewNamespacedResourcesDeleter(nil, nil, testResources, v1.FinalizerKubernetes, true).(*namespacedResourcesDeleter)
+
+	tests := []struct {
+		resource  string
+		operation operation
+		expected  bool
+	}{
+		{resource: "secrets", operation: operationList, expected: true},
+		{resource: "secrets", operation: operationDeleteCollection, expected: true},
+		{resource: "events", operation: operationList, expected: true},
+		{resource: "events", operation: operationDeleteCollection, expected: false},
+		{resource: "bindings", operation: operationList, expected: false},
+		{resource: "unknown", operation: operationList, expected: true},
+	}
+
+	for _, tt := range tests {
+		key := operationKey{operation: tt.operation, gvr: v1.SchemeGroupVersion.WithResource(tt.resource)}
+		if supported := d.opCache.isSupported(key); supported != tt.expected {
+			t.Errorf("%s %s: expected supported %t, got %t", tt.operation, tt.resource, tt.expected, supported)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package namespace
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/mqshen/HuZhou/pkg/controller/namespace/deletion"
+)
+
+// fakeDeleter records the namespaces it is asked to delete.
+type fakeDeleter struct {
+	deleted []string
+	err     error
+}
+
+func (d *fakeDeleter) Delete(nsName string) error {
+	d.deleted = append(d.deleted, nsName)
+	return d.err
+}
+
+// fakeQueue records the keys that are added with a delay instead of adding them.
+type fakeQueue struct {
+	workqueue.RateLimitingInterface
+
+	delayed     map[string]time.Duration
+	rateLimited []string
+}
+
+func (q *fakeQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.delayed[item.(string)] = duration
+}
+
+func (q *fakeQueue) AddRateLimited(item interface{}) {
+	q.rateLimited = append(q.rateLimited, item.(string))
+}
+
+func newTestController(deleter deletion.NamespacedResourcesDeleterInterface, namespaces ...*v1.Namespace) (*NamespaceController, *fakeQueue) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, namespace := range namespaces {
+		indexer.Add(namespace)
+	}
+	queue := &fakeQueue{RateLimitingInterface: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()), delayed: map[string]time.Duration{}}
+	return &NamespaceController{
+		lister:                     corelisters.NewNamespaceLister(indexer),
+		queue:                      queue,
+		namespacedResourcesDeleter: deleter,
+	}, queue
+}
+
+func newNamespace(name string, deleting bool) *v1.Namespace {
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if deleting {
+		now := metav1.Now()
+		namespace.DeletionTimestamp = &now
+	}
+	return namespace
+}
+
+func TestEnqueueNamespace(t *testing.T) {
+	tests := []struct {
+		name        string
+		namespace   *v1.Namespace
+		expectQueue bool
+	}{
+		{name: "active", namespace: newNamespace("ns", false)},
+		{name: "deleting", namespace: newNamespace("ns", true), expectQueue: true},
+	}
+
+	for _, tt := range tests {
+		nm, queue := newTestController(&fakeDeleter{})
+		nm.enqueueNamespace(tt.namespace)
+		delay, queued := queue.delayed["ns"]
+		if queued != tt.expectQueue {
+			t.Errorf("%s: expected queued %t, got %t", tt.name, tt.expectQueue, queued)
+		}
+		if queued && delay != namespaceDeletionGracePeriod {
+			t.Errorf("%s: expected the namespace to be processed after %v, got %v", tt.name, namespaceDeletionGracePeriod, delay)
+		}
+	}
+}
+
+func TestWorker(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []*v1.Namespace
+		err        error
+		// expectDeleted are the namespaces passed to the deleter
+		expectDeleted     []string
+		expectDelay       time.Duration
+		expectRateLimited bool
+	}{
+		{name: "missing namespace"},
+		{name: "deleted", namespaces: []*v1.Namespace{newNamespace("ns", true)}, expectDeleted: []string{"ns"}},
+		{
+			name:          "content remaining",
+			namespaces:    []*v1.Namespace{newNamespace("ns", true)},
+			err:           &deletion.ResourcesRemainingError{Estimate: 10},
+			expectDeleted: []string{"ns"},
+			expectDelay:   6 * time.Second,
+		},
+		{
+			name:              "failure",
+			namespaces:        []*v1.Namespace{newNamespace("ns", true)},
+			err:               fmt.Errorf("failure"),
+			expectDeleted:     []string{"ns"},
+			expectRateLimited: true,
+		},
+	}
+
+	for _, tt := range tests {
+		deleter := &fakeDeleter{err: tt.err}
+		nm, queue := newTestController(deleter, tt.namespaces...)
+		queue.Add("ns")
+		// the worker returns once the queue is drained
+		queue.ShutDown()
+		nm.worker()
+
+		if !reflect.DeepEqual(deleter.deleted, tt.expectDeleted) {
+			t.Errorf("%s: expected %v to be deleted, got %v", tt.name, tt.expectDeleted, deleter.deleted)
+		}
+		if delay := queue.delayed["ns"]; delay != tt.expectDelay {
+			t.Errorf("%s: expected a retry after %v, got %v", tt.name, tt.expectDelay, delay)
+		}
+		if rateLimited := len(queue.rateLimited) != 0; rateLimited != tt.expectRateLimited {
+			t.Errorf("%s: expected rate limited %t, got %t", tt.name, tt.expectRateLimited, rateLimited)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package serviceaccount
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	"github.com/mqshen/HuZhou/pkg/serviceaccount"
+)
+
+// clientGetter implements ServiceAccountTokenGetter using a clientset.Interface
+type clientGetter struct {
+	client clientset.Interface
+}
+
+// NewGetterFromClient returns a ServiceAccountTokenGetter that
+// uses the specified client to retrieve service accounts and secrets.
+// The client should NOT authenticate using a service account token
+// the returned getter will be used to retrieve, or recursion will result.
+func NewGetterFromClient(c clientset.Interface) serviceaccount.ServiceAccountTokenGetter {
+	return clientGetter{c}
+}
+
+func (c clientGetter) GetServiceAccount(namespace, name string) (*v1.ServiceAccount, error) {
+	return c.client.CoreV1().ServiceAccounts(namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c clientGetter) GetSecret(namespace, name string) (*v1.Secret, error) {
+	return c.client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+}
@@ -0,0 +1,253 @@
+package serviceaccount
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+// testGenerator hands out the same token for every secret.
+type testGenerator struct{}
+
+func (testGenerator) GenerateToken(serviceAccount v1.ServiceAccount, secret v1.Secret) (string, error) {
+	return "token", nil
+}
+
+func newServiceAccount(secrets ...string) *v1.ServiceAccount {
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "ns", UID: "12345", ResourceVersion: "1"}}
+	for _, secret := range secrets {
+		serviceAccount.Secrets = append(serviceAccount.Secrets, v1.ObjectReference{Name: secret})
+	}
+	return serviceAccount
+}
+
+func newTokenSecret(name string, withToken bool) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "ns",
+			UID:             "23456",
+			ResourceVersion: "1",
+			Annotations:     map[string]string{v1.ServiceAccountNameKey: "default", v1.ServiceAccountUIDKey: "12345"},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+	if withToken {
+		secret.Data = map[string][]byte{
+			v1.ServiceAccountTokenKey:     []byte("token"),
+			v1.ServiceAccountNamespaceKey: []byte("ns"),
+		}
+	}
+	return secret
+}
+
+func newOpaqueSecret(name string) *v1.Secret {
+	return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", UID: "34567", ResourceVersion: "1"}, Type: v1.SecretTypeOpaque}
+}
+
+// newTestController returns a controller whose caches hold the given objects,
+// and whose client holds the given objects as well.
+func newTestController(serviceAccounts []*v1.ServiceAccount, secrets []*v1.Secret) (*TokensController, *fake.Clientset) {
+	objects := []runtime.Object{}
+	for _, serviceAccount := range serviceAccounts {
+		objects = append(objects, serviceAccount)
+	}
+	for _, secret := range secrets {
+		objects = append(objects, secret)
+	}
+	client := fake.NewSimpleClientset(objects...)
+	informerFactory := informers.NewSharedInformerFactory(client, 0)
+	serviceAccountInformer := informerFactory.Core().V1().ServiceAccounts()
+	secretInformer := informerFactory.Core().V1().Secrets()
+	for _, serviceAccount := range serviceAccounts {
+		serviceAccountInformer.Informer().GetIndexer().Add(serviceAccount)
+	}
+	for _, secret := range secrets {
+		secretInformer.Informer().GetIndexer().Add(secret)
+	}
+
+	controller := NewTokensController(serviceAccountInformer, secretInformer, client, TokensControllerOptions{TokenGenerator: testGenerator{}})
+	return controller, client
+}
+
+// actionStrings returns the verb and resource of each action in order.
+func actionStrings(actions []core.Action) []string {
+	result := []string{}
+	for _, action := range actions {
+		result = append(result, action.GetVerb()+" "+action.GetResource().Resource)
+	}
+	return result
+}
+
+func TestSyncServiceAccount(t *testing.T) {
+	tests := []struct {
+		name            string
+		serviceAccounts []*v1.ServiceAccount
+		secrets         []*v1.Secret
+		// key is the service account that is synced
+		key             *v1.ServiceAccount
+		expectActions   []string
+		expectReference bool
+	}{
+		{
+			name:            "new service account",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount()},
+			key:             newServiceAccount(),
+			expectActions:   []string{"get serviceaccounts", "create secrets", "update serviceaccounts"},
+			expectReference: true,
+		},
+		{
+			name:            "service account referencing a token",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("default-token")},
+			secrets:         []*v1.Secret{newTokenSecret("default-token", true)},
+			key:             newServiceAccount("default-token"),
+			expectActions:   []string{},
+		},
+		{
+			name:            "service account referencing another secret",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("opaque")},
+			secrets:         []*v1.Secret{newOpaqueSecret("opaque")},
+			key:             newServiceAccount("opaque"),
+			expectActions:   []string{"get serviceaccounts", "create secrets", "update serviceaccounts"},
+			expectReference: true,
+		},
+		{
+			name:          "deleted service account",
+			secrets:       []*v1.Secret{newTokenSecret("default-token", true), newOpaqueSecret("opaque")},
+			key:           newServiceAccount("default-token"),
+			expectActions: []string{"delete secrets"},
+		},
+	}
+
+	for _, tt := range tests {
+		controller, client := newTestController(tt.serviceAccounts, tt.secrets)
+		controller.queueServiceAccountSync(tt.key)
+		controller.syncServiceAccount()
+
+		if actions := actionStrings(client.Actions()); !reflect.DeepEqual(actions, tt.expectActions) {
+			t.Errorf("%s: expected actions %v, got %v", tt.name, tt.expectActions, actions)
+			continue
+		}
+		if !tt.expectReference {
+			continue
+		}
+
+		created := client.Actions()[1].(core.CreateAction).GetObject().(*v1.Secret)
+		if created.Type != v1.SecretTypeServiceAccountToken || string(created.Data[v1.ServiceAccountTokenKey]) != "token" || string(created.Data[v1.ServiceAccountNamespaceKey]) != "ns" {
+			t.Errorf("%s: unexpected token secret %#v", tt.name, created)
+		}
+		updated := client.Actions()[2].(core.UpdateAction).GetObject().(*v1.ServiceAccount)
+		if !getSecretReferences(updated).Has(created.Name) {
+			t.Errorf("%s: expected the service account to reference %s, got %v", tt.name, created.Name, updated.Secrets)
+		}
+	}
+}
+
+func TestSyncSecret(t *testing.T) {
+	tests := []struct {
+		name            string
+		serviceAccounts []*v1.ServiceAccount
+		secrets         []*v1.Secret
+		// key is the secret that is synced
+		key           *v1.Secret
+		expectActions []string
+	}{
+		{
+			name:            "token",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("default-token")},
+			secrets:         []*v1.Secret{newTokenSecret("default-token", true)},
+			key:             newTokenSecret("default-token", true),
+			expectActions:   []string{},
+		},
+		{
+			name:            "token without data",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("default-token")},
+			secrets:         []*v1.Secret{newTokenSecret("default-token", false)},
+			key:             newTokenSecret("default-token", false),
+			expectActions:   []string{"get secrets", "update secrets"},
+		},
+		{
+			name:          "token for deleted service account",
+			secrets:       []*v1.Secret{newTokenSecret("default-token", true)},
+			key:           newTokenSecret("default-token", true),
+			expectActions: []string{"get serviceaccounts", "delete secrets"},
+		},
+		{
+			name:            "deleted token",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("default-token", "opaque")},
+			key:             newTokenSecret("default-token", true),
+			expectActions:   []string{"get serviceaccounts", "update serviceaccounts"},
+		},
+		{
+			name:            "deleted token that is not referenced",
+			serviceAccounts: []*v1.ServiceAccount{newServiceAccount("opaque")},
+			key:             newTokenSecret("default-token", true),
+			expectActions:   []string{"get serviceaccounts"},
+		},
+	}
+
+	for _, tt := range tests {
+		controller, client := newTestController(tt.serviceAccounts, tt.secrets)
+		controller.queueSecretSync(tt.key)
+		controller.syncSecret()
+
+		if actions := actionStrings(client.Actions()); !reflect.DeepEqual(actions, tt.expectActions) {
+			t.Errorf("%s: expected actions %v, got %v", tt.name, tt.expectActions, actions)
+			continue
+		}
+		for _, action := range client.Actions() {
+			update, ok := action.(core.UpdateAction)
+			if !ok {
+				continue
+			}
+			switch obj := update.GetObject().(type) {
+			case *v1.Secret:
+				if string(obj.Data[v1.ServiceAccountTokenKey]) != "token" || string(obj.Data[v1.ServiceAccountNamespaceKey]) != "ns" {
+					t.Errorf("%s: expected the token to be populated, got %v", tt.name, obj.Data)
+				}
+			case *v1.ServiceAccount:
+				if references := getSecretReferences(obj); references.Has("default-token") || !references.Has("opaque") {
+					t.Errorf("%s: expected only the deleted token to be dropped, got %v", tt.name, obj.Secrets)
+				}
+			}
+		}
+	}
+}
+
+func TestParseQueueKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		parse     func(interface{}) error
+		key       interface{}
+		expectErr bool
+	}{
+		{name: "service account", parse: parseServiceAccount, key: makeServiceAccountKey(newServiceAccount())},
+		{name: "service account without uid", parse: parseServiceAccount, key: serviceAccountQueueKey{namespace: "ns", name: "default"}, expectErr: true},
+		{name: "secret key for a service account", parse: parseServiceAccount, key: makeSecretQueueKey(newTokenSecret("default-token", true)), expectErr: true},
+		{name: "secret", parse: parseSecret, key: makeSecretQueueKey(newTokenSecret("default-token", true))},
+		{name: "secret without service account", parse: parseSecret, key: makeSecretQueueKey(newOpaqueSecret("opaque")), expectErr: true},
+		{name: "string", parse: parseSecret, key: "ns/default-token", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.parse(tt.key); (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+		}
+	}
+}
+
+func parseServiceAccount(key interface{}) error {
+	_, err := parseServiceAccountKey(key)
+	return err
+}
+
+func parseSecret(key interface{}) error {
+	_, err := parseSecretQueueKey(key)
+	return err
+}
@@ -0,0 +1,46 @@
+package authenticator
+
+import (
+	"github.com/HuZhou/apiserver/pkg/authentication/authenticator"
+	"github.com/HuZhou/apiserver/pkg/authentication/request/bearertoken"
+	"github.com/mqshen/HuZhou/pkg/serviceaccount"
+)
+
+// AuthenticatorConfig is the configuration of the request authenticator of the kube-apiserver.
+type AuthenticatorConfig struct {
+	// ServiceAccountKeyFiles contain the public keys service account tokens are verified with.
+	ServiceAccountKeyFiles []string
+	// ServiceAccountLookup makes the authenticator reject tokens whose secret or service
+	// account no longer exist.
+	ServiceAccountLookup bool
+
+	ServiceAccountTokenGetter serviceaccount.ServiceAccountTokenGetter
+}
+
+// New returns an authenticator.Request or an error that supports the standard
+// Kubernetes authentication mechanisms. It returns nil if no mechanism is configured.
+func (config AuthenticatorConfig) New() (authenticator.Request, error) {
+	if len(config.ServiceAccountKeyFiles) == 0 {
+		return nil, nil
+	}
+	serviceAccountAuth, err := newServiceAccountAuthenticator(config.ServiceAccountKeyFiles, config.ServiceAccountLookup, config.ServiceAccountTokenGetter)
+	if err != nil {
+		return nil, err
+	}
+	return bearertoken.New(serviceAccountAuth), nil
+}
+
+// newServiceAccountAuthenticator returns an authenticator.Token or an error
+func newServiceAccountAuthenticator(keyfiles []string, lookup bool, serviceAccountGetter serviceaccount.ServiceAccountTokenGetter) (authenticator.Token, error) {
+	allPublicKeys := []interface{}{}
+	for _, keyfile := range keyfiles {
+		publicKeys, err := serviceaccount.ReadPublicKeys(keyfile)
+		if err != nil {
+			return nil, err
+		}
+		allPublicKeys = append(allPublicKeys, publicKeys...)
+	}
+
+	tokenAuthenticator := serviceaccount.JWTTokenAuthenticator(allPublicKeys, lookup, serviceAccountGetter)
+	return tokenAuthenticator, nil
+}
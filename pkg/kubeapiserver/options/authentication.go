@@ -0,0 +1,54 @@
+package options
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/mqshen/HuZhou/pkg/kubeapiserver/authenticator"
+)
+
+// BuiltInAuthenticationOptions are the options of the authentication mechanisms built
+// into the kube-apiserver.
+type BuiltInAuthenticationOptions struct {
+	ServiceAccounts *ServiceAccountAuthenticationOptions
+}
+
+// ServiceAccountAuthenticationOptions are the options of the service account token authentication.
+type ServiceAccountAuthenticationOptions struct {
+	KeyFiles []string
+	Lookup   bool
+}
+
+func NewBuiltInAuthenticationOptions() *BuiltInAuthenticationOptions {
+	return &BuiltInAuthenticationOptions{}
+}
+
+// WithServiceAccounts enables the service account token authentication.
+func (s *BuiltInAuthenticationOptions) WithServiceAccounts() *BuiltInAuthenticationOptions {
+	s.ServiceAccounts = &ServiceAccountAuthenticationOptions{Lookup: true}
+	return s
+}
+
+func (s *BuiltInAuthenticationOptions) AddFlags(fs *pflag.FlagSet) {
+	if s.ServiceAccounts != nil {
+		fs.StringArrayVar(&s.ServiceAccounts.KeyFiles, "service-account-key-file", s.ServiceAccounts.KeyFiles, ""+
+			"File containing PEM-encoded x509 RSA or ECDSA private or public keys, used to verify "+
+			"ServiceAccount tokens. If unspecified, --service-account-private-key-file is used. "+
+			"The specified file can contain multiple keys, and the flag can be specified multiple "+
+			"times with different files.")
+
+		fs.BoolVar(&s.ServiceAccounts.Lookup, "service-account-lookup", s.ServiceAccounts.Lookup,
+			"If true, validate ServiceAccount tokens exist in etcd as part of authentication.")
+	}
+}
+
+// ToAuthenticationConfig returns the configuration of the request authenticator.
+func (s *BuiltInAuthenticationOptions) ToAuthenticationConfig() authenticator.AuthenticatorConfig {
+	ret := authenticator.AuthenticatorConfig{}
+
+	if s.ServiceAccounts != nil {
+		ret.ServiceAccountKeyFiles = s.ServiceAccounts.KeyFiles
+		ret.ServiceAccountLookup = s.ServiceAccounts.Lookup
+	}
+
+	return ret
+}
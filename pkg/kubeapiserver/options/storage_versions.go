@@ -1,5 +1,19 @@
 package options
 
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/api/core/v1"
+)
+
 const (
 	DefaultEtcdPathPrefix = "/registry"
 )
+
+// DefaultStorageVersions returns the version the objects of each API group
+// served by kube-apiserver are stored at.
+func DefaultStorageVersions() map[string]schema.GroupVersion {
+	return map[string]schema.GroupVersion{
+		v1.GroupName: v1.SchemeGroupVersion,
+	}
+}
@@ -1,7 +1,46 @@
 package master
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	cacheddiscovery "k8s.io/client-go/discovery/cached"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/registry/generic/migration"
 	genericapiserver "github.com/HuZhou/apiserver/pkg/server"
+	_ "github.com/mqshen/HuZhou/pkg/api/install"
+	"github.com/mqshen/HuZhou/pkg/controller/garbagecollector"
+	namespacecontroller "github.com/mqshen/HuZhou/pkg/controller/namespace"
+	serviceaccountcontroller "github.com/mqshen/HuZhou/pkg/controller/serviceaccount"
+	corerest "github.com/mqshen/HuZhou/pkg/registry/core/rest"
+	"github.com/mqshen/HuZhou/pkg/serviceaccount"
+)
+
+const (
+	// DefaultEventTTL is how long events are kept when no --event-ttl is given.
+	DefaultEventTTL = 1 * time.Hour
+	// DefaultNamespaceSyncPeriod is how often terminating namespaces are resynced when no
+	// --namespace-sync-period is given.
+	DefaultNamespaceSyncPeriod = 5 * time.Minute
+	// DefaultConcurrentServiceAccountTokenSyncs is the number of concurrent token syncs when no
+	// --concurrent-serviceaccount-token-syncs is given.
+	DefaultConcurrentServiceAccountTokenSyncs = 5
+	// DefaultConcurrentGCSyncs is the number of garbage collector workers when no
+	// --concurrent-gc-syncs is given.
+	DefaultConcurrentGCSyncs = 20
+	// gcDiscoverySyncPeriod is how often the garbage collector looks for new
+	// resources to monitor.
+	gcDiscoverySyncPeriod = 30 * time.Second
 )
 
 type ClientCARegistrationHook struct {
@@ -17,6 +56,35 @@ type ClientCARegistrationHook struct {
 type Config struct {
 	GenericConfig *genericapiserver.Config
 
+	// EventTTL is how long events are retained after their last update.
+	EventTTL time.Duration
+
+	// EnableStorageMigration runs a storage migration once the server started.
+	EnableStorageMigration bool
+	// StorageMigrationCheckpointFile is where the storage migration persists its progress.
+	StorageMigrationCheckpointFile string
+
+	// ConcurrentNamespaceSyncs is the number of namespaces the namespace controller purges
+	// concurrently. The controller is not started if it is zero.
+	ConcurrentNamespaceSyncs int
+	// NamespaceSyncPeriod is the period for resyncing namespace lifecycle updates.
+	NamespaceSyncPeriod time.Duration
+
+	// ServiceAccountTokenGenerator signs the tokens of the service account token secrets.
+	// The tokens controller is not started if it is nil.
+	ServiceAccountTokenGenerator serviceaccount.TokenGenerator
+	// ServiceAccountRootCA is stored as ca.crt in the service account token secrets. The CA
+	// of the loopback client is used if it is empty.
+	ServiceAccountRootCA []byte
+	// ConcurrentServiceAccountTokenSyncs is the number of service accounts and token secrets
+	// the tokens controller synchronizes concurrently.
+	ConcurrentServiceAccountTokenSyncs int
+
+	// EnableGarbageCollector runs the garbage collector deleting the objects whose owners
+	// are gone. It must match the EnableGarbageCollection storage option.
+	EnableGarbageCollector bool
+	// ConcurrentGCSyncs is the number of garbage collector workers.
+	ConcurrentGCSyncs int
 }
 
 // Master contains state for a Kubernetes cluster master/api server.
@@ -24,6 +92,12 @@ type Master struct {
 	GenericAPIServer *genericapiserver.GenericAPIServer
 
 	ClientCARegistrationHook ClientCARegistrationHook
+
+	// storageMigrationResources are the resources served from storage by this
+	// master, as registered by InstallLegacyAPI. They are the only resources
+	// the storage migration rewrites.
+	storageMigrationResources      []migration.Resource
+	storageMigrationCheckpointFile string
 }
 
 type completedConfig struct {
@@ -33,6 +107,18 @@ type completedConfig struct {
 // Complete fills in any fields not set that are required to have valid data. It's mutating the receiver.
 func (c *Config) Complete() completedConfig {
 	c.GenericConfig.Complete()
+	if c.EventTTL == 0 {
+		c.EventTTL = DefaultEventTTL
+	}
+	if c.NamespaceSyncPeriod == 0 {
+		c.NamespaceSyncPeriod = DefaultNamespaceSyncPeriod
+	}
+	if c.ConcurrentServiceAccountTokenSyncs == 0 {
+		c.ConcurrentServiceAccountTokenSyncs = DefaultConcurrentServiceAccountTokenSyncs
+	}
+	if c.ConcurrentGCSyncs == 0 {
+		c.ConcurrentGCSyncs = DefaultConcurrentGCSyncs
+	}
 	return completedConfig{c}
 }
 
@@ -42,7 +128,165 @@ func (c completedConfig) New(delegationTarget genericapiserver.DelegationTarget)
 		return nil, err
 	}
 	m := &Master{
-		GenericAPIServer: s,
+		GenericAPIServer:               s,
+		storageMigrationCheckpointFile: c.StorageMigrationCheckpointFile,
+	}
+
+	legacyRESTStorageProvider := corerest.LegacyRESTStorageProvider{
+		EventTTL: c.EventTTL,
+	}
+	if err := m.InstallLegacyAPI(c.GenericConfig.RESTOptionsGetter, legacyRESTStorageProvider); err != nil {
+		return nil, err
+	}
+
+	if c.EnableStorageMigration {
+		m.GenericAPIServer.AddPostStartHook("storage-migration", func(context genericapiserver.PostStartHookContext) error {
+			go func() {
+				progress, err := m.StorageMigrator().Run(context.StopCh)
+				if err != nil {
+					glog.Errorf("Storage migration failed after %d objects: %v", progress.Visited, err)
+					return
+				}
+				glog.Infof("Storage migration finished: %d objects visited, %d migrated", progress.Visited, progress.Migrated)
+			}()
+			return nil
+		})
+	}
+	if c.ConcurrentNamespaceSyncs > 0 {
+		m.GenericAPIServer.AddPostStartHook("start-namespace-controller", func(context genericapiserver.PostStartHookContext) error {
+			return startNamespaceController(context.LoopbackClientConfig, c.NamespaceSyncPeriod, c.ConcurrentNamespaceSyncs, context.StopCh)
+		})
+	}
+	if c.ServiceAccountTokenGenerator != nil {
+		m.GenericAPIServer.AddPostStartHook("start-serviceaccount-tokens-controller", func(context genericapiserver.PostStartHookContext) error {
+			return startServiceAccountTokensController(context.LoopbackClientConfig, c.ServiceAccountTokenGenerator, c.ServiceAccountRootCA, c.ConcurrentServiceAccountTokenSyncs, context.StopCh)
+		})
+	}
+	if c.EnableGarbageCollector {
+		m.GenericAPIServer.AddPostStartHook("start-garbage-collector", func(context genericapiserver.PostStartHookContext) error {
+			return startGarbageCollector(context.LoopbackClientConfig, c.ConcurrentGCSyncs, context.StopCh)
+		})
 	}
 	return m, nil
-}
\ No newline at end of file
+}
+
+// InstallLegacyAPI serves the legacy core API group under /api and registers
+// its resources for storage migration.
+func (m *Master) InstallLegacyAPI(restOptionsGetter generic.RESTOptionsGetter, legacyRESTStorageProvider corerest.LegacyRESTStorageProvider) error {
+	legacyRESTStorage, apiGroupInfo, err := legacyRESTStorageProvider.NewLegacyRESTStorage(restOptionsGetter)
+	if err != nil {
+		return fmt.Errorf("error building core storage: %v", err)
+	}
+
+	if err := m.GenericAPIServer.InstallLegacyAPIGroup(genericapiserver.DefaultLegacyAPIPrefix, &apiGroupInfo); err != nil {
+		return fmt.Errorf("error in registering group versions: %v", err)
+	}
+	m.storageMigrationResources = append(m.storageMigrationResources, legacyRESTStorage.MigrationResources...)
+	return nil
+}
+
+// StorageMigrator returns a migrator rewriting the stored objects of the
+// resources installed by the master. Resources served by delegated servers,
+// such as custom resources, are not part of it. Running the migrator fails if
+// no resource was installed.
+func (m *Master) StorageMigrator() *migration.Migrator {
+	return &migration.Migrator{
+		Resources:      m.storageMigrationResources,
+		CheckpointFile: m.storageMigrationCheckpointFile,
+	}
+}
+
+// startNamespaceController runs the controller purging the content of terminating
+// namespaces through the loopback client of the server.
+func startNamespaceController(clientConfig *restclient.Config, resyncPeriod time.Duration, workers int, stopCh <-chan struct{}) error {
+	if clientConfig == nil {
+		return fmt.Errorf("the namespace controller requires a loopback client configuration")
+	}
+	client, err := clientset.NewForConfig(clientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create the namespace controller client: %v", err)
+	}
+	// the dynamic client pool reaches the resources of every discovered group, including custom resources.
+	clientPool := dynamic.NewDynamicClientPool(clientConfig)
+	sharedInformers := informers.NewSharedInformerFactory(client, resyncPeriod)
+
+	namespaceController := namespacecontroller.NewNamespaceController(
+		client,
+		clientPool,
+		client.Discovery().ServerPreferredNamespacedResources,
+		sharedInformers.Core().V1().Namespaces(),
+		resyncPeriod,
+		v1.FinalizerKubernetes,
+	)
+	sharedInformers.Start(stopCh)
+	go namespaceController.Run(workers, stopCh)
+	return nil
+}
+
+// startServiceAccountTokensController runs the controller minting a token secret for every
+// service account through the loopback client of the server.
+func startServiceAccountTokensController(clientConfig *restclient.Config, tokenGenerator serviceaccount.TokenGenerator, rootCA []byte, workers int, stopCh <-chan struct{}) error {
+	if clientConfig == nil {
+		return fmt.Errorf("the service account tokens controller requires a loopback client configuration")
+	}
+	client, err := clientset.NewForConfig(clientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create the service account tokens controller client: %v", err)
+	}
+	if len(rootCA) == 0 {
+		rootCA = clientConfig.CAData
+	}
+	sharedInformers := informers.NewSharedInformerFactory(client, 0)
+
+	tokensController := serviceaccountcontroller.NewTokensController(
+		sharedInformers.Core().V1().ServiceAccounts(),
+		sharedInformers.Core().V1().Secrets(),
+		client,
+		serviceaccountcontroller.TokensControllerOptions{
+			TokenGenerator: tokenGenerator,
+			RootCA:         rootCA,
+		},
+	)
+	sharedInformers.Start(stopCh)
+	go tokensController.Run(workers, stopCh)
+	return nil
+}
+
+// startGarbageCollector runs the garbage collector deleting the dependents of deleted
+// owners through the loopback client of the server.
+func startGarbageCollector(clientConfig *restclient.Config, workers int, stopCh <-chan struct{}) error {
+	if clientConfig == nil {
+		return fmt.Errorf("the garbage collector requires a loopback client configuration")
+	}
+	client, err := clientset.NewForConfig(clientConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create the garbage collector client: %v", err)
+	}
+	discoveryClient := cacheddiscovery.NewMemCacheClient(client.Discovery())
+	restMapper := discovery.NewDeferredDiscoveryRESTMapper(discoveryClient, meta.InterfacesForUnstructured)
+	// fill the discovery cache, the mapper does not fill it on its own
+	restMapper.Reset()
+
+	// the garbage collector handles every resource, including custom resources, as unstructured objects.
+	config := *clientConfig
+	config.ContentConfig = dynamic.ContentConfig()
+	clientPool := dynamic.NewClientPool(&config, restMapper, dynamic.LegacyAPIPathResolverFunc)
+
+	// Get an initial set of deletable resources to prime the garbage collector.
+	deletableResources := garbagecollector.GetDeletableResources(discoveryClient)
+	garbageCollector, err := garbagecollector.NewGarbageCollector(
+		clientPool,
+		restMapper,
+		deletableResources,
+		garbagecollector.DefaultIgnoredResources(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start the garbage collector: %v", err)
+	}
+	go garbageCollector.Run(workers, stopCh)
+
+	// Periodically refresh the RESTMapper with new discovery information and sync
+	// the garbage collector.
+	go garbageCollector.Sync(client.Discovery(), gcDiscoverySyncPeriod, stopCh)
+	return nil
+}
@@ -0,0 +1,105 @@
+package internalversion
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+)
+
+var objectMetaDescriptions = metav1.ObjectMeta{}.SwaggerDoc()
+
+var (
+	nameColumn = metav1beta1.TableColumnDefinition{Name: "Name", Type: "string", Format: "name", Description: objectMetaDescriptions["name"]}
+	ageColumn  = metav1beta1.TableColumnDefinition{Name: "Age", Type: "string", Description: objectMetaDescriptions["creationTimestamp"]}
+)
+
+// AddHandlers adds the print handlers of the core resources to h.
+func AddHandlers(h printers.PrintHandler) {
+	configMapColumnDefinitions := []metav1beta1.TableColumnDefinition{
+		nameColumn,
+		{Name: "Data", Type: "integer", Description: "Number of keys in the config map."},
+		ageColumn,
+	}
+	h.TableHandler(configMapColumnDefinitions, printConfigMap)
+
+	eventColumnDefinitions := []metav1beta1.TableColumnDefinition{
+		{Name: "Last Seen", Type: "string", Description: "The time at which the most recent occurrence of this event was recorded."},
+		{Name: "First Seen", Type: "string", Description: "The time at which the event was first recorded."},
+		{Name: "Count", Type: "integer", Description: "The number of times this event has occurred."},
+		nameColumn,
+		{Name: "Kind", Type: "string", Description: "Kind of the object this event is about."},
+		{Name: "Subobject", Type: "string", Description: "Field of the object this event is about, if any."},
+		{Name: "Type", Type: "string", Description: "Type of this event (Normal, Warning)."},
+		{Name: "Reason", Type: "string", Description: "Short, machine understandable reason for the event."},
+		{Name: "Source", Type: "string", Description: "The component and host reporting this event."},
+		{Name: "Message", Type: "string", Description: "A human-readable description of the event."},
+	}
+	h.TableHandler(eventColumnDefinitions, printEvent)
+
+	namespaceColumnDefinitions := []metav1beta1.TableColumnDefinition{
+		nameColumn,
+		{Name: "Status", Type: "string", Description: "The lifecycle phase of the namespace."},
+		ageColumn,
+	}
+	h.TableHandler(namespaceColumnDefinitions, printNamespace)
+
+	secretColumnDefinitions := []metav1beta1.TableColumnDefinition{
+		nameColumn,
+		{Name: "Type", Type: "string", Description: "Used to facilitate programmatic handling of secret data."},
+		{Name: "Data", Type: "integer", Description: "Number of keys in the secret."},
+		ageColumn,
+	}
+	h.TableHandler(secretColumnDefinitions, printSecret)
+
+	serviceAccountColumnDefinitions := []metav1beta1.TableColumnDefinition{
+		nameColumn,
+		{Name: "Secrets", Type: "integer", Description: "Number of secrets the service account may use."},
+		ageColumn,
+	}
+	h.TableHandler(serviceAccountColumnDefinitions, printServiceAccount)
+}
+
+func printConfigMap(obj *api.ConfigMap) ([]interface{}, error) {
+	return []interface{}{obj.Name, len(obj.Data), rest.TranslateTimestamp(obj.CreationTimestamp)}, nil
+}
+
+func printEvent(obj *api.Event) ([]interface{}, error) {
+	return []interface{}{
+		rest.TranslateTimestamp(obj.LastTimestamp),
+		rest.TranslateTimestamp(obj.FirstTimestamp),
+		obj.Count,
+		obj.Name,
+		obj.InvolvedObject.Kind,
+		obj.InvolvedObject.FieldPath,
+		obj.Type,
+		obj.Reason,
+		formatEventSource(obj.Source),
+		obj.Message,
+	}, nil
+}
+
+// formatEventSource formats EventSource as a comma separated string excluding Host when empty
+func formatEventSource(es api.EventSource) string {
+	sources := []string{es.Component}
+	if len(es.Host) > 0 {
+		sources = append(sources, es.Host)
+	}
+	return strings.Join(sources, ", ")
+}
+
+func printNamespace(obj *api.Namespace) ([]interface{}, error) {
+	return []interface{}{obj.Name, string(obj.Status.Phase), rest.TranslateTimestamp(obj.CreationTimestamp)}, nil
+}
+
+func printSecret(obj *api.Secret) ([]interface{}, error) {
+	return []interface{}{obj.Name, string(obj.Type), len(obj.Data), rest.TranslateTimestamp(obj.CreationTimestamp)}, nil
+}
+
+func printServiceAccount(obj *api.ServiceAccount) ([]interface{}, error) {
+	return []interface{}{obj.Name, len(obj.Secrets), rest.TranslateTimestamp(obj.CreationTimestamp)}, nil
+}
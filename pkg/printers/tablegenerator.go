@@ -0,0 +1,136 @@
+package printers
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+)
+
+// PrintHandler registers the columns of a type and the function that renders an
+// object of that type as the cells of a row.
+type PrintHandler interface {
+	TableHandler(columns []metav1beta1.TableColumnDefinition, printFunc interface{}) error
+}
+
+type handlerEntry struct {
+	columnDefinitions []metav1beta1.TableColumnDefinition
+	printFunc         reflect.Value
+}
+
+// TableGenerator renders objects, or lists of objects, of the registered types as a
+// metav1beta1.Table. It implements rest.TableConvertor.
+type TableGenerator struct {
+	handlerMap map[reflect.Type]*handlerEntry
+}
+
+var _ PrintHandler = &TableGenerator{}
+var _ rest.TableConvertor = &TableGenerator{}
+
+// NewTableGenerator creates a TableGenerator without handlers.
+func NewTableGenerator() *TableGenerator {
+	return &TableGenerator{handlerMap: make(map[reflect.Type]*handlerEntry)}
+}
+
+// With calls each of fns to register its handlers and returns the generator.
+func (h *TableGenerator) With(fns ...func(PrintHandler)) *TableGenerator {
+	for _, fn := range fns {
+		fn(h)
+	}
+	return h
+}
+
+// TableHandler registers printFunc for the type of its only argument. printFunc
+// must be of the form func(obj *T) ([]interface{}, error) and return one cell
+// per column.
+func (h *TableGenerator) TableHandler(columns []metav1beta1.TableColumnDefinition, printFunc interface{}) error {
+	printFuncValue := reflect.ValueOf(printFunc)
+	if err := validatePrintHandlerFunc(printFuncValue); err != nil {
+		return err
+	}
+	objType := printFuncValue.Type().In(0)
+	if _, ok := h.handlerMap[objType]; ok {
+		return fmt.Errorf("registered duplicate printer for %v", objType)
+	}
+	h.handlerMap[objType] = &handlerEntry{
+		columnDefinitions: columns,
+		printFunc:         printFuncValue,
+	}
+	return nil
+}
+
+// validatePrintHandlerFunc validates the signature of a print function.
+func validatePrintHandlerFunc(printFunc reflect.Value) error {
+	if printFunc.Kind() != reflect.Func {
+		return fmt.Errorf("invalid print handler, not a function")
+	}
+	funcType := printFunc.Type()
+	if funcType.NumIn() != 1 || funcType.NumOut() != 2 {
+		return fmt.Errorf("invalid print handler, must be of the form func(obj *T) ([]interface{}, error)")
+	}
+	if funcType.In(0).Kind() != reflect.Ptr {
+		return fmt.Errorf("invalid print handler, argument must be a pointer, got %v", funcType.In(0))
+	}
+	if funcType.Out(0) != reflect.TypeOf([]interface{}{}) || funcType.Out(1) != reflect.TypeOf((*error)(nil)).Elem() {
+		return fmt.Errorf("invalid print handler, return values must be ([]interface{}, error), got (%v, %v)", funcType.Out(0), funcType.Out(1))
+	}
+	return nil
+}
+
+// ConvertToTable implements rest.TableConvertor. Lists are rendered with the
+// handler of their item type.
+func (h *TableGenerator) ConvertToTable(ctx genericapirequest.Context, object runtime.Object, tableOptions runtime.Object) (*metav1beta1.Table, error) {
+	items := []runtime.Object{object}
+	objType := reflect.TypeOf(object)
+	if meta.IsListType(object) {
+		var err error
+		if items, err = meta.ExtractList(object); err != nil {
+			return nil, err
+		}
+		objType = listItemType(object)
+	}
+	entry, ok := h.handlerMap[objType]
+	if !ok {
+		return nil, fmt.Errorf("no table handler registered for %T", object)
+	}
+
+	table := &metav1beta1.Table{ColumnDefinitions: entry.columnDefinitions}
+	for _, item := range items {
+		if reflect.TypeOf(item) != objType {
+			return nil, fmt.Errorf("unexpected item of type %T in %T", item, object)
+		}
+		results := entry.printFunc.Call([]reflect.Value{reflect.ValueOf(item)})
+		if err, _ := results[1].Interface().(error); err != nil {
+			return nil, err
+		}
+		cells := results[0].Interface().([]interface{})
+		if len(cells) != len(entry.columnDefinitions) {
+			return nil, fmt.Errorf("table handler for %T returned %d cells for %d columns", item, len(cells), len(entry.columnDefinitions))
+		}
+		table.Rows = append(table.Rows, metav1beta1.TableRow{
+			Cells:  cells,
+			Object: runtime.RawExtension{Object: item},
+		})
+	}
+	rest.SetTableListMeta(table, object)
+	return table, nil
+}
+
+// listItemType returns the pointer type of the items of list, or nil if list has
+// no Items slice of structs.
+func listItemType(list runtime.Object) reflect.Type {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	items := v.Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice || items.Type().Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return reflect.PtrTo(items.Type().Elem())
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+	printersinternal "github.com/mqshen/HuZhou/pkg/printers/internalversion"
+	"github.com/mqshen/HuZhou/pkg/registry/core/configmap"
+)
+
+// REST implements a RESTStorage for ConfigMap
+type REST struct {
+	*genericregistry.Store
+}
+
+// NewREST returns a RESTStorage object that will work with ConfigMap objects.
+func NewREST(optsGetter generic.RESTOptionsGetter) (*REST, error) {
+	store := &genericregistry.Store{
+		NewFunc:                  func() runtime.Object { return &api.ConfigMap{} },
+		NewListFunc:              func() runtime.Object { return &api.ConfigMapList{} },
+		DefaultQualifiedResource: api.Resource("configmaps"),
+		TableConvertor:           printers.NewTableGenerator().With(printersinternal.AddHandlers),
+
+		CreateStrategy: configmap.Strategy,
+		UpdateStrategy: configmap.Strategy,
+		DeleteStrategy: configmap.Strategy,
+	}
+	options := &generic.StoreOptions{RESTOptions: optsGetter}
+	if err := store.CompleteWithOptions(options); err != nil {
+		return nil, err
+	}
+	return &REST{store}, nil
+}
+
+// Implement ShortNamesProvider
+var _ rest.ShortNamesProvider = &REST{}
+
+// ShortNames implements the ShortNamesProvider interface. Returns a list of short names for a resource.
+func (r *REST) ShortNames() []string {
+	return []string{"cm"}
+}
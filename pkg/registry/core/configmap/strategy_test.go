@@ -0,0 +1,58 @@
+package configmap
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+func TestConfigMapStrategy(t *testing.T) {
+	ctx := genericapirequest.NewDefaultContext()
+	if !Strategy.NamespaceScoped() {
+		t.Errorf("configmaps must be namespace scoped")
+	}
+	if Strategy.AllowCreateOnUpdate() {
+		t.Errorf("configmaps should not allow create on update")
+	}
+
+	newConfigMap := func(name string, data map[string]string) *api.ConfigMap {
+		return &api.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault, ResourceVersion: "1"},
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name string
+		obj  *api.ConfigMap
+		// old is validated as an update to obj when it is set
+		old       *api.ConfigMap
+		expectErr bool
+	}{
+		{name: "valid", obj: newConfigMap("foo", map[string]string{"key": "value", "file.txt": "value"})},
+		{name: "invalid name", obj: newConfigMap("Foo_", nil), expectErr: true},
+		{name: "invalid key", obj: newConfigMap("foo", map[string]string{"a/b": "value"}), expectErr: true},
+		{name: "too large", obj: newConfigMap("foo", map[string]string{"key": strings.Repeat("a", api.MaxSecretSize+1)}), expectErr: true},
+		{name: "update", obj: newConfigMap("foo", map[string]string{"key": "other"}), old: newConfigMap("foo", map[string]string{"key": "value"})},
+		{name: "update with an invalid key", obj: newConfigMap("foo", map[string]string{"a b": "value"}), old: newConfigMap("foo", nil), expectErr: true},
+		{name: "update renaming", obj: newConfigMap("bar", nil), old: newConfigMap("foo", nil), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		if tt.old == nil {
+			Strategy.PrepareForCreate(ctx, tt.obj)
+			if errs := Strategy.Validate(ctx, tt.obj); (len(errs) != 0) != tt.expectErr {
+				t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+			}
+			continue
+		}
+		Strategy.PrepareForUpdate(ctx, tt.obj, tt.old)
+		if errs := Strategy.ValidateUpdate(ctx, tt.obj, tt.old); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+	printersinternal "github.com/mqshen/HuZhou/pkg/printers/internalversion"
+	"github.com/mqshen/HuZhou/pkg/registry/core/event"
+)
+
+// REST implements a RESTStorage for events.
+type REST struct {
+	*genericregistry.Store
+}
+
+// NewREST returns a RESTStorage object that will work against events.
+// Events are removed from storage ttl seconds after their last update.
+func NewREST(optsGetter generic.RESTOptionsGetter, ttl uint64) (*REST, error) {
+	store := &genericregistry.Store{
+		NewFunc:       func() runtime.Object { return &api.Event{} },
+		NewListFunc:   func() runtime.Object { return &api.EventList{} },
+		PredicateFunc: event.MatchEvent,
+		TTLFunc: func(runtime.Object, uint64, bool) (uint64, error) {
+			return ttl, nil
+		},
+		DefaultQualifiedResource: api.Resource("events"),
+		TableConvertor:           printers.NewTableGenerator().With(printersinternal.AddHandlers),
+
+		CreateStrategy: event.Strategy,
+		UpdateStrategy: event.Strategy,
+		DeleteStrategy: event.Strategy,
+	}
+	options := &generic.StoreOptions{RESTOptions: optsGetter, AttrFunc: event.GetAttrs}
+	if err := store.CompleteWithOptions(options); err != nil {
+		return nil, err
+	}
+	return &REST{store}, nil
+}
+
+// Implement ShortNamesProvider
+var _ rest.ShortNamesProvider = &REST{}
+
+// ShortNames implements the ShortNamesProvider interface. Returns a list of short names for a resource.
+func (r *REST) ShortNames() []string {
+	return []string{"ev"}
+}
@@ -0,0 +1,95 @@
+package event
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/names"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/api/validation"
+)
+
+// eventStrategy implements behavior for Events
+type eventStrategy struct {
+	runtime.ObjectTyper
+	names.NameGenerator
+}
+
+// Strategy is the default logic that applies when creating and updating
+// Event objects via the REST API.
+var Strategy = eventStrategy{api.Scheme, names.SimpleNameGenerator}
+
+func (eventStrategy) NamespaceScoped() bool {
+	return true
+}
+
+func (eventStrategy) PrepareForCreate(ctx genericapirequest.Context, obj runtime.Object) {
+}
+
+func (eventStrategy) PrepareForUpdate(ctx genericapirequest.Context, obj, old runtime.Object) {
+}
+
+func (eventStrategy) Validate(ctx genericapirequest.Context, obj runtime.Object) field.ErrorList {
+	event := obj.(*api.Event)
+	return validation.ValidateEvent(event)
+}
+
+// Canonicalize normalizes the object after validation.
+func (eventStrategy) Canonicalize(obj runtime.Object) {
+}
+
+func (eventStrategy) AllowCreateOnUpdate() bool {
+	return true
+}
+
+func (eventStrategy) ValidateUpdate(ctx genericapirequest.Context, obj, old runtime.Object) field.ErrorList {
+	event := obj.(*api.Event)
+	return validation.ValidateEvent(event)
+}
+
+func (eventStrategy) AllowUnconditionalUpdate() bool {
+	return true
+}
+
+// GetAttrs returns labels and fields of a given object for filtering purposes.
+func GetAttrs(obj runtime.Object) (labels.Set, fields.Set, bool, error) {
+	event, ok := obj.(*api.Event)
+	if !ok {
+		return nil, nil, false, fmt.Errorf("not an event")
+	}
+	return labels.Set(event.Labels), EventToSelectableFields(event), event.Initializers != nil, nil
+}
+
+// MatchEvent returns a generic matcher for a given label and field selector.
+func MatchEvent(label labels.Selector, field fields.Selector) storage.SelectionPredicate {
+	return storage.SelectionPredicate{
+		Label:    label,
+		Field:    field,
+		GetAttrs: GetAttrs,
+	}
+}
+
+// EventToSelectableFields returns a field set that represents the object.
+func EventToSelectableFields(event *api.Event) fields.Set {
+	objectMetaFieldsSet := generic.ObjectMetaFieldsSet(&event.ObjectMeta, true)
+	specificFieldsSet := fields.Set{
+		"involvedObject.kind":            event.InvolvedObject.Kind,
+		"involvedObject.namespace":       event.InvolvedObject.Namespace,
+		"involvedObject.name":            event.InvolvedObject.Name,
+		"involvedObject.uid":             string(event.InvolvedObject.UID),
+		"involvedObject.apiVersion":      event.InvolvedObject.APIVersion,
+		"involvedObject.resourceVersion": event.InvolvedObject.ResourceVersion,
+		"involvedObject.fieldPath":       event.InvolvedObject.FieldPath,
+		"reason":                         event.Reason,
+		"source":                         event.Source.Component,
+		"type":                           event.Type,
+	}
+	return generic.MergeFieldsSets(objectMetaFieldsSet, specificFieldsSet)
+}
@@ -0,0 +1,68 @@
+package event
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+func TestEventStrategy(t *testing.T) {
+	ctx := genericapirequest.NewDefaultContext()
+	if !Strategy.NamespaceScoped() {
+		t.Errorf("events must be namespace scoped")
+	}
+	if !Strategy.AllowCreateOnUpdate() {
+		t.Errorf("events should allow create on update")
+	}
+
+	tests := []struct {
+		name              string
+		namespace         string
+		involvedNamespace string
+		expectErr         bool
+	}{
+		{name: "same namespace", namespace: "ns", involvedNamespace: "ns"},
+		{name: "other namespace", namespace: "ns", involvedNamespace: "other", expectErr: true},
+		{name: "event without namespace", namespace: "", expectErr: true},
+		{name: "cluster scoped object in default", namespace: metav1.NamespaceDefault},
+		{name: "cluster scoped object in namespace", namespace: "ns", expectErr: true},
+		{name: "invalid namespace", namespace: "NS", involvedNamespace: "NS", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		event := &api.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "foo", Namespace: tt.namespace, ResourceVersion: "1"},
+			InvolvedObject: api.ObjectReference{Kind: "Pod", Namespace: tt.involvedNamespace, Name: "bar"},
+		}
+		if errs := Strategy.Validate(ctx, event); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+		if errs := Strategy.ValidateUpdate(ctx, event, event.DeepCopy()); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected update error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+	}
+}
+
+func TestEventToSelectableFields(t *testing.T) {
+	event := &api.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+		InvolvedObject: api.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "bar"},
+		Reason:         "Started",
+	}
+	fields := EventToSelectableFields(event)
+	expected := map[string]string{
+		"metadata.name":            "foo",
+		"involvedObject.kind":      "Pod",
+		"involvedObject.namespace": "ns",
+		"involvedObject.name":      "bar",
+		"reason":                   "Started",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected field %s to be %q, got %q", key, value, fields[key])
+		}
+	}
+}
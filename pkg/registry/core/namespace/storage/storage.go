@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/HuZhou/apiserver/pkg/storage"
+	storageerr "github.com/HuZhou/apiserver/pkg/storage/errors"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+	printersinternal "github.com/mqshen/HuZhou/pkg/printers/internalversion"
+	"github.com/mqshen/HuZhou/pkg/registry/core/namespace"
+)
+
+// REST implements a RESTStorage for namespaces.
+type REST struct {
+	// Store is the underlying storage of namespaces. It is not embedded so
+	// that deleting a namespace always goes through finalization.
+	Store *genericregistry.Store
+}
+
+// StatusREST implements the REST endpoint for changing the status of a namespace.
+type StatusREST struct {
+	store *genericregistry.Store
+}
+
+// FinalizeREST implements the REST endpoint for finalizing a namespace.
+type FinalizeREST struct {
+	store *genericregistry.Store
+}
+
+// NewREST returns a RESTStorage object that will work against namespaces.
+func NewREST(optsGetter generic.RESTOptionsGetter) (*REST, *StatusREST, *FinalizeREST, error) {
+	store := &genericregistry.Store{
+		NewFunc:                  func() runtime.Object { return &api.Namespace{} },
+		NewListFunc:              func() runtime.Object { return &api.NamespaceList{} },
+		PredicateFunc:            namespace.MatchNamespace,
+		DefaultQualifiedResource: api.Resource("namespaces"),
+		TableConvertor:           printers.NewTableGenerator().With(printersinternal.AddHandlers),
+
+		CreateStrategy:      namespace.Strategy,
+		UpdateStrategy:      namespace.Strategy,
+		DeleteStrategy:      namespace.Strategy,
+		ReturnDeletedObject: true,
+	}
+	options := &generic.StoreOptions{RESTOptions: optsGetter, AttrFunc: namespace.GetAttrs}
+	if err := store.CompleteWithOptions(options); err != nil {
+		return nil, nil, nil, err
+	}
+
+	statusStore := *store
+	statusStore.UpdateStrategy = namespace.StatusStrategy
+
+	finalizeStore := *store
+	finalizeStore.UpdateStrategy = namespace.FinalizeStrategy
+
+	return &REST{Store: store}, &StatusREST{store: &statusStore}, &FinalizeREST{store: &finalizeStore}, nil
+}
+
+func (r *REST) NamespaceScoped() bool {
+	return r.Store.NamespaceScoped()
+}
+
+func (r *REST) New() runtime.Object {
+	return r.Store.New()
+}
+
+func (r *REST) NewList() runtime.Object {
+	return r.Store.NewList()
+}
+
+func (r *REST) List(ctx genericapirequest.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	return r.Store.List(ctx, options)
+}
+
+func (r *REST) Create(ctx genericapirequest.Context, obj runtime.Object, includeUninitialized bool) (runtime.Object, error) {
+	return r.Store.Create(ctx, obj, includeUninitialized)
+}
+
+func (r *REST) Update(ctx genericapirequest.Context, name string, objInfo rest.UpdatedObjectInfo) (runtime.Object, bool, error) {
+	return r.Store.Update(ctx, name, objInfo)
+}
+
+func (r *REST) Get(ctx genericapirequest.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	return r.Store.Get(ctx, name, options)
+}
+
+func (r *REST) Watch(ctx genericapirequest.Context, options *metainternalversion.ListOptions) (watch.Interface, error) {
+	return r.Store.Watch(ctx, options)
+}
+
+func (r *REST) ConvertToTable(ctx genericapirequest.Context, object runtime.Object, tableOptions runtime.Object) (*metav1beta1.Table, error) {
+	return r.Store.ConvertToTable(ctx, object, tableOptions)
+}
+
+func (r *REST) SupportsDryRun() bool {
+	return r.Store.SupportsDryRun()
+}
+
+// Delete enforces life-cycle rules for namespace termination
+func (r *REST) Delete(ctx genericapirequest.Context, name string, options *metav1.DeleteOptions) (runtime.Object, bool, error) {
+	nsObj, err := r.Get(ctx, name, &metav1.GetOptions{IncludeUninitialized: true})
+	if err != nil {
+		return nil, false, err
+	}
+
+	namespace := nsObj.(*api.Namespace)
+
+	// Ensure we have a UID precondition
+	if options == nil {
+		options = metav1.NewDeleteOptions(0)
+	}
+	if options.Preconditions == nil {
+		options.Preconditions = &metav1.Preconditions{}
+	}
+	if options.Preconditions.UID == nil {
+		options.Preconditions.UID = &namespace.UID
+	} else if *options.Preconditions.UID != namespace.UID {
+		err = apierrors.NewConflict(
+			api.Resource("namespaces"),
+			name,
+			fmt.Errorf("Precondition failed: UID in precondition: %v, UID in object meta: %v", *options.Preconditions.UID, namespace.UID),
+		)
+		return nil, false, err
+	}
+
+	// upon first request to delete, we switch the phase to start namespace termination
+	if namespace.DeletionTimestamp.IsZero() {
+		key, err := r.Store.KeyFunc(ctx, name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		preconditions := storage.Preconditions{UID: options.Preconditions.UID}
+
+		out := r.Store.NewFunc()
+		err = r.Store.Storage.GuaranteedUpdate(
+			ctx, key, out, false, &preconditions,
+			storage.SimpleUpdate(func(existing runtime.Object) (runtime.Object, error) {
+				existingNamespace, ok := existing.(*api.Namespace)
+				if !ok {
+					// wrong type
+					return nil, fmt.Errorf("expected *api.Namespace, got %v", existing)
+				}
+				// Set the deletion timestamp if needed
+				if existingNamespace.DeletionTimestamp.IsZero() {
+					now := metav1.Now()
+					existingNamespace.DeletionTimestamp = &now
+				}
+				// Set the namespace phase to terminating, if needed
+				if existingNamespace.Status.Phase != api.NamespaceTerminating {
+					existingNamespace.Status.Phase = api.NamespaceTerminating
+				}
+				return existingNamespace, nil
+			}),
+		)
+
+		if err != nil {
+			err = storageerr.InterpretGetError(err, api.Resource("namespaces"), name)
+			err = storageerr.InterpretUpdateError(err, api.Resource("namespaces"), name)
+			if _, ok := err.(*apierrors.StatusError); !ok {
+				err = apierrors.NewInternalError(err)
+			}
+			return nil, false, err
+		}
+
+		return out, false, nil
+	}
+
+	// prior to final deletion, we must ensure that finalizers is empty
+	if len(namespace.Spec.Finalizers) != 0 {
+		err = apierrors.NewConflict(api.Resource("namespaces"), namespace.Name, fmt.Errorf("The system is ensuring all content is removed from this namespace.  Upon completion, this namespace will automatically be purged by the system."))
+		return nil, false, err
+	}
+	return r.Store.Delete(ctx, name, options)
+}
+
+// Implement ShortNamesProvider
+var _ rest.ShortNamesProvider = &REST{}
+
+// ShortNames implements the ShortNamesProvider interface. Returns a list of short names for a resource.
+func (r *REST) ShortNames() []string {
+	return []string{"ns"}
+}
+
+func (r *StatusREST) New() runtime.Object {
+	return r.store.New()
+}
+
+// Get retrieves the object from the storage. It is required to support Patch.
+func (r *StatusREST) Get(ctx genericapirequest.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	return r.store.Get(ctx, name, options)
+}
+
+// Update alters the status subset of an object.
+func (r *StatusREST) Update(ctx genericapirequest.Context, name string, objInfo rest.UpdatedObjectInfo) (runtime.Object, bool, error) {
+	return r.store.Update(ctx, name, objInfo)
+}
+
+func (r *StatusREST) SupportsDryRun() bool {
+	return r.store.SupportsDryRun()
+}
+
+func (r *FinalizeREST) New() runtime.Object {
+	return r.store.New()
+}
+
+// Update alters the status finalizers subset of an object.
+func (r *FinalizeREST) Update(ctx genericapirequest.Context, name string, objInfo rest.UpdatedObjectInfo) (runtime.Object, bool, error) {
+	return r.store.Update(ctx, name, objInfo)
+}
+
+func (r *FinalizeREST) SupportsDryRun() bool {
+	return r.store.SupportsDryRun()
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/HuZhou/api/core/v1"
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+	"github.com/mqshen/HuZhou/pkg/api"
+	_ "github.com/mqshen/HuZhou/pkg/api/install"
+)
+
+// testRESTOptionsGetter keeps namespaces in memory below a prefix of their own.
+type testRESTOptionsGetter struct {
+	prefix string
+}
+
+func (g testRESTOptionsGetter) GetRESTOptions(resource schema.GroupResource) (generic.RESTOptions, error) {
+	return generic.RESTOptions{
+		StorageConfig: &storagebackend.Config{
+			Type:   storagebackend.StorageTypeMemory,
+			Prefix: "/registry",
+			Codec:  api.Codecs.LegacyCodec(v1.SchemeGroupVersion),
+		},
+		Decorator:      generic.UndecoratedStorage,
+		ResourcePrefix: g.prefix,
+	}, nil
+}
+
+func newStorage(t *testing.T) (*REST, *StatusREST, *FinalizeREST) {
+	// the memory backend is shared by the process, so every test keeps its
+	// namespaces apart
+	storage, statusStorage, finalizeStorage, err := NewREST(testRESTOptionsGetter{prefix: "/" + t.Name()})
+	if err != nil {
+		t.Fatalf("NewREST failed: %v", err)
+	}
+	return storage, statusStorage, finalizeStorage
+}
+
+func getNamespace(t *testing.T, storage *REST, name string) *api.Namespace {
+	obj, err := storage.Get(genericapirequest.NewContext(), name, &metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get of %s failed: %v", name, err)
+	}
+	return obj.(*api.Namespace)
+}
+
+func updateNamespace(namespace *api.Namespace) rest.UpdatedObjectInfo {
+	return rest.DefaultUpdatedObjectInfo(namespace, func(ctx genericapirequest.Context, obj, old runtime.Object) (runtime.Object, error) {
+		return obj, nil
+	})
+}
+
+func TestDeleteNamespace(t *testing.T) {
+	storage, _, finalizeStorage := newStorage(t)
+	ctx := genericapirequest.NewContext()
+	if _, err := storage.Create(ctx, &api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, false); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// the first delete only starts the termination
+	if _, deleted, err := storage.Delete(ctx, "foo", nil); err != nil || deleted {
+		t.Fatalf("expected the namespace to start terminating, got deleted=%t: %v", deleted, err)
+	}
+	namespace := getNamespace(t, storage, "foo")
+	if namespace.DeletionTimestamp.IsZero() || namespace.Status.Phase != api.NamespaceTerminating {
+		t.Fatalf("expected a terminating namespace, got %#v", namespace)
+	}
+
+	// the namespace is kept until it is finalized
+	if _, _, err := storage.Delete(ctx, "foo", nil); !apierrors.IsConflict(err) {
+		t.Fatalf("expected a conflict while the namespace has finalizers, got %v", err)
+	}
+
+	// finalizing changes the finalizers only
+	finalized := namespace.DeepCopy()
+	finalized.Spec.Finalizers = nil
+	finalized.Status.Phase = api.NamespaceActive
+	if _, _, err := finalizeStorage.Update(ctx, "foo", updateNamespace(finalized)); err != nil {
+		t.Fatalf("finalize failed: %v", err)
+	}
+	namespace = getNamespace(t, storage, "foo")
+	if len(namespace.Spec.Finalizers) != 0 || namespace.Status.Phase != api.NamespaceTerminating {
+		t.Fatalf("expected a finalized terminating namespace, got %#v", namespace)
+	}
+
+	if _, deleted, err := storage.Delete(ctx, "foo", nil); err != nil || !deleted {
+		t.Fatalf("expected the namespace to be deleted, got deleted=%t: %v", deleted, err)
+	}
+	if _, err := storage.Get(ctx, "foo", &metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the namespace to be removed, got %v", err)
+	}
+}
+
+func TestDeleteNamespacePreconditions(t *testing.T) {
+	storage, _, _ := newStorage(t)
+	ctx := genericapirequest.NewContext()
+	created, err := storage.Create(ctx, &api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, false)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	uid := created.(*api.Namespace).UID
+	otherUID := types.UID("other")
+
+	tests := []struct {
+		name      string
+		uid       *types.UID
+		expectErr bool
+	}{
+		{name: "other uid", uid: &otherUID, expectErr: true},
+		{name: "same uid", uid: &uid},
+	}
+
+	for _, tt := range tests {
+		_, _, err := storage.Delete(ctx, "foo", &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: tt.uid}})
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+		}
+		if tt.expectErr && !apierrors.IsConflict(err) {
+			t.Errorf("%s: expected a conflict, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestFinalizeNamespace(t *testing.T) {
+	tests := []struct {
+		name             string
+		finalizers       []api.FinalizerName
+		expectFinalizers []api.FinalizerName
+		expectErr        bool
+	}{
+		{name: "remove finalizers"},
+		{name: "qualified finalizer", finalizers: []api.FinalizerName{"example.com/finalizer"}, expectFinalizers: []api.FinalizerName{"example.com/finalizer"}},
+		{name: "unqualified finalizer", finalizers: []api.FinalizerName{"finalizer"}, expectErr: true},
+	}
+
+	for i, tt := range tests {
+		storage, _, finalizeStorage := newStorage(t)
+		ctx := genericapirequest.NewContext()
+		name := fmt.Sprintf("ns%d", i)
+		if _, err := storage.Create(ctx, &api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, false); err != nil {
+			t.Fatalf("%s: Create failed: %v", tt.name, err)
+		}
+
+		namespace := getNamespace(t, storage, name)
+		namespace.Spec.Finalizers = tt.finalizers
+		_, _, err := finalizeStorage.Update(ctx, name, updateNamespace(namespace))
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+			continue
+		}
+		if tt.expectErr {
+			continue
+		}
+		finalizers := getNamespace(t, storage, name).Spec.Finalizers
+		if !reflect.DeepEqual(finalizers, tt.expectFinalizers) {
+			t.Errorf("%s: expected finalizers %v, got %v", tt.name, tt.expectFinalizers, finalizers)
+		}
+	}
+}
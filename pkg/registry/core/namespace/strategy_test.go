@@ -0,0 +1,133 @@
+package namespace
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+func newNamespace(name string, phase api.NamespacePhase, finalizers ...api.FinalizerName) *api.Namespace {
+	return &api.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: "1"},
+		Spec:       api.NamespaceSpec{Finalizers: finalizers},
+		Status:     api.NamespaceStatus{Phase: phase},
+	}
+}
+
+func TestNamespaceStrategyCreate(t *testing.T) {
+	ctx := genericapirequest.NewContext()
+	if Strategy.NamespaceScoped() {
+		t.Errorf("namespaces must not be namespace scoped")
+	}
+	if Strategy.AllowCreateOnUpdate() {
+		t.Errorf("namespaces should not allow create on update")
+	}
+
+	tests := []struct {
+		name             string
+		namespace        *api.Namespace
+		expectFinalizers []api.FinalizerName
+		expectErr        bool
+	}{
+		{name: "no finalizers", namespace: newNamespace("foo", api.NamespaceTerminating), expectFinalizers: []api.FinalizerName{api.FinalizerKubernetes}},
+		{
+			name:             "other finalizer",
+			namespace:        newNamespace("foo", "", "example.com/finalizer"),
+			expectFinalizers: []api.FinalizerName{"example.com/finalizer", api.FinalizerKubernetes},
+		},
+		{
+			name:             "kubernetes finalizer",
+			namespace:        newNamespace("foo", "", api.FinalizerKubernetes),
+			expectFinalizers: []api.FinalizerName{api.FinalizerKubernetes},
+		},
+		{name: "invalid name", namespace: newNamespace("Foo", ""), expectFinalizers: []api.FinalizerName{api.FinalizerKubernetes}, expectErr: true},
+		{
+			name:             "unqualified finalizer",
+			namespace:        newNamespace("foo", "", "finalizer"),
+			expectFinalizers: []api.FinalizerName{"finalizer", api.FinalizerKubernetes},
+			expectErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		Strategy.PrepareForCreate(ctx, tt.namespace)
+		if tt.namespace.Status.Phase != api.NamespaceActive {
+			t.Errorf("%s: expected a new namespace to be active, got %q", tt.name, tt.namespace.Status.Phase)
+		}
+		if !reflect.DeepEqual(tt.namespace.Spec.Finalizers, tt.expectFinalizers) {
+			t.Errorf("%s: expected finalizers %v, got %v", tt.name, tt.expectFinalizers, tt.namespace.Spec.Finalizers)
+		}
+		if errs := Strategy.Validate(ctx, tt.namespace); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+	}
+}
+
+func TestNamespaceStrategyUpdate(t *testing.T) {
+	ctx := genericapirequest.NewContext()
+	old := newNamespace("foo", api.NamespaceActive, api.FinalizerKubernetes)
+	namespace := newNamespace("foo", api.NamespaceTerminating)
+	namespace.Labels = map[string]string{"foo": "bar"}
+
+	Strategy.PrepareForUpdate(ctx, namespace, old)
+	if !reflect.DeepEqual(namespace.Spec, old.Spec) || !reflect.DeepEqual(namespace.Status, old.Status) {
+		t.Errorf("expected an update to keep the spec and status, got %#v", namespace)
+	}
+	if errs := Strategy.ValidateUpdate(ctx, namespace, old); len(errs) != 0 {
+		t.Errorf("unexpected error: %v", errs)
+	}
+	if namespace.Labels["foo"] != "bar" {
+		t.Errorf("expected an update to change the labels, got %v", namespace.Labels)
+	}
+}
+
+func TestNamespaceStatusStrategy(t *testing.T) {
+	ctx := genericapirequest.NewContext()
+	now := metav1.Now()
+
+	tests := []struct {
+		name      string
+		deleting  bool
+		phase     api.NamespacePhase
+		expectErr bool
+	}{
+		{name: "active", phase: api.NamespaceActive},
+		{name: "terminating without deletion", phase: api.NamespaceTerminating, expectErr: true},
+		{name: "terminating", deleting: true, phase: api.NamespaceTerminating},
+		{name: "active while deleting", deleting: true, phase: api.NamespaceActive, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		old := newNamespace("foo", api.NamespaceActive, api.FinalizerKubernetes)
+		namespace := newNamespace("foo", tt.phase)
+		if tt.deleting {
+			old.DeletionTimestamp = &now
+			namespace.DeletionTimestamp = &now
+		}
+
+		StatusStrategy.PrepareForUpdate(ctx, namespace, old)
+		if !reflect.DeepEqual(namespace.Spec, old.Spec) {
+			t.Errorf("%s: expected a status update to keep the spec, got %#v", tt.name, namespace.Spec)
+		}
+		if namespace.Status.Phase != tt.phase {
+			t.Errorf("%s: expected phase %q, got %q", tt.name, tt.phase, namespace.Status.Phase)
+		}
+		if errs := StatusStrategy.ValidateUpdate(ctx, namespace, old); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+	}
+}
+
+func TestNamespaceToSelectableFields(t *testing.T) {
+	fields := NamespaceToSelectableFields(newNamespace("foo", api.NamespaceActive))
+	expected := map[string]string{"metadata.name": "foo", "name": "foo", "status.phase": string(api.NamespaceActive)}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected field %s to be %q, got %q", key, value, fields[key])
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/api/core/v1"
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/registry/generic/migration"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	genericapiserver "github.com/HuZhou/apiserver/pkg/server"
+	"github.com/mqshen/HuZhou/pkg/api"
+	configmapstore "github.com/mqshen/HuZhou/pkg/registry/core/configmap/storage"
+	eventstore "github.com/mqshen/HuZhou/pkg/registry/core/event/storage"
+	namespacestore "github.com/mqshen/HuZhou/pkg/registry/core/namespace/storage"
+	secretstore "github.com/mqshen/HuZhou/pkg/registry/core/secret/storage"
+	serviceaccountstore "github.com/mqshen/HuZhou/pkg/registry/core/serviceaccount/storage"
+)
+
+// LegacyRESTStorageProvider provides information needed to build RESTStorage for core, but
+// does NOT implement the "normal" RESTStorageProvider (yet!)
+type LegacyRESTStorageProvider struct {
+	// EventTTL is how long events are retained after their last update.
+	EventTTL time.Duration
+}
+
+// LegacyRESTStorage returns stateful information about particular instances of REST storage to
+// master.go for wiring controllers.
+type LegacyRESTStorage struct {
+	// MigrationResources are the resources of the legacy API group kept in storage.
+	MigrationResources []migration.Resource
+}
+
+func (c LegacyRESTStorageProvider) NewLegacyRESTStorage(restOptionsGetter generic.RESTOptionsGetter) (LegacyRESTStorage, genericapiserver.APIGroupInfo, error) {
+	apiGroupInfo := genericapiserver.APIGroupInfo{
+		PrioritizedVersions:          []schema.GroupVersion{v1.SchemeGroupVersion},
+		VersionedResourcesStorageMap: map[string]map[string]rest.Storage{},
+		Scheme:                       api.Scheme,
+		ParameterCodec:               api.ParameterCodec,
+		NegotiatedSerializer:         api.Codecs,
+	}
+
+	restStorage := LegacyRESTStorage{}
+
+	eventStorage, err := eventstore.NewREST(restOptionsGetter, uint64(c.EventTTL.Seconds()))
+	if err != nil {
+		return LegacyRESTStorage{}, genericapiserver.APIGroupInfo{}, err
+	}
+	namespaceStorage, namespaceStatusStorage, namespaceFinalizeStorage, err := namespacestore.NewREST(restOptionsGetter)
+	if err != nil {
+		return LegacyRESTStorage{}, genericapiserver.APIGroupInfo{}, err
+	}
+	secretStorage, err := secretstore.NewREST(restOptionsGetter)
+	if err != nil {
+		return LegacyRESTStorage{}, genericapiserver.APIGroupInfo{}, err
+	}
+	configMapStorage, err := configmapstore.NewREST(restOptionsGetter)
+	if err != nil {
+		return LegacyRESTStorage{}, genericapiserver.APIGroupInfo{}, err
+	}
+	serviceAccountStorage, err := serviceaccountstore.NewREST(restOptionsGetter)
+	if err != nil {
+		return LegacyRESTStorage{}, genericapiserver.APIGroupInfo{}, err
+	}
+
+	restStorageMap := map[string]rest.Storage{
+		"events": eventStorage,
+
+		"namespaces":          namespaceStorage,
+		"namespaces/status":   namespaceStatusStorage,
+		"namespaces/finalize": namespaceFinalizeStorage,
+
+		"secrets":         secretStorage,
+		"configmaps":      configMapStorage,
+		"serviceaccounts": serviceAccountStorage,
+	}
+	apiGroupInfo.VersionedResourcesStorageMap["v1"] = restStorageMap
+
+	restStorage.MigrationResources = []migration.Resource{
+		migration.ResourceForStore(api.Resource("events"), eventStorage.Store),
+		migration.ResourceForStore(api.Resource("namespaces"), namespaceStorage.Store),
+		migration.ResourceForStore(api.Resource("secrets"), secretStorage.Store),
+		migration.ResourceForStore(api.Resource("configmaps"), configMapStorage.Store),
+		migration.ResourceForStore(api.Resource("serviceaccounts"), serviceAccountStorage.Store),
+	}
+
+	return restStorage, apiGroupInfo, nil
+}
+
+func (p LegacyRESTStorageProvider) GroupName() string {
+	return api.GroupName
+}
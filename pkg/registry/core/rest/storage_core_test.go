@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/api/core/v1"
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+	"github.com/mqshen/HuZhou/pkg/api"
+	_ "github.com/mqshen/HuZhou/pkg/api/install"
+)
+
+// memoryRESTOptionsGetter keeps every resource in memory below its own prefix.
+type memoryRESTOptionsGetter struct{}
+
+func (memoryRESTOptionsGetter) GetRESTOptions(resource schema.GroupResource) (generic.RESTOptions, error) {
+	return generic.RESTOptions{
+		StorageConfig: &storagebackend.Config{
+			Type:   storagebackend.StorageTypeMemory,
+			Prefix: "/registry",
+			Codec:  api.Codecs.LegacyCodec(v1.SchemeGroupVersion),
+		},
+		Decorator:      generic.UndecoratedStorage,
+		ResourcePrefix: "/" + resource.Resource,
+	}, nil
+}
+
+func TestNewLegacyRESTStorage(t *testing.T) {
+	restStorage, apiGroupInfo, err := LegacyRESTStorageProvider{}.NewLegacyRESTStorage(memoryRESTOptionsGetter{})
+	if err != nil {
+		t.Fatalf("NewLegacyRESTStorage failed: %v", err)
+	}
+
+	if len(apiGroupInfo.PrioritizedVersions) != 1 || apiGroupInfo.PrioritizedVersions[0] != v1.SchemeGroupVersion {
+		t.Errorf("expected only %v to be served, got %v", v1.SchemeGroupVersion, apiGroupInfo.PrioritizedVersions)
+	}
+	resources := []string{}
+	for resource := range apiGroupInfo.VersionedResourcesStorageMap["v1"] {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	expected := []string{"configmaps", "events", "namespaces", "namespaces/finalize", "namespaces/status", "secrets", "serviceaccounts"}
+	if len(resources) != len(expected) {
+		t.Fatalf("expected resources %v, got %v", expected, resources)
+	}
+	for i := range expected {
+		if resources[i] != expected[i] {
+			t.Fatalf("expected resources %v, got %v", expected, resources)
+		}
+	}
+	if len(restStorage.MigrationResources) != 5 {
+		t.Errorf("expected every stored resource to be migrated, got %d", len(restStorage.MigrationResources))
+	}
+
+	ctx := genericapirequest.NewDefaultContext()
+	tests := []struct {
+		resource string
+		obj      runtime.Object
+	}{
+		{resource: "configmaps", obj: &api.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}}},
+		{resource: "secrets", obj: &api.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}}},
+		{resource: "serviceaccounts", obj: &api.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault}}},
+		{resource: "namespaces", obj: &api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}},
+	}
+	for _, tt := range tests {
+		storage := apiGroupInfo.VersionedResourcesStorageMap["v1"][tt.resource]
+		created, err := storage.(rest.Creater).Create(ctx, tt.obj, false)
+		if err != nil {
+			t.Errorf("%s: Create failed: %v", tt.resource, err)
+			continue
+		}
+		got, err := storage.(rest.Getter).Get(ctx, tt.obj.(metav1.Object).GetName(), &metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("%s: Get failed: %v", tt.resource, err)
+			continue
+		}
+		if got.(metav1.Object).GetResourceVersion() != created.(metav1.Object).GetResourceVersion() {
+			t.Errorf("%s: expected the created object, got %#v", tt.resource, got)
+		}
+	}
+}
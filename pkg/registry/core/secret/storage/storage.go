@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+	printersinternal "github.com/mqshen/HuZhou/pkg/printers/internalversion"
+	"github.com/mqshen/HuZhou/pkg/registry/core/secret"
+)
+
+// REST implements a RESTStorage for secrets.
+type REST struct {
+	*genericregistry.Store
+}
+
+// NewREST returns a RESTStorage object that will work against secrets.
+func NewREST(optsGetter generic.RESTOptionsGetter) (*REST, error) {
+	store := &genericregistry.Store{
+		NewFunc:                  func() runtime.Object { return &api.Secret{} },
+		NewListFunc:              func() runtime.Object { return &api.SecretList{} },
+		PredicateFunc:            secret.Matcher,
+		DefaultQualifiedResource: api.Resource("secrets"),
+		TableConvertor:           printers.NewTableGenerator().With(printersinternal.AddHandlers),
+
+		CreateStrategy: secret.Strategy,
+		UpdateStrategy: secret.Strategy,
+		DeleteStrategy: secret.Strategy,
+	}
+	options := &generic.StoreOptions{RESTOptions: optsGetter, AttrFunc: secret.GetAttrs}
+	if err := store.CompleteWithOptions(options); err != nil {
+		return nil, err
+	}
+	return &REST{store}, nil
+}
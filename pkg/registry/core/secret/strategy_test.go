@@ -0,0 +1,80 @@
+package secret
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+func TestSecretStrategy(t *testing.T) {
+	ctx := genericapirequest.NewDefaultContext()
+	if !Strategy.NamespaceScoped() {
+		t.Errorf("secrets must be namespace scoped")
+	}
+	if Strategy.AllowCreateOnUpdate() {
+		t.Errorf("secrets should not allow create on update")
+	}
+
+	newSecret := func(secretType api.SecretType, data map[string][]byte) *api.Secret {
+		return &api.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: metav1.NamespaceDefault, ResourceVersion: "1"},
+			Type:       secretType,
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name string
+		obj  *api.Secret
+		// old is validated as an update to obj when it is set
+		old       *api.Secret
+		expectErr bool
+	}{
+		{name: "opaque", obj: newSecret(api.SecretTypeOpaque, map[string][]byte{"key": []byte("value")})},
+		{name: "invalid key", obj: newSecret(api.SecretTypeOpaque, map[string][]byte{"a b": []byte("value")}), expectErr: true},
+		{name: "token without service account", obj: newSecret(api.SecretTypeServiceAccountToken, nil), expectErr: true},
+		{name: "tls without key", obj: newSecret(api.SecretTypeTLS, map[string][]byte{api.TLSCertKey: nil}), expectErr: true},
+		{
+			name: "update keeping the type",
+			obj:  newSecret("", map[string][]byte{"key": []byte("other")}),
+			old:  newSecret(api.SecretTypeOpaque, map[string][]byte{"key": []byte("value")}),
+		},
+		{
+			name:      "update changing the type",
+			obj:       newSecret(api.SecretTypeBasicAuth, map[string][]byte{api.BasicAuthUsernameKey: []byte("user")}),
+			old:       newSecret(api.SecretTypeOpaque, nil),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.old == nil {
+			Strategy.PrepareForCreate(ctx, tt.obj)
+			if errs := Strategy.Validate(ctx, tt.obj); (len(errs) != 0) != tt.expectErr {
+				t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+			}
+			continue
+		}
+		Strategy.PrepareForUpdate(ctx, tt.obj, tt.old)
+		if errs := Strategy.ValidateUpdate(ctx, tt.obj, tt.old); (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+	}
+}
+
+func TestSelectableFields(t *testing.T) {
+	secret := &api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"},
+		Type:       api.SecretTypeTLS,
+	}
+	fields := SelectableFields(secret)
+	expected := map[string]string{"metadata.name": "foo", "metadata.namespace": "ns", "type": string(api.SecretTypeTLS)}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("expected field %s to be %q, got %q", key, value, fields[key])
+		}
+	}
+}
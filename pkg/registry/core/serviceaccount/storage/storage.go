@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/mqshen/HuZhou/pkg/api"
+	"github.com/mqshen/HuZhou/pkg/printers"
+	printersinternal "github.com/mqshen/HuZhou/pkg/printers/internalversion"
+	"github.com/mqshen/HuZhou/pkg/registry/core/serviceaccount"
+)
+
+// REST implements a RESTStorage for service accounts.
+type REST struct {
+	*genericregistry.Store
+}
+
+// NewREST returns a RESTStorage object that will work against service accounts.
+func NewREST(optsGetter generic.RESTOptionsGetter) (*REST, error) {
+	store := &genericregistry.Store{
+		NewFunc:                  func() runtime.Object { return &api.ServiceAccount{} },
+		NewListFunc:              func() runtime.Object { return &api.ServiceAccountList{} },
+		DefaultQualifiedResource: api.Resource("serviceaccounts"),
+		TableConvertor:           printers.NewTableGenerator().With(printersinternal.AddHandlers),
+
+		CreateStrategy:      serviceaccount.Strategy,
+		UpdateStrategy:      serviceaccount.Strategy,
+		DeleteStrategy:      serviceaccount.Strategy,
+		ReturnDeletedObject: true,
+	}
+	options := &generic.StoreOptions{RESTOptions: optsGetter}
+	if err := store.CompleteWithOptions(options); err != nil {
+		return nil, err
+	}
+	return &REST{store}, nil
+}
+
+// Implement ShortNamesProvider
+var _ rest.ShortNamesProvider = &REST{}
+
+// ShortNames implements the ShortNamesProvider interface. Returns a list of short names for a resource.
+func (r *REST) ShortNames() []string {
+	return []string{"sa"}
+}
@@ -0,0 +1,64 @@
+package serviceaccount
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/mqshen/HuZhou/pkg/api"
+)
+
+func TestServiceAccountStrategy(t *testing.T) {
+	ctx := genericapirequest.NewDefaultContext()
+	if !Strategy.NamespaceScoped() {
+		t.Errorf("serviceaccounts must be namespace scoped")
+	}
+
+	newServiceAccount := func(name string, secrets ...api.ObjectReference) *api.ServiceAccount {
+		return &api.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: metav1.NamespaceDefault, ResourceVersion: "1"},
+			Secrets:    secrets,
+		}
+	}
+	secret := api.ObjectReference{Kind: "Secret", Namespace: "other", Name: "token"}
+
+	tests := []struct {
+		name string
+		obj  *api.ServiceAccount
+		// old is validated as an update to obj when it is set
+		old           *api.ServiceAccount
+		expectSecrets []api.ObjectReference
+		expectErr     bool
+	}{
+		{name: "valid", obj: newServiceAccount("default")},
+		{name: "invalid name", obj: newServiceAccount("Default"), expectErr: true},
+		{name: "secret reference cleaned", obj: newServiceAccount("default", secret), expectSecrets: []api.ObjectReference{{Name: "token"}}},
+		{
+			name:          "secret reference cleaned on update",
+			obj:           newServiceAccount("default", secret),
+			old:           newServiceAccount("default"),
+			expectSecrets: []api.ObjectReference{{Name: "token"}},
+		},
+		{name: "update renaming", obj: newServiceAccount("other"), old: newServiceAccount("default"), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		var errs field.ErrorList
+		if tt.old == nil {
+			Strategy.PrepareForCreate(ctx, tt.obj)
+			errs = Strategy.Validate(ctx, tt.obj)
+		} else {
+			Strategy.PrepareForUpdate(ctx, tt.obj, tt.old)
+			errs = Strategy.ValidateUpdate(ctx, tt.obj, tt.old)
+		}
+		if (len(errs) != 0) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, errs)
+		}
+		if !reflect.DeepEqual(tt.obj.Secrets, tt.expectSecrets) {
+			t.Errorf("%s: expected secrets %v, got %v", tt.name, tt.expectSecrets, tt.obj.Secrets)
+		}
+	}
+}
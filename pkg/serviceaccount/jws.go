@@ -0,0 +1,214 @@
+package serviceaccount
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	// register the hash functions used by the signing methods
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// signingMethod is a JWS algorithm service account tokens are signed with.
+type signingMethod struct {
+	// alg is the name of the algorithm in the JOSE header.
+	alg  string
+	hash crypto.Hash
+	// keySize is the length in bytes of each of the r and s halves of an ECDSA
+	// signature. It is zero for RSA methods.
+	keySize int
+}
+
+var (
+	signingMethodRS256 = &signingMethod{alg: "RS256", hash: crypto.SHA256}
+	signingMethodES256 = &signingMethod{alg: "ES256", hash: crypto.SHA256, keySize: 32}
+	signingMethodES384 = &signingMethod{alg: "ES384", hash: crypto.SHA384, keySize: 48}
+	signingMethodES512 = &signingMethod{alg: "ES512", hash: crypto.SHA512, keySize: 66}
+)
+
+var (
+	// errMalformedJWT is returned for tokens which are not JWTs at all.
+	errMalformedJWT = errors.New("token is not a JWT")
+	// errInvalidSignature is returned when a signature does not verify with a key.
+	errInvalidSignature = errors.New("signature is invalid")
+	// errMismatchedSigningMethod is returned when a key does not apply to the signing method of a token.
+	errMismatchedSigningMethod = errors.New("invalid signing method")
+)
+
+// signingMethods are the signing methods of the tokens by the alg header value.
+var signingMethods = map[string]*signingMethod{
+	signingMethodRS256.alg: signingMethodRS256,
+	signingMethodES256.alg: signingMethodES256,
+	signingMethodES384.alg: signingMethodES384,
+	signingMethodES512.alg: signingMethodES512,
+}
+
+// signingMethodForPrivateKey returns the signing method matching the type and curve of key.
+func signingMethodForPrivateKey(key interface{}) (*signingMethod, error) {
+	switch privateKey := key.(type) {
+	case *rsa.PrivateKey:
+		return signingMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		switch privateKey.Curve {
+		case elliptic.P256():
+			return signingMethodES256, nil
+		case elliptic.P384():
+			return signingMethodES384, nil
+		case elliptic.P521():
+			return signingMethodES512, nil
+		default:
+			return nil, fmt.Errorf("unknown private key curve, must be 256, 384, or 521")
+		}
+	default:
+		return nil, fmt.Errorf("unknown private key type %T, must be *rsa.PrivateKey or *ecdsa.PrivateKey", key)
+	}
+}
+
+// signJWT returns the compact serialization of a JWT carrying claims, signed with key.
+func signJWT(method *signingMethod, key interface{}, claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": method.alg, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	signature, err := method.sign(signingInput, key)
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// parsedJWT is a JWT in compact serialization decoded into its parts.
+type parsedJWT struct {
+	method *signingMethod
+	claims map[string]interface{}
+	// signingInput is the encoded header and payload the signature was computed over.
+	signingInput string
+	signature    []byte
+}
+
+// parseJWT decodes token without verifying its signature. It returns errMalformedJWT if
+// the token is not a JWT.
+func parseJWT(token string) (*parsedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedJWT
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJSONSegment(parts[0], &header); err != nil {
+		return nil, errMalformedJWT
+	}
+	claims := map[string]interface{}{}
+	if err := decodeJSONSegment(parts[1], &claims); err != nil {
+		return nil, errMalformedJWT
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedJWT
+	}
+
+	method, ok := signingMethods[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", header.Alg)
+	}
+	return &parsedJWT{
+		method:       method,
+		claims:       claims,
+		signingInput: parts[0] + "." + parts[1],
+		signature:    signature,
+	}, nil
+}
+
+// verify checks the signature of the token with key. It returns errMismatchedSigningMethod
+// if key cannot be used with the signing method of the token, and errInvalidSignature if
+// the signature does not verify.
+func (t *parsedJWT) verify(key interface{}) error {
+	m := t.method
+	digest := m.digest(t.signingInput)
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		if m.keySize != 0 {
+			return errMismatchedSigningMethod
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, m.hash, digest, t.signature); err != nil {
+			return errInvalidSignature
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if m.keySize == 0 {
+			return errMismatchedSigningMethod
+		}
+		if len(t.signature) != 2*m.keySize {
+			return errInvalidSignature
+		}
+		r := new(big.Int).SetBytes(t.signature[:m.keySize])
+		s := new(big.Int).SetBytes(t.signature[m.keySize:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return errInvalidSignature
+		}
+		return nil
+	default:
+		return errMismatchedSigningMethod
+	}
+}
+
+func (m *signingMethod) digest(signingInput string) []byte {
+	hasher := m.hash.New()
+	hasher.Write([]byte(signingInput))
+	return hasher.Sum(nil)
+}
+
+func (m *signingMethod) sign(signingInput string, key interface{}) ([]byte, error) {
+	digest := m.digest(signingInput)
+	switch privateKey := key.(type) {
+	case *rsa.PrivateKey:
+		if m.keySize != 0 {
+			return nil, fmt.Errorf("%s requires an ECDSA private key", m.alg)
+		}
+		return rsa.SignPKCS1v15(rand.Reader, privateKey, m.hash, digest)
+	case *ecdsa.PrivateKey:
+		if m.keySize == 0 {
+			return nil, fmt.Errorf("%s requires an RSA private key", m.alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest)
+		if err != nil {
+			return nil, err
+		}
+		// r and s are left padded to the key size and concatenated, see RFC 7518 section 3.4
+		signature := make([]byte, 2*m.keySize)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[m.keySize-len(rBytes):m.keySize], rBytes)
+		copy(signature[2*m.keySize-len(sBytes):], sBytes)
+		return signature, nil
+	default:
+		return nil, fmt.Errorf("unknown private key type %T", key)
+	}
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeJSONSegment(segment string, into interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
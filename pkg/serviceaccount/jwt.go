@@ -0,0 +1,213 @@
+package serviceaccount
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/glog"
+
+	"k8s.io/api/core/v1"
+	certutil "k8s.io/client-go/util/cert"
+
+	"github.com/HuZhou/apiserver/pkg/authentication/authenticator"
+	apiserverserviceaccount "github.com/HuZhou/apiserver/pkg/authentication/serviceaccount"
+	"github.com/HuZhou/apiserver/pkg/authentication/user"
+)
+
+const (
+	Issuer = "kubernetes/serviceaccount"
+
+	SubjectClaim            = "sub"
+	IssuerClaim             = "iss"
+	ServiceAccountNameClaim = "kubernetes.io/serviceaccount/service-account.name"
+	ServiceAccountUIDClaim  = "kubernetes.io/serviceaccount/service-account.uid"
+	SecretNameClaim         = "kubernetes.io/serviceaccount/secret.name"
+	NamespaceClaim          = "kubernetes.io/serviceaccount/namespace"
+)
+
+// TokenGenerator generates tokens identifying service accounts
+type TokenGenerator interface {
+	// GenerateToken generates a token which will identify the given ServiceAccount.
+	// The returned token will be stored in the given (and yet-unpersisted) Secret.
+	GenerateToken(serviceAccount v1.ServiceAccount, secret v1.Secret) (string, error)
+}
+
+// ServiceAccountTokenGetter defines functions to retrieve a named service account and secret
+type ServiceAccountTokenGetter interface {
+	GetServiceAccount(namespace, name string) (*v1.ServiceAccount, error)
+	GetSecret(namespace, name string) (*v1.Secret, error)
+}
+
+// ReadPrivateKey is a helper function for reading a private key from a PEM-encoded file
+func ReadPrivateKey(file string) (interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := certutil.ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key file %s: %v", file, err)
+	}
+	return key, nil
+}
+
+// ReadPublicKeys is a helper function for reading an array of rsa.PublicKey or ecdsa.PublicKey from a PEM-encoded file.
+// Reads public keys from both public and private key files.
+func ReadPublicKeys(file string) ([]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := certutil.ParsePublicKeysPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading public key file %s: %v", file, err)
+	}
+	return keys, nil
+}
+
+// JWTTokenGenerator returns a TokenGenerator that generates signed JWT tokens, using the given privateKey.
+// privateKey is a *rsa.PrivateKey, signing RS256 tokens, or a *ecdsa.PrivateKey, signing ES256, ES384
+// or ES512 tokens depending on its curve.
+func JWTTokenGenerator(privateKey interface{}) TokenGenerator {
+	return &jwtTokenGenerator{privateKey}
+}
+
+type jwtTokenGenerator struct {
+	privateKey interface{}
+}
+
+func (j *jwtTokenGenerator) GenerateToken(serviceAccount v1.ServiceAccount, secret v1.Secret) (string, error) {
+	method, err := signingMethodForPrivateKey(j.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	claims := map[string]interface{}{
+		// Identify the issuer
+		IssuerClaim: Issuer,
+
+		// Username
+		SubjectClaim: apiserverserviceaccount.MakeUsername(serviceAccount.Namespace, serviceAccount.Name),
+
+		// Persist enough structured info for the authenticator to be able to look up the service account and secret
+		NamespaceClaim:          serviceAccount.Namespace,
+		ServiceAccountNameClaim: serviceAccount.Name,
+		ServiceAccountUIDClaim:  serviceAccount.UID,
+		SecretNameClaim:         secret.Name,
+	}
+
+	// Sign and get the complete encoded token as a string
+	return signJWT(method, j.privateKey, claims)
+}
+
+// JWTTokenAuthenticator authenticates tokens as JWT tokens produced by JWTTokenGenerator
+// Token signatures are verified using each of the given public keys until one works (allowing key rotation)
+// If lookup is true, the service account and secret referenced as claims inside the token are retrieved and verified with the provided ServiceAccountTokenGetter
+func JWTTokenAuthenticator(keys []interface{}, lookup bool, getter ServiceAccountTokenGetter) authenticator.Token {
+	return &jwtTokenAuthenticator{keys, lookup, getter}
+}
+
+type jwtTokenAuthenticator struct {
+	keys   []interface{}
+	lookup bool
+	getter ServiceAccountTokenGetter
+}
+
+func (j *jwtTokenAuthenticator) AuthenticateToken(token string) (user.Info, bool, error) {
+	parsedToken, err := parseJWT(token)
+	if err == errMalformedJWT {
+		// Not a JWT, no point in continuing
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var validationError error
+	verified := false
+	for i, key := range j.keys {
+		// Attempt to verify with each key until we find one that works
+		err := parsedToken.verify(key)
+		if err == nil {
+			verified = true
+			break
+		}
+		// Either the signature does not verify with this key, or the key doesn't apply to the
+		// signature type. Perhaps one of the other keys will verify the signature.
+		// If not, we want to return this error
+		glog.V(4).Infof("Signature error (key %d): %v", i, err)
+		validationError = err
+	}
+	if !verified {
+		return nil, false, validationError
+	}
+
+	// If we get here, we have a token with a recognized signature
+	claims := parsedToken.claims
+
+	// Check issuer
+	issuer, _ := claims[IssuerClaim].(string)
+	if issuer != Issuer {
+		return nil, false, nil
+	}
+
+	// Check the claims
+	namespace, _ := claims[NamespaceClaim].(string)
+	if len(namespace) == 0 {
+		return nil, false, errors.New("namespace claim is missing")
+	}
+	secretName, _ := claims[SecretNameClaim].(string)
+	if len(secretName) == 0 {
+		return nil, false, errors.New("secretName claim is missing")
+	}
+	serviceAccountName, _ := claims[ServiceAccountNameClaim].(string)
+	if len(serviceAccountName) == 0 {
+		return nil, false, errors.New("serviceAccountName claim is missing")
+	}
+	serviceAccountUID, _ := claims[ServiceAccountUIDClaim].(string)
+	if len(serviceAccountUID) == 0 {
+		return nil, false, errors.New("serviceAccountUID claim is missing")
+	}
+
+	subject, _ := claims[SubjectClaim].(string)
+	subjectNamespace, subjectName, err := apiserverserviceaccount.SplitUsername(subject)
+	if err != nil || subjectNamespace != namespace || subjectName != serviceAccountName {
+		return nil, false, errors.New("sub claim is invalid")
+	}
+
+	if j.lookup {
+		// Make sure token hasn't been invalidated by deletion of the secret
+		secret, err := j.getter.GetSecret(namespace, secretName)
+		if err != nil {
+			glog.V(4).Infof("Could not retrieve token %s/%s for service account %s/%s: %v", namespace, secretName, namespace, serviceAccountName, err)
+			return nil, false, errors.New("Token has been invalidated")
+		}
+		if secret.DeletionTimestamp != nil {
+			glog.V(4).Infof("Token is deleted and awaiting removal: %s/%s for service account %s/%s", namespace, secretName, namespace, serviceAccountName)
+			return nil, false, errors.New("Token has been invalidated")
+		}
+		if !bytes.Equal(secret.Data[v1.ServiceAccountTokenKey], []byte(token)) {
+			glog.V(4).Infof("Token contents no longer matches %s/%s for service account %s/%s", namespace, secretName, namespace, serviceAccountName)
+			return nil, false, errors.New("Token does not match server's copy")
+		}
+
+		// Make sure service account still exists (name and UID)
+		serviceAccount, err := j.getter.GetServiceAccount(namespace, serviceAccountName)
+		if err != nil {
+			glog.V(4).Infof("Could not retrieve service account %s/%s: %v", namespace, serviceAccountName, err)
+			return nil, false, err
+		}
+		if serviceAccount.DeletionTimestamp != nil {
+			glog.V(4).Infof("Service account has been deleted %s/%s", namespace, serviceAccountName)
+			return nil, false, fmt.Errorf("ServiceAccount %s/%s has been deleted", namespace, serviceAccountName)
+		}
+		if string(serviceAccount.UID) != serviceAccountUID {
+			glog.V(4).Infof("Service account UID no longer matches %s/%s: %q != %q", namespace, serviceAccountName, string(serviceAccount.UID), serviceAccountUID)
+			return nil, false, fmt.Errorf("ServiceAccount UID (%s) does not match claim (%s)", serviceAccount.UID, serviceAccountUID)
+		}
+	}
+
+	return UserInfo(namespace, serviceAccountName, serviceAccountUID), true, nil
+}
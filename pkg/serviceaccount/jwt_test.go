@@ -0,0 +1,243 @@
+package serviceaccount
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	rsaKey       = mustGenerateRSAKey()
+	otherRSAKey  = mustGenerateRSAKey()
+	ecdsa256Key  = mustGenerateECDSAKey(elliptic.P256())
+	ecdsa384Key  = mustGenerateECDSAKey(elliptic.P384())
+	ecdsa521Key  = mustGenerateECDSAKey(elliptic.P521())
+	otherECDSKey = mustGenerateECDSAKey(elliptic.P256())
+)
+
+func mustGenerateRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustGenerateECDSAKey(curve elliptic.Curve) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// fakeGetter returns the service account and secret it holds, or an error
+// when it holds none.
+type fakeGetter struct {
+	serviceAccount *v1.ServiceAccount
+	secret         *v1.Secret
+}
+
+func (g fakeGetter) GetServiceAccount(namespace, name string) (*v1.ServiceAccount, error) {
+	if g.serviceAccount == nil || g.serviceAccount.Namespace != namespace || g.serviceAccount.Name != name {
+		return nil, fmt.Errorf("service account %s/%s not found", namespace, name)
+	}
+	return g.serviceAccount, nil
+}
+
+func (g fakeGetter) GetSecret(namespace, name string) (*v1.Secret, error) {
+	if g.secret == nil || g.secret.Namespace != namespace || g.secret.Name != name {
+		return nil, fmt.Errorf("secret %s/%s not found", namespace, name)
+	}
+	return g.secret, nil
+}
+
+func TestTokenGenerateAndValidate(t *testing.T) {
+	serviceAccount := v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-service-account", Namespace: "ns", UID: "12345"}}
+	secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "ns"}}
+	deleting := metav1.Now()
+
+	tests := []struct {
+		name       string
+		privateKey interface{}
+		keys       []interface{}
+		lookup     bool
+		// getter is used to look up the token; its secret is given the token
+		getter    fakeGetter
+		expectOK  bool
+		expectErr bool
+	}{
+		{name: "rsa", privateKey: rsaKey, keys: []interface{}{&rsaKey.PublicKey}, expectOK: true},
+		{name: "ecdsa 256", privateKey: ecdsa256Key, keys: []interface{}{&ecdsa256Key.PublicKey}, expectOK: true},
+		{name: "ecdsa 384", privateKey: ecdsa384Key, keys: []interface{}{&ecdsa384Key.PublicKey}, expectOK: true},
+		{name: "ecdsa 521", privateKey: ecdsa521Key, keys: []interface{}{&ecdsa521Key.PublicKey}, expectOK: true},
+		{name: "rotated keys", privateKey: rsaKey, keys: []interface{}{&otherRSAKey.PublicKey, &ecdsa256Key.PublicKey, &rsaKey.PublicKey}, expectOK: true},
+		{name: "wrong rsa key", privateKey: rsaKey, keys: []interface{}{&otherRSAKey.PublicKey}, expectErr: true},
+		{name: "wrong ecdsa key", privateKey: ecdsa256Key, keys: []interface{}{&otherECDSKey.PublicKey}, expectErr: true},
+		{name: "key for another method", privateKey: ecdsa256Key, keys: []interface{}{&rsaKey.PublicKey}, expectErr: true},
+		{name: "no keys", privateKey: rsaKey},
+		{
+			name:       "lookup",
+			privateKey: rsaKey,
+			keys:       []interface{}{&rsaKey.PublicKey},
+			lookup:     true,
+			getter:     fakeGetter{serviceAccount: &serviceAccount, secret: &secret},
+			expectOK:   true,
+		},
+		{
+			name:       "lookup without secret",
+			privateKey: rsaKey,
+			keys:       []interface{}{&rsaKey.PublicKey},
+			lookup:     true,
+			getter:     fakeGetter{serviceAccount: &serviceAccount},
+			expectErr:  true,
+		},
+		{
+			name:       "lookup with deleted secret",
+			privateKey: rsaKey,
+			keys:       []interface{}{&rsaKey.PublicKey},
+			lookup:     true,
+			getter: fakeGetter{
+				serviceAccount: &serviceAccount,
+				secret:         &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "ns", DeletionTimestamp: &deleting}},
+			},
+			expectErr: true,
+		},
+		{
+			name:       "lookup without service account",
+			privateKey: rsaKey,
+			keys:       []interface{}{&rsaKey.PublicKey},
+			lookup:     true,
+			getter:     fakeGetter{secret: &secret},
+			expectErr:  true,
+		},
+		{
+			name:       "lookup with recreated service account",
+			privateKey: rsaKey,
+			keys:       []interface{}{&rsaKey.PublicKey},
+			lookup:     true,
+			getter: fakeGetter{
+				serviceAccount: &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-service-account", Namespace: "ns", UID: "67890"}},
+				secret:         &secret,
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		token, err := JWTTokenGenerator(tt.privateKey).GenerateToken(serviceAccount, secret)
+		if err != nil {
+			t.Errorf("%s: GenerateToken failed: %v", tt.name, err)
+			continue
+		}
+		if tt.getter.secret != nil {
+			stored := tt.getter.secret.DeepCopy()
+			stored.Data = map[string][]byte{v1.ServiceAccountTokenKey: []byte(token)}
+			tt.getter.secret = stored
+		}
+
+		user, ok, err := JWTTokenAuthenticator(tt.keys, tt.lookup, tt.getter).AuthenticateToken(token)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+			continue
+		}
+		if ok != tt.expectOK {
+			t.Errorf("%s: expected ok %t, got %t", tt.name, tt.expectOK, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if user.GetName() != "system:serviceaccount:ns:my-service-account" || user.GetUID() != "12345" {
+			t.Errorf("%s: unexpected user %#v", tt.name, user)
+		}
+	}
+}
+
+func TestGenerateTokenWithUnknownKey(t *testing.T) {
+	serviceAccount := v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-service-account", Namespace: "ns"}}
+	secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "ns"}}
+
+	tests := []struct {
+		name string
+		key  interface{}
+	}{
+		{name: "public key", key: &rsaKey.PublicKey},
+		{name: "unknown curve", key: mustGenerateECDSAKey(elliptic.P224())},
+		{name: "not a key", key: "key"},
+	}
+
+	for _, tt := range tests {
+		if _, err := JWTTokenGenerator(tt.key).GenerateToken(serviceAccount, secret); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestAuthenticateMalformedToken(t *testing.T) {
+	serviceAccount := v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-service-account", Namespace: "ns", UID: "12345"}}
+	secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "my-secret", Namespace: "ns"}}
+	signed := func(claims map[string]interface{}) string {
+		token, err := signJWT(signingMethodRS256, rsaKey, claims)
+		if err != nil {
+			t.Fatalf("signJWT failed: %v", err)
+		}
+		return token
+	}
+	valid, err := JWTTokenGenerator(rsaKey).GenerateToken(serviceAccount, secret)
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{
+			IssuerClaim:             Issuer,
+			SubjectClaim:            "system:serviceaccount:ns:my-service-account",
+			NamespaceClaim:          "ns",
+			ServiceAccountNameClaim: "my-service-account",
+			ServiceAccountUIDClaim:  "12345",
+			SecretNameClaim:         "my-secret",
+		}
+		for key, value := range overrides {
+			if value == nil {
+				delete(result, key)
+				continue
+			}
+			result[key] = value
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		expectErr bool
+	}{
+		{name: "not a jwt", token: "token"},
+		{name: "bad encoding", token: "a.b.c"},
+		{name: "unknown signing method", token: encodeSegment([]byte(`{"alg":"HS256"}`)) + "." + encodeSegment([]byte(`{}`)) + ".", expectErr: true},
+		{name: "tampered signature", token: valid[:len(valid)-4] + "AAAA", expectErr: true},
+		{name: "other issuer", token: signed(claims(map[string]interface{}{IssuerClaim: "other"}))},
+		{name: "missing namespace", token: signed(claims(map[string]interface{}{NamespaceClaim: nil})), expectErr: true},
+		{name: "missing secret", token: signed(claims(map[string]interface{}{SecretNameClaim: nil})), expectErr: true},
+		{name: "missing service account", token: signed(claims(map[string]interface{}{ServiceAccountNameClaim: nil})), expectErr: true},
+		{name: "missing uid", token: signed(claims(map[string]interface{}{ServiceAccountUIDClaim: nil})), expectErr: true},
+		{name: "other subject", token: signed(claims(map[string]interface{}{SubjectClaim: "system:serviceaccount:ns:other"})), expectErr: true},
+	}
+
+	authenticator := JWTTokenAuthenticator([]interface{}{&rsaKey.PublicKey}, false, nil)
+	for _, tt := range tests {
+		_, ok, err := authenticator.AuthenticateToken(tt.token)
+		if ok {
+			t.Errorf("%s: expected the token not to authenticate", tt.name)
+		}
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+		}
+	}
+}
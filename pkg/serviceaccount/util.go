@@ -0,0 +1,37 @@
+package serviceaccount
+
+import (
+	"k8s.io/api/core/v1"
+
+	apiserverserviceaccount "github.com/HuZhou/apiserver/pkg/authentication/serviceaccount"
+	"github.com/HuZhou/apiserver/pkg/authentication/user"
+)
+
+// UserInfo returns a user.Info interface for the given namespace, service account name and UID
+func UserInfo(namespace, name, uid string) user.Info {
+	return &user.DefaultInfo{
+		Name:   apiserverserviceaccount.MakeUsername(namespace, name),
+		UID:    uid,
+		Groups: apiserverserviceaccount.MakeGroupNames(namespace),
+	}
+}
+
+// IsServiceAccountToken returns true if the secret is a valid api token for the service account
+func IsServiceAccountToken(secret *v1.Secret, sa *v1.ServiceAccount) bool {
+	if secret.Type != v1.SecretTypeServiceAccountToken {
+		return false
+	}
+
+	name := secret.Annotations[v1.ServiceAccountNameKey]
+	uid := secret.Annotations[v1.ServiceAccountUIDKey]
+	if name != sa.Name {
+		// Name must match
+		return false
+	}
+	if len(uid) > 0 && uid != string(sa.UID) {
+		// If UID is specified, it must match
+		return false
+	}
+
+	return true
+}
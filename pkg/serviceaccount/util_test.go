@@ -0,0 +1,54 @@
+package serviceaccount
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsServiceAccountToken(t *testing.T) {
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "ns", UID: "12345"}}
+	newSecret := func(secretType v1.SecretType, name, uid string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "token",
+				Namespace:   "ns",
+				Annotations: map[string]string{v1.ServiceAccountNameKey: name, v1.ServiceAccountUIDKey: uid},
+			},
+			Type: secretType,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		secret   *v1.Secret
+		expected bool
+	}{
+		{name: "token", secret: newSecret(v1.SecretTypeServiceAccountToken, "default", "12345"), expected: true},
+		{name: "token without uid", secret: newSecret(v1.SecretTypeServiceAccountToken, "default", ""), expected: true},
+		{name: "other type", secret: newSecret(v1.SecretTypeOpaque, "default", "12345")},
+		{name: "other service account", secret: newSecret(v1.SecretTypeServiceAccountToken, "other", "12345")},
+		{name: "recreated service account", secret: newSecret(v1.SecretTypeServiceAccountToken, "default", "67890")},
+	}
+
+	for _, tt := range tests {
+		if got := IsServiceAccountToken(tt.secret, serviceAccount); got != tt.expected {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	info := UserInfo("ns", "default", "12345")
+	if info.GetName() != "system:serviceaccount:ns:default" || info.GetUID() != "12345" {
+		t.Errorf("unexpected user %#v", info)
+	}
+	groups := map[string]bool{}
+	for _, group := range info.GetGroups() {
+		groups[group] = true
+	}
+	if !groups["system:serviceaccounts"] || !groups["system:serviceaccounts:ns"] {
+		t.Errorf("expected the service account groups, got %v", info.GetGroups())
+	}
+}
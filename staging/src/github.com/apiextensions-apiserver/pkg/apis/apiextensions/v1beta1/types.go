@@ -0,0 +1,27 @@
+package v1beta1
+
+// CustomResourceColumnDefinition specifies a column for server side printing,
+// listed in the additionalPrinterColumns of a custom resource definition.
+type CustomResourceColumnDefinition struct {
+	// name is a human readable name for the column.
+	Name string `json:"name"`
+	// type is an OpenAPI type definition for this column.
+	// See https://github.com/OAI/OpenAPI-Specification/blob/master/versions/2.0.md#data-types for more.
+	Type string `json:"type"`
+	// format is an optional OpenAPI type definition for this column. The 'name' format is applied
+	// to the primary identifier column to assist in clients identifying column is the resource name.
+	// See https://github.com/OAI/OpenAPI-Specification/blob/master/versions/2.0.md#data-types for more.
+	// +optional
+	Format string `json:"format,omitempty"`
+	// description is a human readable description of this column.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// priority is an integer defining the relative importance of this column compared to others. Lower
+	// numbers are considered higher priority. Columns that may be omitted in limited space scenarios
+	// should be given a higher priority.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// JSONPath is a simple JSON path, i.e. with array notation, evaluated against each custom
+	// resource to produce the value of this column.
+	JSONPath string `json:"JSONPath"`
+}
@@ -1,7 +1,11 @@
 package apiserver
 
 import (
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic"
+	"github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	genericoptions "github.com/HuZhou/apiserver/pkg/server/options"
 	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"fmt"
 	"bytes"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -37,4 +41,30 @@ type CRDRESTOptionsGetter struct {
 	DefaultWatchCacheSize   int
 	EnableGarbageCollection bool
 	DeleteCollectionWorkers int
-}
\ No newline at end of file
+	// EtcdServersOverrides moves the storage of some resources to other etcd
+	// servers, in the format of the --etcd-servers-overrides flag.
+	EtcdServersOverrides []string
+}
+
+func (t CRDRESTOptionsGetter) GetRESTOptions(resource schema.GroupResource) (genericregistry.RESTOptions, error) {
+	overrides, err := genericoptions.ParseEtcdServersOverrides(t.EtcdServersOverrides)
+	if err != nil {
+		return genericregistry.RESTOptions{}, err
+	}
+	if servers, ok := overrides[resource]; ok {
+		t.StorageConfig.ServerList = servers
+		t.StorageConfig.OverrideName = genericoptions.EtcdServersOverrideName(resource)
+	}
+	t.StorageConfig.GroupResource = resource
+	ret := genericregistry.RESTOptions{
+		StorageConfig:           &t.StorageConfig,
+		Decorator:               genericregistry.UndecoratedStorage,
+		EnableGarbageCollection: t.EnableGarbageCollection,
+		DeleteCollectionWorkers: t.DeleteCollectionWorkers,
+		ResourcePrefix:          resource.Group + "/" + resource.Resource,
+	}
+	if t.EnableWatchCache {
+		ret.Decorator = registry.StorageWithCacher(t.DefaultWatchCacheSize)
+	}
+	return ret, nil
+}
@@ -14,6 +14,7 @@ func NewCRDRESTOptionsGetter(etcdOptions genericoptions.EtcdOptions) genericregi
 		DefaultWatchCacheSize:   etcdOptions.DefaultWatchCacheSize,
 		EnableGarbageCollection: etcdOptions.EnableGarbageCollection,
 		DeleteCollectionWorkers: etcdOptions.DeleteCollectionWorkers,
+		EtcdServersOverrides:    etcdOptions.EtcdServersOverrides,
 	}
 	ret.StorageConfig.Codec = unstructured.UnstructuredJSONScheme
 	ret.StorageConfig.Copier = apiserver.UnstructuredCopier{}
@@ -0,0 +1,164 @@
+// Package tableconvertor renders custom resources as a Table with the columns
+// listed in the additionalPrinterColumns of their definition.
+package tableconvertor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/HuZhou/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+)
+
+type convertor struct {
+	headers           []metav1beta1.TableColumnDefinition
+	additionalColumns []*jsonpath.JSONPath
+}
+
+// New creates a convertor rendering the name of every custom resource followed
+// by the given columns. A definition without columns gets the age column every
+// other resource has.
+func New(crdColumns []v1beta1.CustomResourceColumnDefinition) (rest.TableConvertor, error) {
+	if len(crdColumns) == 0 {
+		crdColumns = []v1beta1.CustomResourceColumnDefinition{
+			{Name: "Age", Type: "date", Description: rest.DefaultTableColumns[1].Description, JSONPath: ".metadata.creationTimestamp"},
+		}
+	}
+
+	c := &convertor{headers: []metav1beta1.TableColumnDefinition{rest.DefaultTableColumns[0]}}
+	for _, column := range crdColumns {
+		path := jsonpath.New(column.Name)
+		if err := path.Parse(fmt.Sprintf("{%s}", column.JSONPath)); err != nil {
+			return nil, fmt.Errorf("unrecognized column definition %q: %v", column.JSONPath, err)
+		}
+		path.AllowMissingKeys(true)
+
+		description := column.Description
+		if len(description) == 0 {
+			description = fmt.Sprintf("Custom resource definition column (in JSONPath format): %s", column.JSONPath)
+		}
+		c.additionalColumns = append(c.additionalColumns, path)
+		c.headers = append(c.headers, metav1beta1.TableColumnDefinition{
+			Name:        column.Name,
+			Type:        column.Type,
+			Format:      column.Format,
+			Description: description,
+			Priority:    column.Priority,
+		})
+	}
+	return c, nil
+}
+
+// ConvertToTable implements rest.TableConvertor.
+func (c *convertor) ConvertToTable(ctx genericapirequest.Context, object runtime.Object, tableOptions runtime.Object) (*metav1beta1.Table, error) {
+	table := &metav1beta1.Table{ColumnDefinitions: c.headers}
+	fn := func(obj runtime.Object) error {
+		row, err := c.row(obj)
+		if err != nil {
+			return err
+		}
+		table.Rows = append(table.Rows, row)
+		return nil
+	}
+	if meta.IsListType(object) {
+		if err := meta.EachListItem(object, fn); err != nil {
+			return nil, err
+		}
+	} else if err := fn(object); err != nil {
+		return nil, err
+	}
+	rest.SetTableListMeta(table, object)
+	return table, nil
+}
+
+// row renders the cells of a single custom resource.
+func (c *convertor) row(obj runtime.Object) (metav1beta1.TableRow, error) {
+	unstructured, ok := obj.(runtime.Unstructured)
+	if !ok {
+		return metav1beta1.TableRow{}, fmt.Errorf("expected an unstructured custom resource, got %T", obj)
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return metav1beta1.TableRow{}, err
+	}
+
+	cells := make([]interface{}, 1, len(c.headers))
+	cells[0] = m.GetName()
+	buf := &bytes.Buffer{}
+	for i, column := range c.additionalColumns {
+		header := c.headers[i+1]
+		results, err := column.FindResults(unstructured.UnstructuredContent())
+		if err != nil || len(results) == 0 || len(results[0]) == 0 {
+			cells = append(cells, nil)
+			continue
+		}
+		// only simple paths are supported, so the first result is the value
+		value := results[0][0].Interface()
+		if header.Type != "string" {
+			cells = append(cells, cellForJSONValue(header.Type, value))
+			continue
+		}
+		if err := column.PrintResults(buf, []reflect.Value{reflect.ValueOf(value)}); err != nil {
+			cells = append(cells, nil)
+			continue
+		}
+		cells = append(cells, buf.String())
+		buf.Reset()
+	}
+	return metav1beta1.TableRow{Cells: cells, Object: runtime.RawExtension{Object: obj}}, nil
+}
+
+// cellForJSONValue converts a value decoded from JSON into the cell of a column
+// of the given OpenAPI type, or nil if the value does not have that type.
+func cellForJSONValue(headerType string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch headerType {
+	case "integer":
+		switch typed := value.(type) {
+		case int64:
+			return typed
+		case float64:
+			return int64(typed)
+		case json.Number:
+			if i64, err := typed.Int64(); err == nil {
+				return i64
+			}
+		}
+	case "number":
+		switch typed := value.(type) {
+		case int64:
+			return float64(typed)
+		case float64:
+			return typed
+		case json.Number:
+			if f, err := typed.Float64(); err == nil {
+				return f
+			}
+		}
+	case "boolean":
+		if b, ok := value.(bool); ok {
+			return b
+		}
+	case "date":
+		if typed, ok := value.(string); ok {
+			var timestamp metav1.Time
+			if err := timestamp.UnmarshalQueryParameter(typed); err != nil {
+				return "<invalid>"
+			}
+			return rest.TranslateTimestamp(timestamp)
+		}
+	}
+	return nil
+}
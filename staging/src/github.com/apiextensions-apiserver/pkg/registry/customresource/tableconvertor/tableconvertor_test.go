@@ -0,0 +1,127 @@
+package tableconvertor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/HuZhou/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+func newCronTab(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "stable.example.com/v1beta1",
+		"kind":       "CronTab",
+		"metadata": map[string]interface{}{
+			"name":              name,
+			"namespace":         "default",
+			"creationTimestamp": metav1.NewTime(time.Now().Add(-2 * time.Hour)).UTC().Format(time.RFC3339),
+		},
+		"spec": spec,
+	}}
+}
+
+func TestNewInvalidColumn(t *testing.T) {
+	if _, err := New([]v1beta1.CustomResourceColumnDefinition{{Name: "Spec", Type: "string", JSONPath: ".spec[unterminated"}}); err == nil {
+		t.Errorf("expected an error for an invalid JSONPath")
+	}
+}
+
+func TestConvertToTable(t *testing.T) {
+	columns := []v1beta1.CustomResourceColumnDefinition{
+		{Name: "Spec", Type: "string", JSONPath: ".spec.cronSpec"},
+		{Name: "Replicas", Type: "integer", Priority: 1, JSONPath: ".spec.replicas"},
+		{Name: "Ratio", Type: "number", JSONPath: ".spec.ratio"},
+		{Name: "Suspended", Type: "boolean", Description: "Whether the job is suspended", JSONPath: ".spec.suspend"},
+		{Name: "Last Run", Type: "date", JSONPath: ".spec.lastRun"},
+	}
+	c, err := New(columns)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list := &unstructured.UnstructuredList{
+		Object: map[string]interface{}{"metadata": map[string]interface{}{"resourceVersion": "42"}},
+		Items: []unstructured.Unstructured{
+			*newCronTab("full", map[string]interface{}{
+				"cronSpec": "* * * * */5",
+				"replicas": int64(3),
+				"ratio":    0.5,
+				"suspend":  true,
+				"lastRun":  metav1.NewTime(time.Now().Add(-time.Hour)).UTC().Format(time.RFC3339),
+			}),
+			*newCronTab("empty", map[string]interface{}{
+				"replicas": "three",
+				"lastRun":  "yesterday",
+			}),
+		},
+	}
+	table, err := c.ConvertToTable(genericapirequest.NewContext(), list, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := []string{}
+	for _, header := range table.ColumnDefinitions {
+		names = append(names, header.Name)
+	}
+	if expected := []string{"Name", "Spec", "Replicas", "Ratio", "Suspended", "Last Run"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected columns %v, got %v", expected, names)
+	}
+	if header := table.ColumnDefinitions[2]; header.Type != "integer" || header.Priority != 1 {
+		t.Errorf("unexpected replicas column %#v", header)
+	}
+	if header := table.ColumnDefinitions[1]; header.Description != "Custom resource definition column (in JSONPath format): .spec.cronSpec" {
+		t.Errorf("unexpected default description %q", header.Description)
+	}
+	if header := table.ColumnDefinitions[4]; header.Description != "Whether the job is suspended" {
+		t.Errorf("unexpected description %q", header.Description)
+	}
+	if table.ResourceVersion != "42" {
+		t.Errorf("expected the resource version of the list, got %q", table.ResourceVersion)
+	}
+
+	if len(table.Rows) != 2 {
+		t.Fatalf("expected two rows, got %#v", table.Rows)
+	}
+	if expected := []interface{}{"full", "* * * * */5", int64(3), 0.5, true, "1h"}; !reflect.DeepEqual(table.Rows[0].Cells, expected) {
+		t.Errorf("expected cells %#v, got %#v", expected, table.Rows[0].Cells)
+	}
+	if expected := []interface{}{"empty", nil, nil, nil, nil, "<invalid>"}; !reflect.DeepEqual(table.Rows[1].Cells, expected) {
+		t.Errorf("expected cells %#v, got %#v", expected, table.Rows[1].Cells)
+	}
+	if table.Rows[0].Object.Object == nil {
+		t.Errorf("expected the row to carry the object")
+	}
+}
+
+func TestConvertToTableWithoutColumns(t *testing.T) {
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table, err := c.ConvertToTable(genericapirequest.NewContext(), newCronTab("foo", nil), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(table.ColumnDefinitions) != 2 || table.ColumnDefinitions[0].Name != "Name" || table.ColumnDefinitions[1].Name != "Age" {
+		t.Errorf("expected the name and age columns, got %#v", table.ColumnDefinitions)
+	}
+	if expected := []interface{}{"foo", "2h"}; len(table.Rows) != 1 || !reflect.DeepEqual(table.Rows[0].Cells, expected) {
+		t.Errorf("expected cells %#v, got %#v", expected, table.Rows)
+	}
+}
+
+func TestConvertToTableRejectsTypedObjects(t *testing.T) {
+	c, err := New(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.ConvertToTable(genericapirequest.NewContext(), &metav1.Status{}, nil); err == nil {
+		t.Errorf("expected an error converting a typed object")
+	}
+}
@@ -105,6 +105,13 @@ type Event struct {
 	// at Response Level.
 	// +optional
 	ResponseObject *runtime.Unknown
+
+	// Annotations is an unstructured key value map stored with an audit event that may be set by
+	// the components serving the request. Keys should uniquely identify the informing component
+	// to avoid name collisions (e.g. dryrun.apiserver.k8s.io/dry-run). Values should be short.
+	// It is not the annotations of the embedded ObjectMeta, which describe the event object.
+	// +optional
+	Annotations map[string]string
 }
 
 
@@ -7,6 +7,7 @@ import (
 
 	auditinternal "github.com/HuZhou/apiserver/pkg/apis/audit"
 	authenticationv1 "github.com/HuZhou/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"github.com/HuZhou/apiserver/pkg/authorization/authorizer"
@@ -85,6 +86,126 @@ func NewEventFromRequest(req *http.Request, level auditinternal.Level, attribs a
 	return ev, nil
 }
 
+// LogRequestObject fills in the request object into an audit event. The passed runtime.Object
+// will be converted to the given gv.
+func LogRequestObject(ae *auditinternal.Event, obj runtime.Object, gvr schema.GroupVersionResource, subresource string, s runtime.NegotiatedSerializer) {
+	if ae == nil || ae.Level.Less(auditinternal.LevelMetadata) {
+		return
+	}
+
+	// complete ObjectRef
+	if ae.ObjectRef == nil {
+		ae.ObjectRef = &auditinternal.ObjectReference{}
+	}
+	completeObjectRef(ae.ObjectRef, obj)
+	if len(ae.ObjectRef.APIVersion) == 0 {
+		ae.ObjectRef.APIVersion = gvr.Group + "/" + gvr.Version
+	}
+	if len(ae.ObjectRef.Resource) == 0 {
+		ae.ObjectRef.Resource = gvr.Resource
+	}
+	if len(ae.ObjectRef.Subresource) == 0 {
+		ae.ObjectRef.Subresource = subresource
+	}
+
+	if ae.Level.Less(auditinternal.LevelRequest) {
+		return
+	}
+	// TODO(audit): hook into the serializer to avoid double conversion
+	var err error
+	ae.RequestObject, err = encodeObject(obj, gvr.GroupVersion(), s)
+	if err != nil {
+		glog.Warningf("Audit failed for %q request: %v", reflect.TypeOf(obj).Name(), err)
+	}
+}
+
+// LogDeletion records the outcome of a delete request in an audit event. The
+// ObjectRef is completed with the identity of the object the request acted on,
+// which is either the Status describing the removed object or the object that
+// is only marked for deletion. In the latter case the ResponseStatus tells what
+// the removal still waits for: the grace period, finalizers, or both.
+func LogDeletion(ae *auditinternal.Event, obj runtime.Object, deleted bool, code int) {
+	if ae == nil || ae.Level.Less(auditinternal.LevelMetadata) || obj == nil {
+		return
+	}
+	if ae.ObjectRef == nil {
+		ae.ObjectRef = &auditinternal.ObjectReference{}
+	}
+	completeObjectRef(ae.ObjectRef, obj)
+
+	if _, ok := obj.(*metav1.Status); ok {
+		// recorded as the response status by LogResponseObject
+		return
+	}
+	ae.ResponseStatus = &metav1.Status{Status: metav1.StatusSuccess, Code: int32(code)}
+	if deleted {
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	var pending []string
+	if grace := accessor.GetDeletionGracePeriodSeconds(); grace != nil && *grace > 0 {
+		pending = append(pending, fmt.Sprintf("a grace period of %d seconds", *grace))
+	}
+	if finalizers := accessor.GetFinalizers(); len(finalizers) > 0 {
+		pending = append(pending, fmt.Sprintf("finalizers %v", finalizers))
+	}
+	if len(pending) > 0 {
+		ae.ResponseStatus.Message = fmt.Sprintf("object marked for deletion, removal is pending %s", strings.Join(pending, " and "))
+	}
+}
+
+// LogAnnotation records an annotation describing how the request was served
+// in an audit event. An existing value for key is kept.
+func LogAnnotation(ae *auditinternal.Event, key, value string) {
+	if ae == nil || ae.Level.Less(auditinternal.LevelMetadata) {
+		return
+	}
+	if ae.Annotations == nil {
+		ae.Annotations = make(map[string]string)
+	}
+	if v, ok := ae.Annotations[key]; ok && v != value {
+		glog.Warningf("Failed to set annotation %q to %q for audit event %q, it has already been set to %q", key, value, ae.AuditID, v)
+		return
+	}
+	ae.Annotations[key] = value
+}
+
+// completeObjectRef fills in the fields of ref that are still empty from the
+// metadata of obj, or from the details of obj if it is a Status.
+func completeObjectRef(ref *auditinternal.ObjectReference, obj runtime.Object) {
+	if status, ok := obj.(*metav1.Status); ok {
+		if status.Details == nil {
+			return
+		}
+		if len(ref.Name) == 0 {
+			ref.Name = status.Details.Name
+		}
+		if len(ref.UID) == 0 {
+			ref.UID = status.Details.UID
+		}
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	if len(ref.Namespace) == 0 {
+		ref.Namespace = accessor.GetNamespace()
+	}
+	if len(ref.Name) == 0 {
+		ref.Name = accessor.GetName()
+	}
+	if len(ref.UID) == 0 {
+		ref.UID = accessor.GetUID()
+	}
+	if len(ref.ResourceVersion) == 0 {
+		ref.ResourceVersion = accessor.GetResourceVersion()
+	}
+}
+
 // LogResponseObject fills in the response object into an audit event. The passed runtime.Object
 // will be converted to the given gv.
 func LogResponseObject(ae *auditinternal.Event, obj runtime.Object, gv schema.GroupVersion, s runtime.NegotiatedSerializer) {
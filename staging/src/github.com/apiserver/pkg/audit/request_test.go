@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"testing"
+
+	auditinternal "github.com/HuZhou/apiserver/pkg/apis/audit"
+)
+
+func TestLogAnnotation(t *testing.T) {
+	ev := &auditinternal.Event{Level: auditinternal.LevelMetadata}
+	LogAnnotation(ev, "foo", "bar")
+	LogAnnotation(ev, "foo", "baz")
+	LogAnnotation(ev, "qux", "quux")
+
+	if ev.Annotations["foo"] != "bar" {
+		t.Errorf("expected the first value of an annotation to be kept, got %q", ev.Annotations["foo"])
+	}
+	if ev.Annotations["qux"] != "quux" {
+		t.Errorf("expected annotation qux to be set, got %v", ev.Annotations)
+	}
+	if len(ev.ObjectMeta.Annotations) > 0 {
+		t.Errorf("unexpected annotations of the event object %v", ev.ObjectMeta.Annotations)
+	}
+
+	// events below the metadata level are not annotated
+	ev = &auditinternal.Event{Level: auditinternal.LevelNone}
+	LogAnnotation(ev, "foo", "bar")
+	if len(ev.Annotations) > 0 {
+		t.Errorf("unexpected annotations %v", ev.Annotations)
+	}
+	LogAnnotation(nil, "foo", "bar")
+}
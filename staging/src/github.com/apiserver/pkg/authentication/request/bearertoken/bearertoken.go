@@ -0,0 +1,51 @@
+package bearertoken
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/HuZhou/apiserver/pkg/authentication/authenticator"
+	"github.com/HuZhou/apiserver/pkg/authentication/user"
+)
+
+// Authenticator authenticates requests carrying a bearer token in their
+// Authorization header with a token authenticator.
+type Authenticator struct {
+	auth authenticator.Token
+}
+
+// New returns a request authenticator validating bearer tokens with auth.
+func New(auth authenticator.Token) *Authenticator {
+	return &Authenticator{auth}
+}
+
+var invalidToken = errors.New("invalid bearer token")
+
+// AuthenticateRequest implements authenticator.Request.
+func (a *Authenticator) AuthenticateRequest(req *http.Request) (user.Info, bool, error) {
+	auth := strings.TrimSpace(req.Header.Get("Authorization"))
+	if auth == "" {
+		return nil, false, nil
+	}
+	parts := strings.Split(auth, " ")
+	if len(parts) < 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, false, nil
+	}
+
+	token := parts[1]
+
+	// Empty bearer tokens aren't valid
+	if len(token) == 0 {
+		return nil, false, nil
+	}
+
+	user, ok, err := a.auth.AuthenticateToken(token)
+
+	// If the token authenticator didn't error, provide a default error
+	if !ok && err == nil {
+		err = invalidToken
+	}
+
+	return user, ok, err
+}
@@ -0,0 +1,35 @@
+package discovery
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServerAddressByClientCIDRs(t *testing.T) {
+	_, internal, _ := net.ParseCIDR("10.0.0.0/24")
+	addresses := DefaultAddresses{
+		CIDRRules:      []CIDRRule{{IPRange: *internal, Address: "10.0.0.1:443"}},
+		DefaultAddress: "example.com:443",
+	}
+	defaultAddress := metav1.ServerAddressByClientCIDR{ClientCIDR: "0.0.0.0/0", ServerAddress: "example.com:443"}
+	internalAddress := metav1.ServerAddressByClientCIDR{ClientCIDR: "10.0.0.0/24", ServerAddress: "10.0.0.1:443"}
+
+	tests := []struct {
+		name     string
+		clientIP net.IP
+		expected []metav1.ServerAddressByClientCIDR
+	}{
+		{name: "internal client", clientIP: net.ParseIP("10.0.0.5"), expected: []metav1.ServerAddressByClientCIDR{defaultAddress, internalAddress}},
+		{name: "external client", clientIP: net.ParseIP("192.168.0.5"), expected: []metav1.ServerAddressByClientCIDR{defaultAddress}},
+		{name: "unknown client", expected: []metav1.ServerAddressByClientCIDR{defaultAddress}},
+	}
+
+	for _, tt := range tests {
+		if got := addresses.ServerAddressByClientCIDRs(tt.clientIP); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
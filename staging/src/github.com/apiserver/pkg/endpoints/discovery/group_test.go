@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+func TestAPIGroupHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		group            metav1.APIGroup
+		expectAPIVersion string
+	}{
+		{name: "group", group: group("apps", "v1", "v1beta1"), expectAPIVersion: "v1"},
+		{name: "extensions", group: group("extensions", "v1beta1"), expectAPIVersion: ""},
+	}
+
+	for _, tt := range tests {
+		mapper := request.NewRequestContextMapper()
+		code, body := serve(NewAPIGroupHandler(codecs, tt.group, mapper).WebService(), mapper, APIGroupPrefix+"/"+tt.group.Name, "192.168.0.5")
+		if code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, http.StatusOK, code, string(body))
+			continue
+		}
+		apiGroup := &metav1.APIGroup{}
+		if apiVersion := decode(t, body, apiGroup); apiVersion != tt.expectAPIVersion {
+			t.Errorf("%s: expected apiVersion %q, got %q", tt.name, tt.expectAPIVersion, apiVersion)
+		}
+		apiGroup.TypeMeta = metav1.TypeMeta{}
+		if !reflect.DeepEqual(*apiGroup, tt.group) {
+			t.Errorf("%s: expected group %#v, got %#v", tt.name, tt.group, *apiGroup)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+func TestLegacyRootAPIHandler(t *testing.T) {
+	_, internal, _ := net.ParseCIDR("10.0.0.0/24")
+	addresses := DefaultAddresses{CIDRRules: []CIDRRule{{IPRange: *internal, Address: "10.0.0.1:443"}}, DefaultAddress: "example.com:443"}
+
+	tests := []struct {
+		name            string
+		prefix          string
+		versions        []string
+		clientIP        string
+		expectAddresses int
+	}{
+		{name: "api", prefix: "/api", versions: []string{"v1"}, clientIP: "192.168.0.5", expectAddresses: 1},
+		{name: "internal client", prefix: "/api", versions: []string{"v1"}, clientIP: "10.0.0.5", expectAddresses: 2},
+		{name: "other prefix", prefix: "/legacy", versions: []string{"v2", "v1"}, clientIP: "192.168.0.5", expectAddresses: 1},
+	}
+
+	for _, tt := range tests {
+		mapper := request.NewRequestContextMapper()
+		code, body := serve(NewLegacyRootAPIHandler(addresses, codecs, tt.prefix, tt.versions, mapper).WebService(), mapper, tt.prefix, tt.clientIP)
+		if code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, http.StatusOK, code, string(body))
+			continue
+		}
+		versions := &metav1.APIVersions{}
+		if apiVersion := decode(t, body, versions); apiVersion != "" {
+			t.Errorf("%s: expected the apiVersion to be stripped, got %q", tt.name, apiVersion)
+		}
+		if !reflect.DeepEqual(versions.Versions, tt.versions) {
+			t.Errorf("%s: expected versions %v, got %v", tt.name, tt.versions, versions.Versions)
+		}
+		if len(versions.ServerAddressByClientCIDRs) != tt.expectAddresses {
+			t.Errorf("%s: expected %d server addresses, got %v", tt.name, tt.expectAddresses, versions.ServerAddressByClientCIDRs)
+		}
+	}
+}
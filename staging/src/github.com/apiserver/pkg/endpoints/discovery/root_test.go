@@ -0,0 +1,170 @@
+package discovery
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+func init() {
+	// the discovery types are served in the legacy v1 version
+	scheme.AddUnversionedTypes(schema.GroupVersion{Version: "v1"},
+		&metav1.Status{},
+		&metav1.APIVersions{},
+		&metav1.APIGroupList{},
+		&metav1.APIGroup{},
+		&metav1.APIResourceList{},
+	)
+}
+
+// serve registers ws in a container and serves a GET of path from clientIP.
+// It returns the status code and the raw body of the response.
+func serve(ws *restful.WebService, mapper request.RequestContextMapper, path, clientIP string) (int, []byte) {
+	container := restful.NewContainer()
+	container.Add(ws)
+	req := httptest.NewRequest("GET", path, nil)
+	req.RemoteAddr = net.JoinHostPort(clientIP, "12345")
+	w := httptest.NewRecorder()
+	request.WithRequestContext(container, mapper).ServeHTTP(w, req)
+	return w.Code, w.Body.Bytes()
+}
+
+// decode unmarshals body into into and returns the apiVersion it was sent with.
+func decode(t *testing.T, body []byte, into interface{}) string {
+	if err := json.Unmarshal(body, into); err != nil {
+		t.Fatalf("unexpected response %q: %v", string(body), err)
+	}
+	typeMeta := &metav1.TypeMeta{}
+	if err := json.Unmarshal(body, typeMeta); err != nil {
+		t.Fatalf("unexpected response %q: %v", string(body), err)
+	}
+	return typeMeta.APIVersion
+}
+
+func group(name string, versions ...string) metav1.APIGroup {
+	apiGroup := metav1.APIGroup{Name: name}
+	for _, version := range versions {
+		apiGroup.Versions = append(apiGroup.Versions, metav1.GroupVersionForDiscovery{GroupVersion: name + "/" + version, Version: version})
+	}
+	apiGroup.PreferredVersion = apiGroup.Versions[0]
+	return apiGroup
+}
+
+func TestRootAPIsHandler(t *testing.T) {
+	_, internal, _ := net.ParseCIDR("10.0.0.0/24")
+	addresses := DefaultAddresses{CIDRRules: []CIDRRule{{IPRange: *internal, Address: "10.0.0.1:443"}}, DefaultAddress: "example.com:443"}
+
+	tests := []struct {
+		name     string
+		add      []metav1.APIGroup
+		remove   []string
+		clientIP string
+		// expectGroups are the names of the listed groups, in order
+		expectGroups    []string
+		expectPreferred []string
+		expectAddresses int
+	}{
+		{name: "no groups", clientIP: "192.168.0.5", expectGroups: []string{}, expectPreferred: []string{}},
+		{
+			name:            "insertion order",
+			add:             []metav1.APIGroup{group("b", "v1"), group("a", "v1")},
+			clientIP:        "192.168.0.5",
+			expectGroups:    []string{"b", "a"},
+			expectPreferred: []string{"v1", "v1"},
+			expectAddresses: 1,
+		},
+		{
+			name:            "readded group keeps its place",
+			add:             []metav1.APIGroup{group("b", "v1"), group("a", "v1"), group("b", "v2", "v1")},
+			clientIP:        "192.168.0.5",
+			expectGroups:    []string{"b", "a"},
+			expectPreferred: []string{"v2", "v1"},
+			expectAddresses: 1,
+		},
+		{
+			name:            "removed group",
+			add:             []metav1.APIGroup{group("b", "v1"), group("a", "v1"), group("c", "v1")},
+			remove:          []string{"a", "unknown"},
+			clientIP:        "192.168.0.5",
+			expectGroups:    []string{"b", "c"},
+			expectPreferred: []string{"v1", "v1"},
+			expectAddresses: 1,
+		},
+		{
+			name:            "internal client",
+			add:             []metav1.APIGroup{group("a", "v1")},
+			clientIP:        "10.0.0.5",
+			expectGroups:    []string{"a"},
+			expectPreferred: []string{"v1"},
+			expectAddresses: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		mapper := request.NewRequestContextMapper()
+		handler := NewRootAPIsHandler(addresses, codecs, mapper)
+		for _, apiGroup := range tt.add {
+			handler.AddGroup(apiGroup)
+		}
+		for _, name := range tt.remove {
+			handler.RemoveGroup(name)
+		}
+
+		code, body := serve(handler.WebService(), mapper, APIGroupPrefix, tt.clientIP)
+		if code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, http.StatusOK, code, string(body))
+			continue
+		}
+		groupList := &metav1.APIGroupList{}
+		if apiVersion := decode(t, body, groupList); apiVersion != "" {
+			t.Errorf("%s: expected the apiVersion to be stripped, got %q", tt.name, apiVersion)
+		}
+		names := []string{}
+		for _, apiGroup := range groupList.Groups {
+			names = append(names, apiGroup.Name)
+			if len(apiGroup.ServerAddressByClientCIDRs) != tt.expectAddresses {
+				t.Errorf("%s: expected %d server addresses for %s, got %v", tt.name, tt.expectAddresses, apiGroup.Name, apiGroup.ServerAddressByClientCIDRs)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.expectGroups) {
+			t.Errorf("%s: expected groups %v, got %v", tt.name, tt.expectGroups, names)
+		}
+		preferred := []string{}
+		for _, apiGroup := range groupList.Groups {
+			preferred = append(preferred, apiGroup.PreferredVersion.Version)
+		}
+		if !reflect.DeepEqual(preferred, tt.expectPreferred) {
+			t.Errorf("%s: expected preferred versions %v, got %v", tt.name, tt.expectPreferred, preferred)
+		}
+	}
+}
+
+func TestDiscoveryWithoutContext(t *testing.T) {
+	mapper := request.NewRequestContextMapper()
+	handlers := map[string]http.Handler{
+		"root":    NewRootAPIsHandler(DefaultAddresses{}, codecs, mapper),
+		"group":   NewAPIGroupHandler(codecs, group("a", "v1"), mapper),
+		"version": NewAPIVersionHandler(codecs, schema.GroupVersion{Group: "a", Version: "v1"}, APIResourceListerFunc(func() []metav1.APIResource { return nil }), mapper),
+	}
+	for name, handler := range handlers {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected status %d without a request context, got %d", name, http.StatusInternalServerError, w.Code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package discovery
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+func TestAPIVersionHandler(t *testing.T) {
+	resources := []metav1.APIResource{
+		{Name: "carps", Namespaced: true, Kind: "Carp", Verbs: metav1.Verbs{"get", "list"}},
+		{Name: "carps/status", Namespaced: true, Kind: "Carp", Verbs: metav1.Verbs{"get"}},
+	}
+
+	tests := []struct {
+		name             string
+		groupVersion     schema.GroupVersion
+		resources        []metav1.APIResource
+		expectAPIVersion string
+	}{
+		{name: "group version", groupVersion: schema.GroupVersion{Group: "apps", Version: "v1"}, resources: resources, expectAPIVersion: "v1"},
+		{name: "no resources", groupVersion: schema.GroupVersion{Group: "apps", Version: "v1"}, expectAPIVersion: "v1"},
+		{name: "legacy version", groupVersion: schema.GroupVersion{Version: "v1"}, resources: resources, expectAPIVersion: ""},
+	}
+
+	for _, tt := range tests {
+		mapper := request.NewRequestContextMapper()
+		list := tt.resources
+		handler := NewAPIVersionHandler(codecs, tt.groupVersion, APIResourceListerFunc(func() []metav1.APIResource { return list }), mapper)
+		ws := new(restful.WebService)
+		ws.Path("/apis/" + tt.groupVersion.String())
+		handler.AddToWebService(ws)
+
+		code, body := serve(ws, mapper, "/apis/"+tt.groupVersion.String(), "192.168.0.5")
+		if code != http.StatusOK {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, http.StatusOK, code, string(body))
+			continue
+		}
+		resourceList := &metav1.APIResourceList{}
+		if apiVersion := decode(t, body, resourceList); apiVersion != tt.expectAPIVersion {
+			t.Errorf("%s: expected apiVersion %q, got %q", tt.name, tt.expectAPIVersion, apiVersion)
+		}
+		if resourceList.GroupVersion != tt.groupVersion.String() {
+			t.Errorf("%s: expected group version %q, got %q", tt.name, tt.groupVersion.String(), resourceList.GroupVersion)
+		}
+		if !reflect.DeepEqual(resourceList.APIResources, tt.resources) {
+			t.Errorf("%s: expected resources %v, got %v", tt.name, tt.resources, resourceList.APIResources)
+		}
+	}
+}
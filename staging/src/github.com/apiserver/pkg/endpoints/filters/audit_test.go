@@ -0,0 +1,83 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auditinternal "github.com/HuZhou/apiserver/pkg/apis/audit"
+	"github.com/HuZhou/apiserver/pkg/audit"
+	"github.com/HuZhou/apiserver/pkg/authorization/authorizer"
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+// recordingSink keeps the stage and annotations of every event it is sent.
+// Events are reused by the caller, so nothing else of them is kept.
+type recordingSink struct {
+	stages      []auditinternal.Stage
+	annotations []map[string]string
+}
+
+func (s *recordingSink) ProcessEvents(events ...*auditinternal.Event) {
+	for _, ev := range events {
+		annotations := map[string]string{}
+		for key, value := range ev.Annotations {
+			annotations[key] = value
+		}
+		s.stages = append(s.stages, ev.Stage)
+		s.annotations = append(s.annotations, annotations)
+	}
+}
+
+type fixedLevel auditinternal.Level
+
+func (l fixedLevel) Level(authorizer.Attributes) auditinternal.Level {
+	return auditinternal.Level(l)
+}
+
+func TestWithAuditAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       auditinternal.Level
+		annotate    bool
+		expectValue string
+	}{
+		{name: "annotated", level: auditinternal.LevelMetadata, annotate: true, expectValue: "true"},
+		{name: "not annotated", level: auditinternal.LevelMetadata},
+		{name: "request level", level: auditinternal.LevelRequest, annotate: true, expectValue: "true"},
+	}
+
+	for _, tt := range tests {
+		mapper := request.NewRequestContextMapper()
+		sink := &recordingSink{}
+		annotate := tt.annotate
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, _ := mapper.Get(req)
+			if annotate {
+				audit.LogAnnotation(request.AuditEventFrom(ctx), "dryrun.apiserver.k8s.io/dry-run", "true")
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		handler = WithAudit(handler, mapper, sink, fixedLevel(tt.level), nil)
+		withRequestInfo := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx, _ := mapper.Get(req)
+			mapper.Update(req, request.WithRequestInfo(ctx, &request.RequestInfo{IsResourceRequest: true, Verb: "create", Resource: "secrets"}))
+			handler.ServeHTTP(w, req)
+		})
+
+		req := httptest.NewRequest("POST", "/api/v1/namespaces/ns/secrets?dryRun=All", nil)
+		request.WithRequestContext(withRequestInfo, mapper).ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(sink.stages) == 0 || sink.stages[len(sink.stages)-1] != auditinternal.StageResponseComplete {
+			t.Errorf("%s: expected the sink to receive the completed event, got stages %v", tt.name, sink.stages)
+			continue
+		}
+		if len(sink.annotations[0]) != 0 {
+			t.Errorf("%s: expected no annotations when the request is received, got %v", tt.name, sink.annotations[0])
+		}
+		last := sink.annotations[len(sink.annotations)-1]
+		if value := last["dryrun.apiserver.k8s.io/dry-run"]; value != tt.expectValue {
+			t.Errorf("%s: expected the dry-run annotation %q to reach the sink, got %v", tt.name, tt.expectValue, last)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package negotiation
 
 import (
+	"mime"
 	"net/http"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -104,7 +105,10 @@ func NegotiateMediaTypeOptions(header string, accepted []AcceptedMediaType, endp
 				clause.Type == "*" && clause.SubType == "*":
 				// TODO: should we prefer the first type with no unrecognized options?  Do we need to ignore unrecognized
 				// parameters.
-				return acceptMediaTypeOptions(clause.Params, accepts, endpoint)
+				// a clause with options the endpoint does not allow falls through to the next one
+				if options, ok := acceptMediaTypeOptions(clause.Params, accepts, endpoint); ok {
+					return options, true
+				}
 			}
 		}
 	}
@@ -149,6 +153,49 @@ func NegotiateOutputSerializer(req *http.Request, ns runtime.NegotiatedSerialize
 	return info, err
 }
 
+// NegotiateOutputStreamSerializer returns the serializer used to write a watch stream for the
+// provided request.
+func NegotiateOutputStreamSerializer(req *http.Request, ns runtime.NegotiatedSerializer) (runtime.SerializerInfo, error) {
+	_, info, err := NegotiateOutputMediaType(req, ns, DefaultEndpointRestrictions)
+	if err != nil {
+		return runtime.SerializerInfo{}, err
+	}
+	if info.StreamSerializer == nil {
+		_, supported := MediaTypesForSerializer(ns)
+		return runtime.SerializerInfo{}, NewNotAcceptableError(supported)
+	}
+	return info, nil
+}
+
+// NegotiateInputSerializer returns the input serializer for the provided request.
+func NegotiateInputSerializer(req *http.Request, ns runtime.NegotiatedSerializer) (runtime.SerializerInfo, error) {
+	mediaType := req.Header.Get("Content-Type")
+	return NegotiateInputSerializerForMediaType(mediaType, ns)
+}
+
+// NegotiateInputSerializerForMediaType returns the appropriate serializer for the given media type or an error.
+func NegotiateInputSerializerForMediaType(mediaType string, ns runtime.NegotiatedSerializer) (runtime.SerializerInfo, error) {
+	mediaTypes := ns.SupportedMediaTypes()
+	if len(mediaType) == 0 {
+		mediaType = mediaTypes[0].MediaType
+	}
+	mediaType, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		supported, _ := MediaTypesForSerializer(ns)
+		return runtime.SerializerInfo{}, NewUnsupportedMediaTypeError(supported)
+	}
+
+	for _, info := range mediaTypes {
+		if info.MediaType != mediaType {
+			continue
+		}
+		return info, nil
+	}
+
+	supported, _ := MediaTypesForSerializer(ns)
+	return runtime.SerializerInfo{}, NewUnsupportedMediaTypeError(supported)
+}
+
 
 // acceptMediaTypeOptions returns an options object that matches the provided media type params. If
 // it returns false, the provided options are not allowed and the media type must be skipped.  These
@@ -0,0 +1,138 @@
+package negotiation
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// tableEndpoint allows conversion to a meta.k8s.io Table in the given versions.
+type tableEndpoint struct {
+	versions []string
+}
+
+func (e tableEndpoint) AllowsConversion(gvk schema.GroupVersionKind) bool {
+	if gvk.Group != "meta.k8s.io" || gvk.Kind != "Table" {
+		return false
+	}
+	for _, version := range e.versions {
+		if gvk.Version == version {
+			return true
+		}
+	}
+	return false
+}
+func (tableEndpoint) AllowsServerVersion(version string) bool { return version == "v1" }
+func (tableEndpoint) AllowsStreamSchema(s string) bool        { return s == "watch" }
+
+func acceptedMediaTypes() []AcceptedMediaType {
+	return []AcceptedMediaType{
+		{Type: "application", SubType: "json", Serializer: runtime.SerializerInfo{MediaType: "application/json", StreamSerializer: &runtime.StreamSerializerInfo{}}},
+		{Type: "application", SubType: "yaml", Serializer: runtime.SerializerInfo{MediaType: "application/yaml"}},
+	}
+}
+
+func TestNegotiateMediaTypeOptions(t *testing.T) {
+	tableV1beta1 := &schema.GroupVersionKind{Group: "meta.k8s.io", Version: "v1beta1", Kind: "Table"}
+	tableV1alpha1 := &schema.GroupVersionKind{Group: "meta.k8s.io", Version: "v1alpha1", Kind: "Table"}
+
+	tests := []struct {
+		name      string
+		header    string
+		endpoint  EndpointRestrictions
+		ok        bool
+		mediaType string
+		convert   *schema.GroupVersionKind
+		stream    string
+	}{
+		{name: "no header", endpoint: DefaultEndpointRestrictions, ok: true, mediaType: "application/json"},
+		{name: "plain json", header: "application/json", endpoint: DefaultEndpointRestrictions, ok: true, mediaType: "application/json"},
+		{name: "wildcard", header: "*/*", endpoint: DefaultEndpointRestrictions, ok: true, mediaType: "application/json"},
+		{name: "unknown type", header: "text/plain", endpoint: DefaultEndpointRestrictions},
+		{
+			name:      "v1beta1 table",
+			header:    "application/json;as=Table;v=v1beta1;g=meta.k8s.io",
+			endpoint:  tableEndpoint{versions: []string{"v1beta1", "v1alpha1"}},
+			ok:        true,
+			mediaType: "application/json",
+			convert:   tableV1beta1,
+		},
+		{
+			name:      "v1alpha1 table",
+			header:    "application/json;as=Table;v=v1alpha1;g=meta.k8s.io",
+			endpoint:  tableEndpoint{versions: []string{"v1beta1", "v1alpha1"}},
+			ok:        true,
+			mediaType: "application/json",
+			convert:   tableV1alpha1,
+		},
+		{
+			name:      "preferred table version",
+			header:    "application/json;as=Table;v=v1beta1;g=meta.k8s.io, application/json;as=Table;v=v1alpha1;g=meta.k8s.io",
+			endpoint:  tableEndpoint{versions: []string{"v1beta1", "v1alpha1"}},
+			ok:        true,
+			mediaType: "application/json",
+			convert:   tableV1beta1,
+		},
+		{
+			name:      "unsupported table version falls through to the next table",
+			header:    "application/json;as=Table;v=v1;g=meta.k8s.io, application/json;as=Table;v=v1alpha1;g=meta.k8s.io",
+			endpoint:  tableEndpoint{versions: []string{"v1beta1", "v1alpha1"}},
+			ok:        true,
+			mediaType: "application/json",
+			convert:   tableV1alpha1,
+		},
+		{
+			name:      "disallowed table falls through to plain json",
+			header:    "application/json;as=Table;v=v1beta1;g=meta.k8s.io, application/json",
+			endpoint:  DefaultEndpointRestrictions,
+			ok:        true,
+			mediaType: "application/json",
+		},
+		{
+			name:     "disallowed table without fallback",
+			header:   "application/json;as=Table;v=v1beta1;g=meta.k8s.io",
+			endpoint: DefaultEndpointRestrictions,
+		},
+		{
+			name:      "unsupported server version falls through",
+			header:    "application/json;sv=v2, application/yaml",
+			endpoint:  tableEndpoint{},
+			ok:        true,
+			mediaType: "application/yaml",
+		},
+		{
+			name:      "stream without a stream serializer falls through",
+			header:    "application/yaml;stream=watch, application/json;stream=watch",
+			endpoint:  tableEndpoint{},
+			ok:        true,
+			mediaType: "application/json",
+			stream:    "watch",
+		},
+		{
+			name:     "every clause rejected",
+			header:   "application/json;stream=other, application/yaml;stream=watch, application/json;sv=v2",
+			endpoint: tableEndpoint{},
+		},
+	}
+
+	for _, tt := range tests {
+		options, ok := NegotiateMediaTypeOptions(tt.header, acceptedMediaTypes(), tt.endpoint)
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%t, got %t", tt.name, tt.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if options.Accepted.Serializer.MediaType != tt.mediaType {
+			t.Errorf("%s: expected media type %q, got %q", tt.name, tt.mediaType, options.Accepted.Serializer.MediaType)
+		}
+		if (options.Convert == nil) != (tt.convert == nil) || (tt.convert != nil && *options.Convert != *tt.convert) {
+			t.Errorf("%s: expected conversion to %v, got %v", tt.name, tt.convert, options.Convert)
+		}
+		if options.Stream != tt.stream {
+			t.Errorf("%s: expected stream %q, got %q", tt.name, tt.stream, options.Stream)
+		}
+	}
+}
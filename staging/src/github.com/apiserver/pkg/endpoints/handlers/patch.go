@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evanphx/json-patch"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/negotiation"
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/responsewriters"
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// PatchResource returns a function that will handle a resource patch
+func PatchResource(r rest.Patcher, scope RequestScope, patchTypes []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Do this first, otherwise name extraction can fail for unrecognized content types
+		// TODO: handle this in negotiation
+		contentType := req.Header.Get("Content-Type")
+		// Remove "; charset=" if included in header.
+		if idx := strings.Index(contentType, ";"); idx > 0 {
+			contentType = contentType[:idx]
+		}
+		patchType := types.PatchType(contentType)
+
+		// Ensure the patchType is one we support
+		if !sets.NewString(patchTypes...).Has(contentType) {
+			scope.err(negotiation.NewUnsupportedMediaTypeError(patchTypes), w, req)
+			return
+		}
+
+		// TODO: we either want to remove timeout or document it (if we document, move timeout out of this function and declare it in api_installer)
+		timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+		namespace, name, err := scope.Namer.Name(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+		ctx, err = withDryRun(ctx, req, scope.SupportsDryRun)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		patchJS, err := readBody(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		s, ok := runtime.SerializerInfoForMediaType(scope.Serializer.SupportedMediaTypes(), runtime.ContentTypeJSON)
+		if !ok {
+			scope.err(fmt.Errorf("no serializer defined for JSON"), w, req)
+			return
+		}
+		gv := scope.Kind.GroupVersion()
+		// strategic merge patches are computed against the struct tags of
+		// the versioned type the client sees
+		versionedObj, err := scope.UnsafeConvertor.ConvertToVersion(r.New(), gv)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		codec := runtime.NewCodec(
+			scope.Serializer.EncoderForVersion(s.Serializer, gv),
+			scope.Serializer.DecoderToVersion(s.Serializer, schema.GroupVersion{Group: gv.Group, Version: runtime.APIVersionInternal}),
+		)
+
+		result, err := patchResource(ctx, timeout, r, name, patchType, patchJS, versionedObj, scope.Namer, codec, authorizeInitializers(scope.Authorizer))
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		if err := setSelfLink(result, req, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, http.StatusOK, result)
+	}
+}
+
+// patchResource applies the patch to the current state of the named object and stores the
+// result. The patch is applied from within the storage GuaranteedUpdate, so whenever the
+// write conflicts with a concurrent change (storage.IsConflict) the patch is re-applied to
+// the fresh object. A conflict is only returned to the client if the patch itself pins a
+// stale metadata.resourceVersion. The transformers are applied to the patched object.
+func patchResource(
+	ctx request.Context,
+	timeout time.Duration,
+	patcher rest.Patcher,
+	name string,
+	patchType types.PatchType,
+	patchJS []byte,
+	versionedObj runtime.Object,
+	namer ScopeNamer,
+	codec runtime.Codec,
+	transformers ...rest.TransformFunc,
+) (runtime.Object, error) {
+	namespace := request.NamespaceValue(ctx)
+
+	applyPatch := func(_ request.Context, _, currentObject runtime.Object) (runtime.Object, error) {
+		currentObjectJS, err := runtime.Encode(codec, currentObject)
+		if err != nil {
+			return nil, err
+		}
+		patchedObjectJS, err := applyJSPatch(patchType, patchJS, currentObjectJS, versionedObj)
+		if err != nil {
+			return nil, err
+		}
+
+		objToUpdate := patcher.New()
+		if err := runtime.DecodeInto(codec, patchedObjectJS, objToUpdate); err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		if err := checkName(objToUpdate, name, namespace, namer); err != nil {
+			return nil, err
+		}
+		return objToUpdate, nil
+	}
+
+	return finishRequest(timeout, func() (runtime.Object, error) {
+		updateObject, _, updateErr := patcher.Update(ctx, name, rest.DefaultUpdatedObjectInfo(nil, append([]rest.TransformFunc{applyPatch}, transformers...)...))
+		return updateObject, updateErr
+	})
+}
+
+// applyJSPatch applies the patch of the given type to the JSON encoded object.
+// versionedObj provides the patch strategies of strategic merge patches.
+func applyJSPatch(patchType types.PatchType, patchJS, originalJS []byte, versionedObj runtime.Object) ([]byte, error) {
+	switch patchType {
+	case types.JSONPatchType:
+		patchObj, err := jsonpatch.DecodePatch(patchJS)
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		patchedJS, err := patchObj.Apply(originalJS)
+		if err != nil {
+			return nil, newUnprocessablePatchError(err)
+		}
+		return patchedJS, nil
+	case types.MergePatchType:
+		patchedJS, err := jsonpatch.MergePatch(originalJS, patchJS)
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		return patchedJS, nil
+	case types.StrategicMergePatchType:
+		patchedJS, err := strategicpatch.StrategicMergePatch(originalJS, patchJS, versionedObj)
+		if err != nil {
+			return nil, interpretPatchError(err)
+		}
+		return patchedJS, nil
+	default:
+		// only here as a safety net - go-restful filters content-type
+		return nil, fmt.Errorf("unknown Content-Type header for patch: %v", patchType)
+	}
+}
+
+// interpretPatchError interprets the error type and returns an error with appropriate HTTP code.
+func interpretPatchError(err error) error {
+	switch err {
+	case mergepatch.ErrBadJSONDoc, mergepatch.ErrBadPatchFormatForPrimitiveList, mergepatch.ErrBadPatchFormatForRetainKeys, mergepatch.ErrBadPatchFormatForSetElementOrderList:
+		return errors.NewBadRequest(err.Error())
+	case mergepatch.ErrNoListOfLists, mergepatch.ErrPatchContentNotMatchRetainKeys:
+		return newUnprocessablePatchError(err)
+	default:
+		return err
+	}
+}
+
+// newUnprocessablePatchError returns a 422 error for a well-formed patch that cannot be
+// applied to the current object, keeping the reason in the message seen by clients.
+func newUnprocessablePatchError(err error) error {
+	return &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusUnprocessableEntity,
+		Reason:  metav1.StatusReasonInvalid,
+		Message: fmt.Sprintf("the patch cannot be applied: %v", err),
+	}}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/mergepatch"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+)
+
+var patchScheme = runtime.NewScheme()
+var patchCodecs = serializer.NewCodecFactory(patchScheme)
+
+func init() {
+	metav1.AddToGroupVersion(patchScheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(patchScheme)
+	testapigroupv1.AddToScheme(patchScheme)
+}
+
+// testPatcher stores a single carp. Like the storage, it retries an update
+// that conflicts with a concurrent change against the fresh object, and
+// rejects updates pinning a stale resourceVersion.
+type testPatcher struct {
+	current *testapigroup.Carp
+	// concurrent is applied to the stored carp while the first update is
+	// in progress
+	concurrent func(*testapigroup.Carp)
+}
+
+func (p *testPatcher) New() runtime.Object {
+	return &testapigroup.Carp{}
+}
+
+func (p *testPatcher) Get(ctx request.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	return p.current.DeepCopy(), nil
+}
+
+func (p *testPatcher) Update(ctx request.Context, name string, objInfo rest.UpdatedObjectInfo) (runtime.Object, bool, error) {
+	for {
+		current := p.current.DeepCopy()
+		obj, err := objInfo.UpdatedObject(ctx, current)
+		if err != nil {
+			return nil, false, err
+		}
+		if p.concurrent != nil {
+			p.concurrent(p.current)
+			p.current.ResourceVersion = "2"
+			p.concurrent = nil
+			continue
+		}
+		updated := obj.(*testapigroup.Carp)
+		if len(updated.ResourceVersion) > 0 && updated.ResourceVersion != p.current.ResourceVersion {
+			return nil, false, errors.NewConflict(testapigroup.Resource("carps"), name, fmt.Errorf("the object has been modified"))
+		}
+		p.current = updated
+		return updated, false, nil
+	}
+}
+
+func newPatchedCarp() *testapigroup.Carp {
+	return &testapigroup.Carp{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns", ResourceVersion: "1", Labels: map[string]string{"a": "b"}},
+		Status: testapigroup.CarpStatus{
+			Conditions: []testapigroup.CarpCondition{{Type: "Ready", Status: "True"}},
+		},
+	}
+}
+
+func TestPatchResource(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType types.PatchType
+		patch     string
+		// concurrent changes the stored carp while the patch is applied
+		concurrent   func(*testapigroup.Carp)
+		expectLabels map[string]string
+		// expectConditions are the types of the conditions of the result
+		expectConditions []testapigroup.CarpConditionType
+		expectCode       int32
+	}{
+		{
+			name:             "json patch",
+			patchType:        types.JSONPatchType,
+			patch:            `[{"op": "add", "path": "/metadata/labels/c", "value": "d"}]`,
+			expectLabels:     map[string]string{"a": "b", "c": "d"},
+			expectConditions: []testapigroup.CarpConditionType{"Ready"},
+		},
+		{
+			name:             "merge patch",
+			patchType:        types.MergePatchType,
+			patch:            `{"metadata": {"labels": {"a": null, "c": "d"}}}`,
+			expectLabels:     map[string]string{"c": "d"},
+			expectConditions: []testapigroup.CarpConditionType{"Ready"},
+		},
+		{
+			name:             "merge patch replacing a list",
+			patchType:        types.MergePatchType,
+			patch:            `{"status": {"conditions": [{"type": "Scheduled", "status": "True"}]}}`,
+			expectLabels:     map[string]string{"a": "b"},
+			expectConditions: []testapigroup.CarpConditionType{"Scheduled"},
+		},
+		{
+			name:             "strategic merge patch merging a list",
+			patchType:        types.StrategicMergePatchType,
+			patch:            `{"status": {"conditions": [{"type": "Scheduled", "status": "True"}]}}`,
+			expectLabels:     map[string]string{"a": "b"},
+			expectConditions: []testapigroup.CarpConditionType{"Scheduled", "Ready"},
+		},
+		{
+			name:       "malformed json patch",
+			patchType:  types.JSONPatchType,
+			patch:      `{"op": "add"}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "json patch that cannot be applied",
+			patchType:  types.JSONPatchType,
+			patch:      `[{"op": "test", "path": "/metadata/labels/a", "value": "c"}]`,
+			expectCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "malformed merge patch",
+			patchType:  types.MergePatchType,
+			patch:      `{"metadata": `,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed strategic merge patch",
+			patchType:  types.StrategicMergePatchType,
+			patch:      `{"metadata": `,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:       "patch renaming the object",
+			patchType:  types.MergePatchType,
+			patch:      `{"metadata": {"name": "bar"}}`,
+			expectCode: http.StatusBadRequest,
+		},
+		{
+			name:             "concurrent change",
+			patchType:        types.MergePatchType,
+			patch:            `{"metadata": {"labels": {"c": "d"}}}`,
+			concurrent:       func(carp *testapigroup.Carp) { carp.Labels["e"] = "f" },
+			expectLabels:     map[string]string{"a": "b", "c": "d", "e": "f"},
+			expectConditions: []testapigroup.CarpConditionType{"Ready"},
+		},
+		{
+			name:       "concurrent change with a pinned resourceVersion",
+			patchType:  types.MergePatchType,
+			patch:      `{"metadata": {"resourceVersion": "1", "labels": {"c": "d"}}}`,
+			concurrent: func(carp *testapigroup.Carp) { carp.Labels["e"] = "f" },
+			expectCode: http.StatusConflict,
+		},
+	}
+
+	namer := ContextBasedNaming{SelfLinker: meta.NewAccessor()}
+	codec := patchCodecs.LegacyCodec(testapigroupv1.SchemeGroupVersion)
+	for _, tt := range tests {
+		patcher := &testPatcher{current: newPatchedCarp(), concurrent: tt.concurrent}
+		ctx := request.WithNamespace(request.NewContext(), "ns")
+		result, err := patchResource(ctx, time.Minute, patcher, "foo", tt.patchType, []byte(tt.patch), &testapigroupv1.Carp{}, namer, codec)
+
+		if tt.expectCode != 0 {
+			status, ok := err.(errors.APIStatus)
+			if !ok || status.Status().Code != tt.expectCode {
+				t.Errorf("%s: expected an error with code %d, got %v", tt.name, tt.expectCode, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		carp := result.(*testapigroup.Carp)
+		if !reflect.DeepEqual(carp.Labels, tt.expectLabels) {
+			t.Errorf("%s: expected labels %v, got %v", tt.name, tt.expectLabels, carp.Labels)
+		}
+		conditions := []testapigroup.CarpConditionType{}
+		for _, condition := range carp.Status.Conditions {
+			conditions = append(conditions, condition.Type)
+		}
+		if !reflect.DeepEqual(conditions, tt.expectConditions) {
+			t.Errorf("%s: expected conditions %v, got %v", tt.name, tt.expectConditions, conditions)
+		}
+	}
+}
+
+func TestApplyJSPatchUnknownType(t *testing.T) {
+	_, err := applyJSPatch(types.PatchType("application/yaml"), []byte(`{}`), []byte(`{}`), &testapigroupv1.Carp{})
+	if err == nil {
+		t.Fatalf("expected an error for an unknown patch type")
+	}
+	if _, ok := err.(errors.APIStatus); ok {
+		t.Errorf("expected an internal error, got %v", err)
+	}
+}
+
+func TestInterpretPatchError(t *testing.T) {
+	other := fmt.Errorf("other")
+
+	tests := []struct {
+		name       string
+		err        error
+		expectCode int32
+	}{
+		{name: "bad json", err: mergepatch.ErrBadJSONDoc, expectCode: http.StatusBadRequest},
+		{name: "bad primitive list", err: mergepatch.ErrBadPatchFormatForPrimitiveList, expectCode: http.StatusBadRequest},
+		{name: "bad retain keys", err: mergepatch.ErrBadPatchFormatForRetainKeys, expectCode: http.StatusBadRequest},
+		{name: "bad element order", err: mergepatch.ErrBadPatchFormatForSetElementOrderList, expectCode: http.StatusBadRequest},
+		{name: "list of lists", err: mergepatch.ErrNoListOfLists, expectCode: http.StatusUnprocessableEntity},
+		{name: "retain keys mismatch", err: mergepatch.ErrPatchContentNotMatchRetainKeys, expectCode: http.StatusUnprocessableEntity},
+		{name: "other error", err: other},
+	}
+
+	for _, tt := range tests {
+		err := interpretPatchError(tt.err)
+		if tt.expectCode == 0 {
+			if err != tt.err {
+				t.Errorf("%s: expected the error to be returned unchanged, got %v", tt.name, err)
+			}
+			continue
+		}
+		status, ok := err.(errors.APIStatus)
+		if !ok || status.Status().Code != tt.expectCode {
+			t.Errorf("%s: expected an error with code %d, got %v", tt.name, tt.expectCode, err)
+			continue
+		}
+		if !strings.Contains(status.Status().Message, tt.err.Error()) {
+			t.Errorf("%s: expected the message to contain %q, got %q", tt.name, tt.err.Error(), status.Status().Message)
+		}
+	}
+}
+
+func TestNewUnprocessablePatchError(t *testing.T) {
+	err := newUnprocessablePatchError(fmt.Errorf("testing value failed"))
+	if !errors.IsInvalid(err) {
+		t.Errorf("expected an invalid error, got %v", err)
+	}
+	status := err.(errors.APIStatus).Status()
+	if status.Code != http.StatusUnprocessableEntity || status.Status != metav1.StatusFailure {
+		t.Errorf("expected a failed status with code %d, got %#v", http.StatusUnprocessableEntity, status)
+	}
+	if expected := "the patch cannot be applied: testing value failed"; status.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, status.Message)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1alpha1 "k8s.io/apimachinery/pkg/apis/meta/v1alpha1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/negotiation"
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/responsewriters"
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+// transformResponseObject takes an object loaded from storage and performs any necessary transformations.
+// Will write the complete response object.
+func transformResponseObject(ctx request.Context, scope RequestScope, req *http.Request, w http.ResponseWriter, statusCode int, result runtime.Object) {
+	// TODO: fetch the media type much earlier in request processing and separate this logic.
+	mediaType, _, err := negotiation.NegotiateOutputMediaType(req, scope.Serializer, &scope)
+	if err != nil {
+		status := responsewriters.ErrorToAPIStatus(err)
+		responsewriters.WriteRawJSON(int(status.Code), status, w)
+		return
+	}
+
+	// If conversion was allowed by the scope, perform it before writing the response
+	if target := mediaType.Convert; target != nil {
+		switch {
+		case target.Kind == "Table" && (target.GroupVersion() == metav1beta1.SchemeGroupVersion || target.GroupVersion() == metav1alpha1.SchemeGroupVersion):
+			// both versions share the query parameters of their table options
+			opts := &metav1beta1.TableOptions{}
+			if err := metav1beta1.ParameterCodec.DecodeParameters(req.URL.Query(), metav1beta1.SchemeGroupVersion, opts); err != nil {
+				scope.err(errors.NewBadRequest(err.Error()), w, req)
+				return
+			}
+			table, err := asV1Beta1Table(ctx, result, opts, scope)
+			if err != nil {
+				scope.err(err, w, req)
+				return
+			}
+			var out runtime.Object = table
+			if target.GroupVersion() == metav1alpha1.SchemeGroupVersion {
+				out = asV1Alpha1Table(table)
+			}
+
+			// renegotiate under the meta types, which know how to serialize a Table
+			_, info, err := negotiation.NegotiateOutputMediaType(req, metainternalversion.Codecs, &scope)
+			if err != nil {
+				scope.err(err, w, req)
+				return
+			}
+			encoder := metainternalversion.Codecs.EncoderForVersion(info.Serializer, target.GroupVersion())
+			responsewriters.SerializeObject(info.MediaType, encoder, w, req, statusCode, out)
+			return
+
+		default:
+			// this block should only be hit if scope AllowsConversion is incorrect
+			accepted, _ := negotiation.MediaTypesForSerializer(metainternalversion.Codecs)
+			err := negotiation.NewNotAcceptableError(accepted)
+			status := responsewriters.ErrorToAPIStatus(err)
+			responsewriters.WriteRawJSON(int(status.Code), status, w)
+			return
+		}
+	}
+
+	responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, statusCode, result)
+}
+
+// asV1Beta1Table converts result into a Table and replaces the object of every
+// row with the representation selected by the includeObject option.
+func asV1Beta1Table(ctx request.Context, result runtime.Object, opts *metav1beta1.TableOptions, scope RequestScope) (*metav1beta1.Table, error) {
+	table, err := scope.TableConvertor.ConvertToTable(ctx, result, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range table.Rows {
+		item := &table.Rows[i]
+		switch opts.IncludeObject {
+		case metav1beta1.IncludeObject:
+			item.Object.Object, err = scope.Convertor.ConvertToVersion(item.Object.Object, scope.Kind.GroupVersion())
+			if err != nil {
+				return nil, err
+			}
+		// TODO: rely on defaulting for the value here?
+		case metav1beta1.IncludeMetadata, "":
+			m, err := meta.Accessor(item.Object.Object)
+			if err != nil {
+				return nil, err
+			}
+			// TODO: turn this into an internal type and do conversion in order to get object kind automatically set?
+			partial := meta.AsPartialObjectMetadata(m)
+			partial.GetObjectKind().SetGroupVersionKind(metav1beta1.SchemeGroupVersion.WithKind("PartialObjectMetadata"))
+			item.Object.Object = partial
+		case metav1beta1.IncludeNone:
+			item.Object.Object = nil
+		default:
+			return nil, errors.NewBadRequest("unrecognized includeObject value: " + string(opts.IncludeObject))
+		}
+	}
+
+	return table, nil
+}
+
+// asV1Alpha1Table returns table in the meta.k8s.io/v1alpha1 version still
+// served to older clients. Both versions have the same fields.
+func asV1Alpha1Table(table *metav1beta1.Table) *metav1alpha1.Table {
+	out := &metav1alpha1.Table{ListMeta: table.ListMeta}
+	for _, column := range table.ColumnDefinitions {
+		out.ColumnDefinitions = append(out.ColumnDefinitions, metav1alpha1.TableColumnDefinition{
+			Name:        column.Name,
+			Type:        column.Type,
+			Format:      column.Format,
+			Description: column.Description,
+			Priority:    column.Priority,
+		})
+	}
+	for _, row := range table.Rows {
+		alphaRow := metav1alpha1.TableRow{Cells: row.Cells, Object: row.Object}
+		for _, condition := range row.Conditions {
+			alphaRow.Conditions = append(alphaRow.Conditions, metav1alpha1.TableRowCondition{
+				Type:    metav1alpha1.RowConditionType(condition.Type),
+				Status:  metav1alpha1.ConditionStatus(condition.Status),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			})
+		}
+		if partial, ok := row.Object.Object.(*metav1beta1.PartialObjectMetadata); ok {
+			alphaPartial := &metav1alpha1.PartialObjectMetadata{ObjectMeta: partial.ObjectMeta}
+			alphaPartial.GetObjectKind().SetGroupVersionKind(metav1alpha1.SchemeGroupVersion.WithKind("PartialObjectMetadata"))
+			alphaRow.Object.Object = alphaPartial
+		}
+		out.Rows = append(out.Rows, alphaRow)
+	}
+	return out
+}
+
+// AllowsConversion implements negotiation.EndpointRestrictions. A Table, in
+// meta.k8s.io/v1beta1 or the older v1alpha1, is the only alternate
+// representation of the resources of a scope.
+func (scope *RequestScope) AllowsConversion(gvk schema.GroupVersionKind) bool {
+	if (gvk.GroupVersion() == metav1beta1.SchemeGroupVersion || gvk.GroupVersion() == metav1alpha1.SchemeGroupVersion) && gvk.Kind == "Table" {
+		return scope.TableConvertor != nil
+	}
+	return false
+}
+
+// AllowsServerVersion implements negotiation.EndpointRestrictions.
+func (scope *RequestScope) AllowsServerVersion(version string) bool {
+	return version == scope.MetaGroupVersion.Version
+}
+
+// AllowsStreamSchema implements negotiation.EndpointRestrictions.
+func (scope *RequestScope) AllowsStreamSchema(s string) bool {
+	return s == "watch"
+}
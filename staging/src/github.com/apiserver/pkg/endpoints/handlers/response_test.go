@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+)
+
+func TestTransformResponseObjectAsTable(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+	codecs := serializer.NewCodecFactory(scheme)
+
+	obj := &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"}}
+
+	tests := []struct {
+		name           string
+		accept         string
+		query          string
+		tableConvertor rest.TableConvertor
+		code           int
+		apiVersion     string
+		rowKind        string
+	}{
+		{
+			name:           "v1beta1",
+			accept:         "application/json;as=Table;v=v1beta1;g=meta.k8s.io",
+			tableConvertor: rest.NewDefaultTableConvertor(testapigroup.Resource("carps")),
+			code:           http.StatusOK,
+			apiVersion:     "meta.k8s.io/v1beta1",
+			rowKind:        "PartialObjectMetadata",
+		},
+		{
+			name:           "v1alpha1",
+			accept:         "application/json;as=Table;v=v1alpha1;g=meta.k8s.io",
+			tableConvertor: rest.NewDefaultTableConvertor(testapigroup.Resource("carps")),
+			code:           http.StatusOK,
+			apiVersion:     "meta.k8s.io/v1alpha1",
+			rowKind:        "PartialObjectMetadata",
+		},
+		{
+			name:           "whole objects",
+			accept:         "application/json;as=Table;v=v1beta1;g=meta.k8s.io",
+			query:          "?includeObject=Object",
+			tableConvertor: rest.NewDefaultTableConvertor(testapigroup.Resource("carps")),
+			code:           http.StatusOK,
+			apiVersion:     "meta.k8s.io/v1beta1",
+			rowKind:        "Carp",
+		},
+		{
+			name:       "no convertor falls back to the resource",
+			accept:     "application/json;as=Table;v=v1beta1;g=meta.k8s.io, application/json",
+			code:       http.StatusOK,
+			apiVersion: "testapigroup.apimachinery.k8s.io/v1",
+		},
+		{
+			name:   "no convertor",
+			accept: "application/json;as=Table;v=v1beta1;g=meta.k8s.io",
+			code:   http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		scope := RequestScope{
+			ContextFunc:    func(*http.Request) request.Context { return request.NewContext() },
+			Serializer:     codecs,
+			Convertor:      scheme,
+			Kind:           testapigroupv1.SchemeGroupVersion.WithKind("Carp"),
+			TableConvertor: tt.tableConvertor,
+		}
+		req := httptest.NewRequest("GET", "/apis/testapigroup.apimachinery.k8s.io/v1/namespaces/ns/carps/foo"+tt.query, nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+
+		transformResponseObject(request.NewContext(), scope, req, w, http.StatusOK, obj)
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected code %d, got %d: %s", tt.name, tt.code, w.Code, w.Body.String())
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		out := struct {
+			APIVersion string `json:"apiVersion"`
+			Rows       []struct {
+				Cells  []interface{} `json:"cells"`
+				Object struct {
+					Kind string `json:"kind"`
+				} `json:"object"`
+			} `json:"rows"`
+		}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Errorf("%s: unexpected body %s: %v", tt.name, w.Body.String(), err)
+			continue
+		}
+		if out.APIVersion != tt.apiVersion {
+			t.Errorf("%s: expected apiVersion %q, got %s", tt.name, tt.apiVersion, w.Body.String())
+		}
+		if len(tt.rowKind) == 0 {
+			continue
+		}
+		if len(out.Rows) != 1 || len(out.Rows[0].Cells) != 2 || out.Rows[0].Cells[0] != "foo" {
+			t.Errorf("%s: unexpected rows %s", tt.name, w.Body.String())
+			continue
+		}
+		if out.Rows[0].Object.Kind != tt.rowKind {
+			t.Errorf("%s: expected rows to carry a %s, got %s", tt.name, tt.rowKind, w.Body.String())
+		}
+	}
+}
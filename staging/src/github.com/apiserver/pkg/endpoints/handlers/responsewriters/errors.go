@@ -1,6 +1,7 @@
 package responsewriters
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -37,7 +38,7 @@ func Forbidden(ctx request.Context, attributes authorizer.Attributes, w http.Res
 	}
 	gv := schema.GroupVersion{Group: attributes.GetAPIGroup(), Version: attributes.GetAPIVersion()}
 	gr := schema.GroupResource{Group: attributes.GetAPIGroup(), Resource: attributes.GetResource()}
-	ErrorNegotiated(ctx, apierrors.NewForbidden(gr, attributes.GetName(), fmt.Errorf(errMsg)), s, gv, w, req)
+	ErrorNegotiated(ctx, apierrors.NewForbidden(gr, attributes.GetName(), errors.New(errMsg)), s, gv, w, req)
 }
 
 
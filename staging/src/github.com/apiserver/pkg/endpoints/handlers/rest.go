@@ -0,0 +1,893 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/HuZhou/apiserver/pkg/audit"
+	"github.com/HuZhou/apiserver/pkg/authorization/authorizer"
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/negotiation"
+	"github.com/HuZhou/apiserver/pkg/endpoints/handlers/responsewriters"
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ContextFunc returns a Context given a request - a context must be returned
+type ContextFunc func(req *http.Request) request.Context
+
+// RequestScope encapsulates common fields across all RESTful handler methods.
+type RequestScope struct {
+	Namer ScopeNamer
+	ContextFunc
+
+	Serializer runtime.NegotiatedSerializer
+	runtime.ParameterCodec
+
+	Creater         runtime.ObjectCreater
+	Convertor       runtime.ObjectConvertor
+	Defaulter       runtime.ObjectDefaulter
+	Typer           runtime.ObjectTyper
+	UnsafeConvertor runtime.ObjectConvertor
+
+	Resource    schema.GroupVersionResource
+	Kind        schema.GroupVersionKind
+	Subresource string
+
+	MetaGroupVersion schema.GroupVersion
+
+	// TableConvertor renders the resources of the scope for clients that ask
+	// for a Table in the Accept header.
+	TableConvertor rest.TableConvertor
+
+	// Authorizer authorizes changes to the pending initializers of an object. If it
+	// is nil, the pending initializers cannot be changed.
+	Authorizer authorizer.Authorizer
+
+	// SupportsDryRun is true if the storage of the scope implements
+	// rest.DryRunnableStorage and skips the writes of dry runs.
+	SupportsDryRun bool
+}
+
+func (scope *RequestScope) err(err error, w http.ResponseWriter, req *http.Request) {
+	ctx := scope.ContextFunc(req)
+	responsewriters.ErrorNegotiated(ctx, err, scope.Serializer, scope.Kind.GroupVersion(), w, req)
+}
+
+// getterFunc performs a get request with the given context and object name. The request
+// may be used to deserialize an options object to pass to the getter.
+type getterFunc func(ctx request.Context, name string, req *http.Request) (runtime.Object, error)
+
+// getResourceHandler is an HTTP handler function for get requests. It delegates to the
+// passed-in getterFunc to perform the actual get.
+func getResourceHandler(scope RequestScope, getter getterFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		namespace, name, err := scope.Namer.Name(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+
+		result, err := getter(ctx, name, req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		if err := setSelfLink(result, req, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		transformResponseObject(ctx, scope, req, w, http.StatusOK, result)
+	}
+}
+
+// GetResource returns a function that handles retrieving a single resource from a rest.Storage object.
+func GetResource(r rest.Getter, scope RequestScope) http.HandlerFunc {
+	return getResourceHandler(scope,
+		func(ctx request.Context, name string, req *http.Request) (runtime.Object, error) {
+			options := metav1.GetOptions{}
+			if values := req.URL.Query(); len(values) > 0 {
+				if err := metainternalversion.ParameterCodec.DecodeParameters(values, scope.MetaGroupVersion, &options); err != nil {
+					return nil, errors.NewBadRequest(err.Error())
+				}
+			}
+			return r.Get(ctx, name, &options)
+		})
+}
+
+// GetResourceWithOptions returns a function that handles retrieving a single resource from a rest.Storage object.
+func GetResourceWithOptions(r rest.GetterWithOptions, scope RequestScope, isSubresource bool) http.HandlerFunc {
+	return getResourceHandler(scope,
+		func(ctx request.Context, name string, req *http.Request) (runtime.Object, error) {
+			opts, subpath, subpathKey := r.NewGetOptions()
+			if err := getRequestOptions(req, scope, opts, subpath, subpathKey, isSubresource); err != nil {
+				return nil, err
+			}
+			return r.Get(ctx, name, opts)
+		})
+}
+
+// getRequestOptions parses the query parameters of the request into the options object,
+// copying the trailing path of the request into subpathKey when subpath is set.
+func getRequestOptions(req *http.Request, scope RequestScope, into runtime.Object, subpath bool, subpathKey string, isSubresource bool) error {
+	if into == nil {
+		return nil
+	}
+
+	query := req.URL.Query()
+	if subpath {
+		newQuery := make(url.Values)
+		for k, v := range query {
+			newQuery[k] = v
+		}
+
+		ctx := scope.ContextFunc(req)
+		requestInfo, _ := request.RequestInfoFrom(ctx)
+		startingIndex := 2
+		if isSubresource {
+			startingIndex = 3
+		}
+
+		p := ""
+		if len(requestInfo.Parts) > startingIndex {
+			p = strings.Join(requestInfo.Parts[startingIndex:], "/")
+		}
+
+		// ensure non-empty subpaths correctly reflect a leading slash
+		if len(p) > 0 && !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+
+		// ensure subpaths correctly reflect the presence of a trailing slash on the original request
+		if strings.HasSuffix(requestInfo.Path, "/") && !strings.HasSuffix(p, "/") {
+			p += "/"
+		}
+
+		newQuery[subpathKey] = []string{p}
+		query = newQuery
+	}
+	if err := scope.ParameterCodec.DecodeParameters(query, scope.Kind.GroupVersion(), into); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	return nil
+}
+
+// ListResource returns a function that handles listing, and watching when requested, the
+// resources of a rest.Storage object.
+func ListResource(r rest.Lister, rw rest.Watcher, scope RequestScope, forceWatch bool, minRequestTimeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		namespace, err := scope.Namer.Namespace(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		// Watches for single objects are routed to this function.
+		// Treat a name parameter the same as a field selector entry.
+		hasName := true
+		_, name, err := scope.Namer.Name(req)
+		if err != nil {
+			hasName = false
+		}
+
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+
+		opts := metainternalversion.ListOptions{}
+		if err := metainternalversion.ParameterCodec.DecodeParameters(req.URL.Query(), scope.MetaGroupVersion, &opts); err != nil {
+			scope.err(errors.NewBadRequest(err.Error()), w, req)
+			return
+		}
+
+		// transform fields
+		// TODO: DecodeParametersInto should do this.
+		if opts.FieldSelector != nil {
+			fn := func(label, value string) (newLabel, newValue string, err error) {
+				return scope.Convertor.ConvertFieldLabel(scope.Kind.GroupVersion().String(), scope.Kind.Kind, label, value)
+			}
+			if opts.FieldSelector, err = opts.FieldSelector.Transform(fn); err != nil {
+				// TODO: allow bad request to set field causes based on query parameters
+				scope.err(errors.NewBadRequest(err.Error()), w, req)
+				return
+			}
+		}
+
+		if hasName {
+			// metadata.name is the canonical internal name.
+			// SelectionPredicate will notice that this is
+			// a request for a single object and optimize the
+			// storage query accordingly.
+			nameSelector := fields.OneTermEqualSelector("metadata.name", name)
+			if opts.FieldSelector != nil && !opts.FieldSelector.Empty() {
+				// It doesn't make sense to ask for both a name
+				// and a field selector, since just the name is
+				// sufficient to narrow down the request to a
+				// single object.
+				scope.err(errors.NewBadRequest("both a name and a field selector provided; please provide one or the other."), w, req)
+				return
+			}
+			opts.FieldSelector = nameSelector
+		}
+
+		if opts.Watch || forceWatch {
+			if rw == nil {
+				scope.err(errors.NewMethodNotSupported(scope.Resource.GroupResource(), "watch"), w, req)
+				return
+			}
+			// TODO: Currently we explicitly ignore ?timeout= and use only ?timeoutSeconds=.
+			timeout := time.Duration(0)
+			if opts.TimeoutSeconds != nil {
+				timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+			}
+			if timeout == 0 && minRequestTimeout > 0 {
+				timeout = time.Duration(float64(minRequestTimeout) * (rand.Float64() + 1.0))
+			}
+			glog.V(2).Infof("Starting watch for %s, rv=%s labels=%s fields=%s timeout=%s", req.URL.Path, opts.ResourceVersion, opts.LabelSelector, opts.FieldSelector, timeout)
+
+			watcher, err := rw.Watch(ctx, &opts)
+			if err != nil {
+				scope.err(err, w, req)
+				return
+			}
+			serveWatch(watcher, scope, req, w, timeout)
+			return
+		}
+
+		result, err := r.List(ctx, &opts)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		if _, err := setListSelfLink(result, req, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		transformResponseObject(ctx, scope, req, w, http.StatusOK, result)
+	}
+}
+
+func createHandler(r rest.NamedCreater, scope RequestScope, typer runtime.ObjectTyper, includeName bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// TODO: we either want to remove timeout or document it (if we document, move timeout out of this function and declare it in api_installer)
+		timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+		var (
+			namespace, name string
+			err             error
+		)
+		if includeName {
+			namespace, name, err = scope.Namer.Name(req)
+		} else {
+			namespace, err = scope.Namer.Namespace(req)
+		}
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+		ctx, err = withDryRun(ctx, req, scope.SupportsDryRun)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		gv := scope.Kind.GroupVersion()
+		s, err := negotiation.NegotiateInputSerializer(req, scope.Serializer)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		decoder := scope.Serializer.DecoderToVersion(s.Serializer, schema.GroupVersion{Group: gv.Group, Version: runtime.APIVersionInternal})
+
+		body, err := readBody(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		defaultGVK := scope.Kind
+		original := r.New()
+		obj, gvk, err := decoder.Decode(body, &defaultGVK, original)
+		if err != nil {
+			err = transformDecodeError(typer, err, original, gvk, body)
+			scope.err(err, w, req)
+			return
+		}
+		if gvk.GroupVersion() != gv {
+			err = errors.NewBadRequest(fmt.Sprintf("the API version in the data (%s) does not match the expected API version (%v)", gvk.GroupVersion().String(), gv.String()))
+			scope.err(err, w, req)
+			return
+		}
+
+		// the request context bounds how long Create may block waiting
+		// for the pending initializers of the new object
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		includeUninitialized, _ := strconv.ParseBool(req.URL.Query().Get("includeUninitialized"))
+		result, err := finishRequest(timeout, func() (runtime.Object, error) {
+			out, err := r.Create(ctx, name, obj, includeUninitialized)
+			if status, ok := out.(*metav1.Status); ok && err == nil && status.Code == 0 {
+				status.Code = http.StatusCreated
+			}
+			return out, err
+		})
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		if err := setSelfLink(result, req, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, http.StatusCreated, result)
+	}
+}
+
+// CreateNamedResource returns a function that will handle a resource creation with name.
+func CreateNamedResource(r rest.NamedCreater, scope RequestScope, typer runtime.ObjectTyper) http.HandlerFunc {
+	return createHandler(r, scope, typer, true)
+}
+
+// CreateResource returns a function that will handle a resource creation.
+func CreateResource(r rest.Creater, scope RequestScope, typer runtime.ObjectTyper) http.HandlerFunc {
+	return createHandler(&namedCreaterAdapter{r}, scope, typer, false)
+}
+
+type namedCreaterAdapter struct {
+	rest.Creater
+}
+
+func (c *namedCreaterAdapter) Create(ctx request.Context, name string, obj runtime.Object, includeUninitialized bool) (runtime.Object, error) {
+	return c.Creater.Create(ctx, obj, includeUninitialized)
+}
+
+// UpdateResource returns a function that will handle a resource update
+func UpdateResource(r rest.Updater, scope RequestScope, typer runtime.ObjectTyper) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// TODO: we either want to remove timeout or document it (if we document, move timeout out of this function and declare it in api_installer)
+		timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+		namespace, name, err := scope.Namer.Name(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+		ctx, err = withDryRun(ctx, req, scope.SupportsDryRun)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		body, err := readBody(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		s, err := negotiation.NegotiateInputSerializer(req, scope.Serializer)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		defaultGVK := scope.Kind
+		original := r.New()
+		decoder := scope.Serializer.DecoderToVersion(s.Serializer, schema.GroupVersion{Group: defaultGVK.Group, Version: runtime.APIVersionInternal})
+		obj, gvk, err := decoder.Decode(body, &defaultGVK, original)
+		if err != nil {
+			err = transformDecodeError(typer, err, original, gvk, body)
+			scope.err(err, w, req)
+			return
+		}
+		if gvk.GroupVersion() != defaultGVK.GroupVersion() {
+			err = errors.NewBadRequest(fmt.Sprintf("the API version in the data (%s) does not match the expected API version (%s)", gvk.GroupVersion(), defaultGVK.GroupVersion()))
+			scope.err(err, w, req)
+			return
+		}
+
+		if err := checkName(obj, name, namespace, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		wasCreated := false
+		result, err := finishRequest(timeout, func() (runtime.Object, error) {
+			obj, created, err := r.Update(ctx, name, rest.DefaultUpdatedObjectInfo(obj, authorizeInitializers(scope.Authorizer)))
+			wasCreated = created
+			return obj, err
+		})
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		if err := setSelfLink(result, req, scope.Namer); err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		status := http.StatusOK
+		if wasCreated {
+			status = http.StatusCreated
+		}
+		responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, status, result)
+	}
+}
+
+// DeleteResource returns a function that will handle a resource deletion
+func DeleteResource(r rest.GracefulDeleter, allowsOptions bool, scope RequestScope) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// TODO: we either want to remove timeout or document it (if we document, move timeout out of this function and declare it in api_installer)
+		timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+		namespace, name, err := scope.Namer.Name(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+		ctx, err = withDryRun(ctx, req, scope.SupportsDryRun)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		options := &metav1.DeleteOptions{}
+		if allowsOptions {
+			if err := decodeDeleteOptions(req, scope, options); err != nil {
+				scope.err(err, w, req)
+				return
+			}
+		}
+		ae := request.AuditEventFrom(ctx)
+		audit.LogRequestObject(ae, options, scope.Resource, scope.Subresource, scope.Serializer)
+
+		wasDeleted := true
+		result, err := finishRequest(timeout, func() (runtime.Object, error) {
+			obj, deleted, err := r.Delete(ctx, name, options)
+			wasDeleted = deleted
+			return obj, err
+		})
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+		status := http.StatusOK
+		// Return http.StatusAccepted if the resource was not deleted immediately and
+		// user requested cascading deletion by setting OrphanDependents=false.
+		// Note: We want to do this always if resource was not deleted immediately, but
+		// that will break existing clients.
+		if !wasDeleted && options.OrphanDependents != nil && *options.OrphanDependents == false {
+			status = http.StatusAccepted
+		}
+		audit.LogDeletion(ae, result, wasDeleted, status)
+		// if the rest.Deleter returns a nil object, fill out a status. Callers may return a valid
+		// object with the response.
+		if result == nil {
+			result = &metav1.Status{
+				Status: metav1.StatusSuccess,
+				Code:   int32(status),
+				Details: &metav1.StatusDetails{
+					Name: name,
+					Kind: scope.Kind.Kind,
+				},
+			}
+		} else {
+			// when a non-status response is returned, set the self link
+			if _, ok := result.(*metav1.Status); !ok {
+				if err := setSelfLink(result, req, scope.Namer); err != nil {
+					scope.err(err, w, req)
+					return
+				}
+			}
+		}
+		responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, status, result)
+	}
+}
+
+// DeleteCollection returns a function that will handle a collection deletion
+func DeleteCollection(r rest.CollectionDeleter, checkBody bool, scope RequestScope) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// TODO: we either want to remove timeout or document it (if we document, move timeout out of this function and declare it in api_installer)
+		timeout := parseTimeout(req.URL.Query().Get("timeout"))
+
+		namespace, err := scope.Namer.Namespace(req)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		ctx := scope.ContextFunc(req)
+		ctx = request.WithNamespace(ctx, namespace)
+		ctx, err = withDryRun(ctx, req, scope.SupportsDryRun)
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		listOptions := metainternalversion.ListOptions{}
+		if err := metainternalversion.ParameterCodec.DecodeParameters(req.URL.Query(), scope.MetaGroupVersion, &listOptions); err != nil {
+			scope.err(errors.NewBadRequest(err.Error()), w, req)
+			return
+		}
+
+		// transform fields
+		// TODO: DecodeParametersInto should do this.
+		if listOptions.FieldSelector != nil {
+			fn := func(label, value string) (newLabel, newValue string, err error) {
+				return scope.Convertor.ConvertFieldLabel(scope.Kind.GroupVersion().String(), scope.Kind.Kind, label, value)
+			}
+			if listOptions.FieldSelector, err = listOptions.FieldSelector.Transform(fn); err != nil {
+				// TODO: allow bad request to set field causes based on query parameters
+				scope.err(errors.NewBadRequest(err.Error()), w, req)
+				return
+			}
+		}
+
+		options := &metav1.DeleteOptions{}
+		if checkBody {
+			if err := decodeDeleteOptions(req, scope, options); err != nil {
+				scope.err(err, w, req)
+				return
+			}
+		}
+		ae := request.AuditEventFrom(ctx)
+		audit.LogRequestObject(ae, options, scope.Resource, scope.Subresource, scope.Serializer)
+
+		result, err := finishRequest(timeout, func() (runtime.Object, error) {
+			return r.DeleteCollection(ctx, options, &listOptions)
+		})
+		if err != nil {
+			scope.err(err, w, req)
+			return
+		}
+
+		// if the rest.Deleter returns a nil object, fill out a status. Callers may return a valid
+		// object with the response.
+		if result == nil {
+			result = &metav1.Status{
+				Status: metav1.StatusSuccess,
+				Code:   http.StatusOK,
+				Details: &metav1.StatusDetails{
+					Kind: scope.Kind.Kind,
+				},
+			}
+		} else {
+			// when a non-status response is returned, set the self link
+			if _, ok := result.(*metav1.Status); !ok {
+				if _, err := setListSelfLink(result, req, scope.Namer); err != nil {
+					scope.err(err, w, req)
+					return
+				}
+			}
+		}
+		responsewriters.WriteObjectNegotiated(ctx, scope.Serializer, scope.Kind.GroupVersion(), w, req, http.StatusOK, result)
+	}
+}
+
+// decodeDeleteOptions reads the DeleteOptions of a delete request from its body, or from
+// its query parameters when the body is empty.
+func decodeDeleteOptions(req *http.Request, scope RequestScope, options *metav1.DeleteOptions) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		s, err := negotiation.NegotiateInputSerializer(req, metainternalversion.Codecs)
+		if err != nil {
+			return err
+		}
+		// For backwards compatibility, we need to allow existing clients to submit per group DeleteOptions
+		// It is also allowed to pass a body with meta.k8s.io/v1.DeleteOptions
+		defaultGVK := scope.MetaGroupVersion.WithKind("DeleteOptions")
+		obj, _, err := metainternalversion.Codecs.DecoderToVersion(s.Serializer, defaultGVK.GroupVersion()).Decode(body, &defaultGVK, options)
+		if err != nil {
+			return errors.NewBadRequest(err.Error())
+		}
+		if obj != options {
+			return fmt.Errorf("decoded object cannot be converted to DeleteOptions")
+		}
+		return nil
+	}
+	if values := req.URL.Query(); len(values) > 0 {
+		if err := metainternalversion.ParameterCodec.DecodeParameters(values, scope.MetaGroupVersion, options); err != nil {
+			return errors.NewBadRequest(err.Error())
+		}
+	}
+	return nil
+}
+
+// resultFunc is a function that returns a rest result and can be run in a goroutine
+type resultFunc func() (runtime.Object, error)
+
+// finishRequest makes a given resultFunc asynchronous and handles errors returned by the response.
+// An api.Status object with status != success is considered an "error", which interrupts the normal response flow.
+func finishRequest(timeout time.Duration, fn resultFunc) (result runtime.Object, err error) {
+	// these channels need to be buffered to prevent the goroutine below from hanging indefinitely
+	// when the select statement reads something other than the one the goroutine sends on.
+	ch := make(chan runtime.Object, 1)
+	errCh := make(chan error, 1)
+	panicCh := make(chan interface{}, 1)
+	go func() {
+		// panics don't cross goroutine boundaries, so we have to handle ourselves
+		defer utilruntime.HandleCrash(func(panicReason interface{}) {
+			// Propagate to parent goroutine
+			panicCh <- panicReason
+		})
+
+		if result, err := fn(); err != nil {
+			errCh <- err
+		} else {
+			ch <- result
+		}
+	}()
+
+	select {
+	case result = <-ch:
+		if status, ok := result.(*metav1.Status); ok {
+			if status.Status != metav1.StatusSuccess {
+				return nil, errors.FromObject(status)
+			}
+		}
+		return result, nil
+	case err = <-errCh:
+		return nil, err
+	case p := <-panicCh:
+		panic(p)
+	case <-time.After(timeout):
+		return nil, errors.NewTimeoutError("request did not complete within allowed duration", 0)
+	}
+}
+
+// transformDecodeError adds additional information when a decode fails.
+func transformDecodeError(typer runtime.ObjectTyper, baseErr error, into runtime.Object, gvk *schema.GroupVersionKind, body []byte) error {
+	objGVKs, _, err := typer.ObjectKinds(into)
+	if err != nil {
+		return err
+	}
+	objGVK := objGVKs[0]
+	if gvk != nil && len(gvk.Kind) > 0 {
+		return errors.NewBadRequest(fmt.Sprintf("%s in version %q cannot be handled as a %s: %v", gvk.Kind, gvk.Version, objGVK.Kind, baseErr))
+	}
+	summary := summarizeData(body, 30)
+	return errors.NewBadRequest(fmt.Sprintf("the object provided is unrecognized (must be of type %s): %v (%s)", objGVK.Kind, baseErr, summary))
+}
+
+// setSelfLink sets the self link of an object (or the child items in a list) to the base URL of the request
+// plus the path and query generated by the provided linkFunc
+func setSelfLink(obj runtime.Object, req *http.Request, namer ScopeNamer) error {
+	// TODO: SelfLink generation should return a full URL?
+	uri, err := namer.GenerateLink(req, obj)
+	if err != nil {
+		return nil
+	}
+
+	return namer.SetSelfLink(obj, uri)
+}
+
+// checkName checks the provided name against the request
+func checkName(obj runtime.Object, name, namespace string, namer ScopeNamer) error {
+	if objNamespace, objName, err := namer.ObjectName(obj); err == nil {
+		if objName != name {
+			return errors.NewBadRequest(fmt.Sprintf(
+				"the name of the object (%s) does not match the name on the URL (%s)", objName, name))
+		}
+		if len(namespace) > 0 {
+			if len(objNamespace) > 0 && objNamespace != namespace {
+				return errors.NewBadRequest(fmt.Sprintf(
+					"the namespace of the object (%s) does not match the namespace on the request (%s)", objNamespace, namespace))
+			}
+		}
+	}
+	return nil
+}
+
+// setListSelfLink sets the self link of a list to the base URL, then sets the self links
+// on all child objects returned. Returns the number of items in the list.
+func setListSelfLink(obj runtime.Object, req *http.Request, namer ScopeNamer) (int, error) {
+	if !meta.IsListType(obj) {
+		return 0, nil
+	}
+
+	uri, err := namer.GenerateListLink(req)
+	if err != nil {
+		return 0, err
+	}
+	if err := namer.SetSelfLink(obj, uri); err != nil {
+		glog.V(4).Infof("Unable to set self link on object: %v", err)
+	}
+
+	count := 0
+	err = meta.EachListItem(obj, func(obj runtime.Object) error {
+		count++
+		return setSelfLink(obj, req, namer)
+	})
+	return count, err
+}
+
+func summarizeData(data []byte, maxLength int) string {
+	switch {
+	case len(data) == 0:
+		return "<empty>"
+	case data[0] == '{':
+		if len(data) > maxLength {
+			return string(data[:maxLength]) + " ..."
+		}
+		return string(data)
+	default:
+		if len(data) > maxLength {
+			return hex.EncodeToString(data[:maxLength]) + " ..."
+		}
+		return hex.EncodeToString(data)
+	}
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	defer req.Body.Close()
+	return ioutil.ReadAll(req.Body)
+}
+
+// dryRunAll is the only supported value of the dryRun query parameter. It
+// requests that all stages of the request run except for persisting it.
+const dryRunAll = "All"
+
+// dryRunAnnotationKey is the audit event annotation marking dry run requests.
+const dryRunAnnotationKey = "dryrun.apiserver.k8s.io/dry-run"
+
+// withDryRun returns ctx marked as a dry run if the dryRun query parameter of
+// req asks for one, and records that in the audit event of the request. Dry
+// runs are rejected unless the storage serving the request supports them.
+func withDryRun(ctx request.Context, req *http.Request, supportsDryRun bool) (request.Context, error) {
+	values := req.URL.Query()["dryRun"]
+	if len(values) == 0 {
+		return ctx, nil
+	}
+	if !supportsDryRun {
+		return nil, errors.NewBadRequest("dryRun is not supported by this resource")
+	}
+	for _, v := range values {
+		if v != dryRunAll {
+			return nil, errors.NewBadRequest(fmt.Sprintf("invalid dryRun value %q, the only supported value is %q", v, dryRunAll))
+		}
+	}
+	audit.LogAnnotation(request.AuditEventFrom(ctx), dryRunAnnotationKey, "true")
+	return request.WithDryRun(ctx, true), nil
+}
+
+// initializeVerb is the verb a user has to be authorized for in order to change
+// the pending initializers of an object.
+const initializeVerb = "initialize"
+
+// authorizeInitializers returns a transform that rejects changes to the
+// pending initializers of an object unless the user of the request may
+// initialize the resource. Removing an initializer completes its part of the
+// initialization, so it is reserved for that initializer rather than for
+// anyone who may update the object: the name of every removed initializer is
+// authorized as the subresource, so that a policy can grant the initialize
+// verb on e.g. "carps/<initializer>". Other changes are authorized against the
+// resource itself. Without an authorizer, the pending initializers of an
+// object cannot be changed.
+func authorizeInitializers(a authorizer.Authorizer) rest.TransformFunc {
+	return func(ctx request.Context, newObj, oldObj runtime.Object) (runtime.Object, error) {
+		if newObj == nil || oldObj == nil {
+			return newObj, nil
+		}
+		newMeta, err := meta.Accessor(newObj)
+		if err != nil {
+			return nil, err
+		}
+		oldMeta, err := meta.Accessor(oldObj)
+		if err != nil {
+			return nil, err
+		}
+		newPending, oldPending := pendingInitializers(newMeta), pendingInitializers(oldMeta)
+		if apiequality.Semantic.DeepEqual(newPending, oldPending) {
+			return newObj, nil
+		}
+
+		requestInfo, ok := request.RequestInfoFrom(ctx)
+		if !ok {
+			return nil, errors.NewInternalError(fmt.Errorf("no RequestInfo found in the context"))
+		}
+		gr := schema.GroupResource{Group: requestInfo.APIGroup, Resource: requestInfo.Resource}
+		if a == nil {
+			return nil, errors.NewForbidden(gr, requestInfo.Name, fmt.Errorf("pending initializers cannot be changed without an authorizer"))
+		}
+
+		removed := removedInitializers(newPending, oldPending)
+		if len(removed) == 0 {
+			removed = []string{""}
+		}
+		for _, initializer := range removed {
+			attributes := authorizer.AttributesRecord{
+				Verb:            initializeVerb,
+				Namespace:       requestInfo.Namespace,
+				APIGroup:        requestInfo.APIGroup,
+				APIVersion:      requestInfo.APIVersion,
+				Resource:        requestInfo.Resource,
+				Subresource:     initializer,
+				Name:            requestInfo.Name,
+				ResourceRequest: true,
+				Path:            requestInfo.Path,
+			}
+			if user, ok := request.UserFrom(ctx); ok {
+				attributes.User = user
+			}
+			authorized, reason, err := a.Authorize(attributes)
+			if authorized {
+				continue
+			}
+			if err != nil {
+				return nil, errors.NewInternalError(err)
+			}
+			if len(initializer) == 0 {
+				return nil, errors.NewForbidden(gr, requestInfo.Name, fmt.Errorf("not authorized to %s the resource: %s", initializeVerb, reason))
+			}
+			return nil, errors.NewForbidden(gr, requestInfo.Name, fmt.Errorf("not authorized to %s the resource as %q: %s", initializeVerb, initializer, reason))
+		}
+		return newObj, nil
+	}
+}
+
+// removedInitializers returns the names of the initializers that are pending
+// in oldPending but not in newPending.
+func removedInitializers(newPending, oldPending []metav1.Initializer) []string {
+	remaining := sets.NewString()
+	for _, initializer := range newPending {
+		remaining.Insert(initializer.Name)
+	}
+	removed := []string{}
+	for _, initializer := range oldPending {
+		if !remaining.Has(initializer.Name) {
+			removed = append(removed, initializer.Name)
+		}
+	}
+	return removed
+}
+
+// pendingInitializers returns the pending initializers of an object.
+func pendingInitializers(m metav1.Object) []metav1.Initializer {
+	if initializers := m.GetInitializers(); initializers != nil {
+		return initializers.Pending
+	}
+	return nil
+}
+
+func parseTimeout(str string) time.Duration {
+	if str != "" {
+		timeout, err := time.ParseDuration(str)
+		if err == nil {
+			return timeout
+		}
+		glog.Errorf("Failed to parse %q: %v", str, err)
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+
+	auditinternal "github.com/HuZhou/apiserver/pkg/apis/audit"
+	"github.com/HuZhou/apiserver/pkg/authentication/user"
+	"github.com/HuZhou/apiserver/pkg/authorization/authorizer"
+	"github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+// initializerAuthorizer allows users to remove the initializer named after
+// them, unless it denies everything.
+type initializerAuthorizer struct {
+	deny       bool
+	attributes []authorizer.Attributes
+}
+
+func (a *initializerAuthorizer) Authorize(attributes authorizer.Attributes) (bool, string, error) {
+	a.attributes = append(a.attributes, attributes)
+	if !a.deny && attributes.GetVerb() == initializeVerb && len(attributes.GetSubresource()) > 0 && attributes.GetSubresource() == attributes.GetUser().GetName() {
+		return true, "", nil
+	}
+	return false, "only an initializer may remove itself", nil
+}
+
+func initializingCarp(pending ...string) *testapigroup.Carp {
+	obj := &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns"}}
+	if len(pending) > 0 {
+		obj.Initializers = &metav1.Initializers{}
+		for _, name := range pending {
+			obj.Initializers.Pending = append(obj.Initializers.Pending, metav1.Initializer{Name: name})
+		}
+	}
+	return obj
+}
+
+func initializerContext(userName string) request.Context {
+	ctx := request.WithNamespace(request.NewContext(), "ns")
+	ctx = request.WithUser(ctx, &user.DefaultInfo{Name: userName})
+	return request.WithRequestInfo(ctx, &request.RequestInfo{
+		IsResourceRequest: true,
+		Verb:              "update",
+		APIGroup:          testapigroup.GroupName,
+		APIVersion:        "v1",
+		Namespace:         "ns",
+		Resource:          "carps",
+		Name:              "foo",
+	})
+}
+
+func TestAuthorizeInitializers(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		old, new *testapigroup.Carp
+		deny     bool
+		// expectSubresources are the subresources authorized, in order
+		expectSubresources []string
+		expectForbidden    bool
+	}{
+		{name: "unchanged initializers", user: "someone", old: initializingCarp("a", "b"), new: initializingCarp("a", "b")},
+		{name: "no initializers", user: "someone", old: initializingCarp(), new: initializingCarp()},
+		{name: "initializer removes the first pending entry", user: "a", old: initializingCarp("a", "b"), new: initializingCarp("b"), expectSubresources: []string{"a"}},
+		{name: "last initializer clears the initializers", user: "b", old: initializingCarp("b"), new: initializingCarp(), expectSubresources: []string{"b"}},
+		{name: "initializer removes an entry it does not own", user: "b", old: initializingCarp("a", "b"), new: initializingCarp("b"), expectSubresources: []string{"a"}, expectForbidden: true},
+		{name: "initializer removes another entry", user: "a", old: initializingCarp("a", "b"), new: initializingCarp("a"), expectSubresources: []string{"b"}, expectForbidden: true},
+		{name: "initializer removes itself and another entry", user: "a", old: initializingCarp("a", "b", "c"), new: initializingCarp("c"), expectSubresources: []string{"a", "b"}, expectForbidden: true},
+		{name: "reordered initializers", user: "a", old: initializingCarp("a", "b"), new: initializingCarp("b", "a"), expectSubresources: []string{""}, expectForbidden: true},
+		{name: "authorizer denies", user: "a", old: initializingCarp("a", "b"), new: initializingCarp("b"), deny: true, expectSubresources: []string{"a"}, expectForbidden: true},
+	}
+
+	for _, tt := range tests {
+		a := &initializerAuthorizer{deny: tt.deny}
+		obj, err := authorizeInitializers(a)(initializerContext(tt.user), tt.new, tt.old)
+		if tt.expectForbidden {
+			if !errors.IsForbidden(err) {
+				t.Errorf("%s: expected a forbidden error, got %v", tt.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		} else if obj != tt.new {
+			t.Errorf("%s: expected the new object to be returned", tt.name)
+		}
+
+		subresources := []string{}
+		for _, attributes := range a.attributes {
+			if attributes.GetVerb() != initializeVerb || attributes.GetResource() != "carps" || attributes.GetName() != "foo" || attributes.GetNamespace() != "ns" || attributes.GetUser().GetName() != tt.user {
+				t.Errorf("%s: unexpected attributes %#v", tt.name, attributes)
+			}
+			subresources = append(subresources, attributes.GetSubresource())
+		}
+		if len(subresources) != len(tt.expectSubresources) {
+			t.Errorf("%s: expected subresources %v to be authorized, got %v", tt.name, tt.expectSubresources, subresources)
+			continue
+		}
+		for i := range subresources {
+			if subresources[i] != tt.expectSubresources[i] {
+				t.Errorf("%s: expected subresources %v to be authorized, got %v", tt.name, tt.expectSubresources, subresources)
+				break
+			}
+		}
+	}
+}
+
+func TestAuthorizeInitializersWithoutAuthorizer(t *testing.T) {
+	old := initializingCarp("a")
+	if _, err := authorizeInitializers(nil)(initializerContext("a"), initializingCarp("a"), old); err != nil {
+		t.Errorf("unexpected error for unchanged initializers: %v", err)
+	}
+	if _, err := authorizeInitializers(nil)(initializerContext("a"), initializingCarp(), old); !errors.IsForbidden(err) {
+		t.Errorf("expected a forbidden error without an authorizer, got %v", err)
+	}
+}
+
+func TestWithDryRun(t *testing.T) {
+	tests := []struct {
+		query          string
+		supportsDryRun bool
+		expectDryRun   bool
+		expectError    bool
+	}{
+		{query: "", supportsDryRun: true},
+		{query: "", supportsDryRun: false},
+		{query: "dryRun=All", supportsDryRun: true, expectDryRun: true},
+		{query: "dryRun=All&dryRun=All", supportsDryRun: true, expectDryRun: true},
+		{query: "dryRun=All", supportsDryRun: false, expectError: true},
+		{query: "dryRun=true", supportsDryRun: true, expectError: true},
+		{query: "dryRun=All&dryRun=foo", supportsDryRun: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest("POST", "/api/v1/namespaces/ns/secrets?"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev := &auditinternal.Event{Level: auditinternal.LevelMetadata}
+		ctx := request.WithAuditEvent(request.NewContext(), ev)
+
+		ctx, err = withDryRun(ctx, req, tt.supportsDryRun)
+		if tt.expectError {
+			if !errors.IsBadRequest(err) {
+				t.Errorf("%q: expected a bad request, got %v", tt.query, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.query, err)
+			continue
+		}
+		if dryRun := request.DryRunFrom(ctx); dryRun != tt.expectDryRun {
+			t.Errorf("%q: expected dry run %v, got %v", tt.query, tt.expectDryRun, dryRun)
+		}
+		if _, annotated := ev.Annotations[dryRunAnnotationKey]; annotated != tt.expectDryRun {
+			t.Errorf("%q: unexpected audit annotations %v", tt.query, ev.Annotations)
+		}
+		if len(ev.ObjectMeta.Annotations) > 0 {
+			t.Errorf("%q: unexpected annotations of the event object %v", tt.query, ev.ObjectMeta.Annotations)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/types"
 	"github.com/HuZhou/apiserver/pkg/authentication/user"
 	"github.com/HuZhou/apiserver/pkg/apis/audit"
 )
@@ -28,6 +29,9 @@ const (
 	// auditKey is the context key for the audit event.
 	auditKey
 
+	// dryRunKey is the context key for whether the request is a dry run.
+	dryRunKey
+
 	namespaceDefault = "default" // TODO(sttts): solve import cycle when using metav1.NamespaceDefault
 )
 
@@ -47,6 +51,11 @@ type Context interface {
 	Err() error
 }
 
+// NewDefaultContext instantiates a base context object for request flows in the default namespace
+func NewDefaultContext() Context {
+	return WithNamespace(NewContext(), namespaceDefault)
+}
+
 // WithValue returns a copy of parent in which the value associated with key is val.
 func WithValue(parent Context, key interface{}, val interface{}) Context {
 	internalCtx, ok := parent.(context.Context)
@@ -56,12 +65,49 @@ func WithValue(parent Context, key interface{}, val interface{}) Context {
 	return context.WithValue(internalCtx, key, val)
 }
 
+// WithNamespace returns a copy of parent in which the namespace value is set
+func WithNamespace(parent Context, namespace string) Context {
+	return WithValue(parent, namespaceKey, namespace)
+}
+
+// NamespaceFrom returns the value of the namespace key on the ctx
+func NamespaceFrom(ctx Context) (string, bool) {
+	namespace, ok := ctx.Value(namespaceKey).(string)
+	return namespace, ok
+}
+
+// NamespaceValue returns the value of the namespace key on the ctx, or the empty string if none
+func NamespaceValue(ctx Context) string {
+	namespace, _ := NamespaceFrom(ctx)
+	return namespace
+}
+
+// WithNamespaceDefaultIfNone returns a context whose namespace is the default if and only if the parent context has no namespace value
+func WithNamespaceDefaultIfNone(parent Context) Context {
+	namespace, ok := NamespaceFrom(parent)
+	if !ok || len(namespace) == 0 {
+		return WithNamespace(parent, namespaceDefault)
+	}
+	return parent
+}
+
 // UserFrom returns the value of the user key on the ctx
 func UserFrom(ctx Context) (user.Info, bool) {
 	user, ok := ctx.Value(userKey).(user.Info)
 	return user, ok
 }
 
+// WithUID returns a copy of parent in which the uid value is set
+func WithUID(parent Context, uid types.UID) Context {
+	return WithValue(parent, uidKey, uid)
+}
+
+// UIDFrom returns the value of the uid key on the ctx
+func UIDFrom(ctx Context) (types.UID, bool) {
+	uid, ok := ctx.Value(uidKey).(types.UID)
+	return uid, ok
+}
+
 // WithAuditEvent returns set audit event struct.
 func WithAuditEvent(parent Context, ev *audit.Event) Context {
 	return WithValue(parent, auditKey, ev)
@@ -77,4 +123,15 @@ func WithUser(parent Context, user user.Info) Context {
 func AuditEventFrom(ctx Context) *audit.Event {
 	ev, _ := ctx.Value(auditKey).(*audit.Event)
 	return ev
-}
\ No newline at end of file
+}
+
+// WithDryRun returns a copy of parent in which the dry run flag is set
+func WithDryRun(parent Context, dryRun bool) Context {
+	return WithValue(parent, dryRunKey, dryRun)
+}
+
+// DryRunFrom returns true if the request on the ctx must not be persisted
+func DryRunFrom(ctx Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey).(bool)
+	return dryRun
+}
@@ -4,6 +4,10 @@ import (
 	utilfeature "github.com/HuZhou/apiserver/pkg/util/feature"
 )
 
+func init() {
+	utilfeature.DefaultFeatureGate.Add(defaultKubernetesFeatureGates)
+}
+
 const (
 	// Every feature gate should add method here following this template:
 	//
@@ -38,4 +42,14 @@ const (
 	// Allow asynchronous coordination of object creation.
 	// Auto-enabled by the Initializers admission plugin.
 	Initializers utilfeature.Feature = "Initializers"
-)
\ No newline at end of file
+)
+
+// defaultKubernetesFeatureGates consists of all known Kubernetes-specific feature keys.
+// To add a new feature, define a key for it above and add it here. The features will be
+// available throughout Kubernetes binaries.
+var defaultKubernetesFeatureGates = map[utilfeature.Feature]utilfeature.FeatureSpec{
+	StreamingProxyRedirects: {Default: true, PreRelease: utilfeature.Beta},
+	AdvancedAuditing:        {Default: false, PreRelease: utilfeature.Alpha},
+	APIResponseCompression:  {Default: false, PreRelease: utilfeature.Alpha},
+	Initializers:            {Default: false, PreRelease: utilfeature.Alpha},
+}
@@ -0,0 +1,296 @@
+// Package migration rewrites stored objects so that they are persisted with the
+// current storage codec and value transformer.
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/storage"
+)
+
+// checkpointInterval is the number of objects visited between two checkpoints.
+const checkpointInterval = 100
+
+// listPageSize is the number of objects listed from storage at once.
+const listPageSize = 500
+
+// Resource is a resource whose stored objects are migrated.
+type Resource struct {
+	GroupResource schema.GroupResource
+	// Storage is the storage the objects of the resource are kept in.
+	Storage storage.Interface
+	// NewListFunc returns a new list of the type stored for the resource.
+	NewListFunc func() runtime.Object
+	// KeyRoot is the key all objects of the resource are stored under.
+	KeyRoot string
+	// KeyFunc returns the storage key of an object of the resource.
+	KeyFunc func(obj runtime.Object) (string, error)
+}
+
+// ResourceForStore returns the Resource for the objects kept by a completed
+// generic registry Store.
+func ResourceForStore(resource schema.GroupResource, e *genericregistry.Store) Resource {
+	return Resource{
+		GroupResource: resource,
+		Storage:       e.Storage,
+		NewListFunc:   e.NewListFunc,
+		KeyRoot:       e.KeyRootFunc(genericapirequest.NewContext()),
+		KeyFunc: func(obj runtime.Object) (string, error) {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return "", err
+			}
+			ctx := genericapirequest.WithNamespace(genericapirequest.NewContext(), accessor.GetNamespace())
+			return e.KeyFunc(ctx, accessor.GetName())
+		},
+	}
+}
+
+// Checkpoint records the last object whose migration completed.
+type Checkpoint struct {
+	// Resource is the resource being migrated, in the form resource.group.
+	Resource string `json:"resource"`
+	// Key is the storage key of the last migrated object of Resource.
+	Key string `json:"key"`
+}
+
+// Progress reports how far a migration went.
+type Progress struct {
+	// Visited is the number of objects read.
+	Visited int
+	// Migrated is the number of objects that were rewritten.
+	Migrated int
+	// Resources is the number of resources fully migrated.
+	Resources int
+}
+
+// Migrator walks the objects of a set of resources and rewrites every object
+// whose stored form differs from what the current codec and transformer
+// produce, e.g. after a storage version change or an encryption key rotation.
+// Only the given resources are migrated; keys of other resources sharing the
+// storage prefix are left alone.
+type Migrator struct {
+	// Resources are the resources to migrate. At least one is required.
+	Resources []Resource
+	// CheckpointFile is where progress is persisted so that an interrupted
+	// migration resumes where it stopped. Optional.
+	CheckpointFile string
+}
+
+// Run migrates all resources and removes the checkpoint once done. It returns
+// early with the progress made so far if stopCh is closed.
+func (m *Migrator) Run(stopCh <-chan struct{}) (Progress, error) {
+	progress := Progress{}
+	if len(m.Resources) == 0 {
+		return progress, fmt.Errorf("no resources to migrate")
+	}
+
+	checkpoint, err := m.loadCheckpoint()
+	if err != nil {
+		return progress, err
+	}
+	if checkpoint != nil {
+		glog.Infof("Resuming storage migration of %s after %q", checkpoint.Resource, checkpoint.Key)
+	}
+
+	resources := make([]Resource, len(m.Resources))
+	copy(resources, m.Resources)
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].GroupResource.String() < resources[j].GroupResource.String()
+	})
+
+	for _, resource := range resources {
+		name := resource.GroupResource.String()
+		after := ""
+		if checkpoint != nil {
+			if name < checkpoint.Resource {
+				continue
+			}
+			if name == checkpoint.Resource {
+				after = checkpoint.Key
+			}
+			checkpoint = nil
+		}
+		if err := m.migrateResource(resource, after, &progress, stopCh); err != nil {
+			return progress, fmt.Errorf("unable to migrate %s: %v", name, err)
+		}
+		select {
+		case <-stopCh:
+			return progress, nil
+		default:
+		}
+		progress.Resources++
+		glog.Infof("Storage migration of %s done: %d of %d resources, %d objects visited, %d migrated",
+			name, progress.Resources, len(resources), progress.Visited, progress.Migrated)
+	}
+
+	if len(m.CheckpointFile) != 0 {
+		if err := os.Remove(m.CheckpointFile); err != nil && !os.IsNotExist(err) {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+// migrateResource rewrites the objects of resource whose keys sort after the
+// given key. The objects are listed in pages of listPageSize, in key order.
+func (m *Migrator) migrateResource(resource Resource, after string, progress *Progress, stopCh <-chan struct{}) error {
+	ctx := context.TODO()
+	name := resource.GroupResource.String()
+
+	last := after
+	continueToken := ""
+	for {
+		pred := storage.Everything
+		pred.Limit = listPageSize
+		pred.Continue = continueToken
+		list := resource.NewListFunc()
+		err := resource.Storage.List(ctx, resource.KeyRoot, "", pred, list)
+		if isExpired(err) && len(continueToken) > 0 {
+			// the snapshot the pages were listed from was compacted, start
+			// a new list and skip the objects already migrated
+			glog.V(2).Infof("Storage migration of %s restarts its list after %q: %v", name, last, err)
+			continueToken = ""
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			select {
+			case <-stopCh:
+				if len(last) > 0 {
+					return m.saveCheckpoint(&Checkpoint{Resource: name, Key: last})
+				}
+				return nil
+			default:
+			}
+
+			key, err := resource.KeyFunc(item)
+			if err != nil {
+				return err
+			}
+			if key <= last {
+				continue
+			}
+			migrated, err := migrateObject(ctx, resource.Storage, key, item)
+			if err != nil {
+				return fmt.Errorf("unable to migrate %q: %v", key, err)
+			}
+			last = key
+			progress.Visited++
+			if migrated {
+				progress.Migrated++
+			}
+			if progress.Visited%checkpointInterval == 0 {
+				if err := m.saveCheckpoint(&Checkpoint{Resource: name, Key: key}); err != nil {
+					return err
+				}
+				glog.V(2).Infof("Storage migration progress: %d objects visited, %d migrated", progress.Visited, progress.Migrated)
+			}
+		}
+
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return err
+		}
+		continueToken = listMeta.GetContinue()
+		if len(continueToken) == 0 {
+			return nil
+		}
+	}
+}
+
+// isExpired returns true if err is a 410 Gone returned for a list whose
+// resource version or continue token was compacted.
+func isExpired(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	return ok && status.Status().Reason == metav1.StatusReasonExpired
+}
+
+// migrateObject reads the object stored at key and writes it back unchanged.
+// The storage skips the write when the stored bytes are already current and
+// not stale, so only outdated objects are rewritten.
+func migrateObject(ctx context.Context, s storage.Interface, key string, obj runtime.Object) (bool, error) {
+	out := obj.DeepCopyObject()
+	var before uint64
+	err := s.GuaranteedUpdate(ctx, key, out, false, nil, func(input runtime.Object, res storage.ResponseMeta) (runtime.Object, *uint64, error) {
+		before = res.ResourceVersion
+		return input, nil, nil
+	})
+	if storage.IsNotFound(err) {
+		// deleted since it was listed, nothing to migrate
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	after, err := s.Versioner().ObjectResourceVersion(out)
+	if err != nil {
+		return false, err
+	}
+	return before != 0 && after != before, nil
+}
+
+func (m *Migrator) loadCheckpoint() (*Checkpoint, error) {
+	if len(m.CheckpointFile) == 0 {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(m.CheckpointFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid storage migration checkpoint %q: %v", m.CheckpointFile, err)
+	}
+	return checkpoint, nil
+}
+
+func (m *Migrator) saveCheckpoint(checkpoint *Checkpoint) error {
+	if len(m.CheckpointFile) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that a crash never leaves a truncated checkpoint
+	tmp, err := ioutil.TempFile(filepath.Dir(m.CheckpointFile), filepath.Base(m.CheckpointFile))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), m.CheckpointFile)
+}
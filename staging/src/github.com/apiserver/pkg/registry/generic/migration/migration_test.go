@@ -0,0 +1,208 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/memory"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+func init() {
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+}
+
+// recordingStorage records the updates and lists served by the wrapped storage
+// and closes stopCh once stopAfter updates were made.
+type recordingStorage struct {
+	storage.Interface
+
+	updates   map[string]int
+	limits    []int64
+	stopAfter int
+	stopCh    chan struct{}
+	// expireContinue fails the next list given a continue token with a 410 Gone.
+	expireContinue bool
+}
+
+func (s *recordingStorage) GuaranteedUpdate(ctx context.Context, key string, ptrToType runtime.Object, ignoreNotFound bool, preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc, suggestion ...runtime.Object) error {
+	if err := s.Interface.GuaranteedUpdate(ctx, key, ptrToType, ignoreNotFound, preconditions, tryUpdate, suggestion...); err != nil {
+		return err
+	}
+	s.updates[key]++
+	total := 0
+	for _, count := range s.updates {
+		total += count
+	}
+	if total == s.stopAfter {
+		close(s.stopCh)
+	}
+	return nil
+}
+
+func (s *recordingStorage) List(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate, listObj runtime.Object) error {
+	s.limits = append(s.limits, p.Limit)
+	if s.expireContinue && len(p.Continue) > 0 {
+		s.expireContinue = false
+		return apierrors.NewResourceExpired("The provided continue parameter is too old to display a consistent list result.")
+	}
+	return s.Interface.List(ctx, key, resourceVersion, p, listObj)
+}
+
+func newTestResource(resource string, s storage.Interface) Resource {
+	return Resource{
+		GroupResource: schema.GroupResource{Group: testapigroup.GroupName, Resource: resource},
+		Storage:       s,
+		NewListFunc:   func() runtime.Object { return &testapigroup.CarpList{} },
+		KeyRoot:       "/" + resource,
+		KeyFunc: func(obj runtime.Object) (string, error) {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return "", err
+			}
+			return "/" + resource + "/" + accessor.GetNamespace() + "/" + accessor.GetName(), nil
+		},
+	}
+}
+
+func createCarps(t *testing.T, resource Resource, count int) []string {
+	keys := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		obj := &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("carp-%04d", i), Namespace: "ns"}}
+		key, err := resource.KeyFunc(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := resource.Storage.Create(context.TODO(), key, obj, nil, 0); err != nil {
+			t.Fatalf("failed to create %s: %v", key, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestRunWithoutResources(t *testing.T) {
+	if _, err := (&Migrator{}).Run(make(chan struct{})); err == nil {
+		t.Errorf("expected an error migrating no resources")
+	}
+}
+
+func TestRunResumesFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage-migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "checkpoint")
+
+	s := &recordingStorage{
+		Interface: memory.New(memory.NewBackend(), codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion), "/registry", value.IdentityTransformer),
+		updates:   map[string]int{},
+		stopAfter: 250,
+		stopCh:    make(chan struct{}),
+	}
+	carps := newTestResource("carps", s)
+	fish := newTestResource("fish", s)
+	carpKeys := createCarps(t, carps, listPageSize+50)
+	fishKeys := createCarps(t, fish, 10)
+
+	m := &Migrator{Resources: []Resource{fish, carps}, CheckpointFile: checkpointFile}
+
+	// the first run is interrupted in the middle of the first page of carps
+	progress, err := m.Run(s.stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if progress.Visited != 250 || progress.Resources != 0 {
+		t.Errorf("unexpected progress of the interrupted run: %#v", progress)
+	}
+	data, err := ioutil.ReadFile(checkpointFile)
+	if err != nil {
+		t.Fatalf("expected a checkpoint: %v", err)
+	}
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	expected := Checkpoint{Resource: carps.GroupResource.String(), Key: carpKeys[249]}
+	if *checkpoint != expected {
+		t.Errorf("expected checkpoint %#v, got %#v", expected, *checkpoint)
+	}
+
+	// the second run resumes after the checkpoint
+	s.stopAfter = 0
+	s.limits = nil
+	progress, err = m.Run(make(chan struct{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if progress.Visited != len(carpKeys)-250+len(fishKeys) || progress.Resources != 2 {
+		t.Errorf("unexpected progress of the resumed run: %#v", progress)
+	}
+	for _, key := range append(carpKeys, fishKeys...) {
+		if s.updates[key] != 1 {
+			t.Errorf("expected %s to be migrated once, got %d", key, s.updates[key])
+		}
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed, got %v", err)
+	}
+
+	// carps take two pages, fish one
+	if len(s.limits) != 3 {
+		t.Errorf("expected three lists, got %v", s.limits)
+	}
+	for _, limit := range s.limits {
+		if limit != listPageSize {
+			t.Errorf("expected lists limited to %d, got %v", listPageSize, s.limits)
+			break
+		}
+	}
+}
+
+func TestRunRestartsExpiredList(t *testing.T) {
+	s := &recordingStorage{
+		Interface:      memory.New(memory.NewBackend(), codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion), "/registry", value.IdentityTransformer),
+		updates:        map[string]int{},
+		expireContinue: true,
+	}
+	carps := newTestResource("carps", s)
+	keys := createCarps(t, carps, listPageSize+50)
+
+	progress, err := (&Migrator{Resources: []Resource{carps}}).Run(make(chan struct{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if progress.Visited != len(keys) || progress.Resources != 1 {
+		t.Errorf("unexpected progress: %#v", progress)
+	}
+	for _, key := range keys {
+		if s.updates[key] != 1 {
+			t.Errorf("expected %s to be migrated once, got %d", key, s.updates[key])
+		}
+	}
+	// the expired second page restarts the list, which takes two more pages
+	if len(s.limits) != 4 {
+		t.Errorf("expected four lists, got %v", s.limits)
+	}
+}
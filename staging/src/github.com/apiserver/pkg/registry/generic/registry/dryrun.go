@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/storage"
+)
+
+// dryRunnableStorage wraps the storage.Interface of a Store. Requests whose
+// context is marked with genericapirequest.WithDryRun are evaluated against the
+// current state of the underlying storage exactly like a real write, but the
+// write itself is skipped and out is set to the object that would have been
+// persisted.
+type dryRunnableStorage struct {
+	storage storage.Interface
+}
+
+var _ storage.Interface = &dryRunnableStorage{}
+
+// newDryRunnableStorage wraps s unless it already supports dry runs.
+func newDryRunnableStorage(s storage.Interface) storage.Interface {
+	if _, ok := s.(*dryRunnableStorage); ok {
+		return s
+	}
+	return &dryRunnableStorage{storage: s}
+}
+
+// Versioner implements storage.Interface.
+func (s *dryRunnableStorage) Versioner() storage.Versioner {
+	return s.storage.Versioner()
+}
+
+// Create implements storage.Interface.
+func (s *dryRunnableStorage) Create(ctx context.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	if !genericapirequest.DryRunFrom(ctx) {
+		return s.storage.Create(ctx, key, obj, out, ttl)
+	}
+	existing := obj.DeepCopyObject()
+	if err := s.storage.Get(ctx, key, "", existing, false); err == nil {
+		return storage.NewKeyExistsError(key, 0)
+	} else if !storage.IsNotFound(err) {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return copyInto(obj, out)
+}
+
+// Delete implements storage.Interface.
+func (s *dryRunnableStorage) Delete(ctx context.Context, key string, out runtime.Object, preconditions *storage.Preconditions) error {
+	if !genericapirequest.DryRunFrom(ctx) {
+		return s.storage.Delete(ctx, key, out, preconditions)
+	}
+	if err := s.storage.Get(ctx, key, "", out, false); err != nil {
+		return err
+	}
+	return preconditions.Check(key, out)
+}
+
+// Watch implements storage.Interface.
+func (s *dryRunnableStorage) Watch(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate) (watch.Interface, error) {
+	return s.storage.Watch(ctx, key, resourceVersion, p)
+}
+
+// WatchList implements storage.Interface.
+func (s *dryRunnableStorage) WatchList(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate) (watch.Interface, error) {
+	return s.storage.WatchList(ctx, key, resourceVersion, p)
+}
+
+// Get implements storage.Interface.
+func (s *dryRunnableStorage) Get(ctx context.Context, key string, resourceVersion string, objPtr runtime.Object, ignoreNotFound bool) error {
+	return s.storage.Get(ctx, key, resourceVersion, objPtr, ignoreNotFound)
+}
+
+// GetToList implements storage.Interface.
+func (s *dryRunnableStorage) GetToList(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate, listObj runtime.Object) error {
+	return s.storage.GetToList(ctx, key, resourceVersion, p, listObj)
+}
+
+// List implements storage.Interface.
+func (s *dryRunnableStorage) List(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate, listObj runtime.Object) error {
+	return s.storage.List(ctx, key, resourceVersion, p, listObj)
+}
+
+// GuaranteedUpdate implements storage.Interface. A dry run calls tryUpdate
+// once against the current object; there is no write that could conflict.
+func (s *dryRunnableStorage) GuaranteedUpdate(
+	ctx context.Context, key string, ptrToType runtime.Object, ignoreNotFound bool,
+	preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc, suggestion ...runtime.Object) error {
+	if !genericapirequest.DryRunFrom(ctx) {
+		return s.storage.GuaranteedUpdate(ctx, key, ptrToType, ignoreNotFound, preconditions, tryUpdate, suggestion...)
+	}
+	if err := s.storage.Get(ctx, key, "", ptrToType, ignoreNotFound); err != nil {
+		return err
+	}
+	if err := preconditions.Check(key, ptrToType); err != nil {
+		return err
+	}
+	rev, err := s.Versioner().ObjectResourceVersion(ptrToType)
+	if err != nil {
+		return err
+	}
+	updated, _, err := tryUpdate(ptrToType.DeepCopyObject(), storage.ResponseMeta{ResourceVersion: rev})
+	if err != nil {
+		return err
+	}
+	return copyInto(updated, ptrToType)
+}
+
+// copyInto sets the object out points to to a deep copy of in.
+func copyInto(in, out runtime.Object) error {
+	outVal, err := conversion.EnforcePtr(out)
+	if err != nil {
+		return err
+	}
+	inVal := reflect.ValueOf(in.DeepCopyObject()).Elem()
+	if inVal.Type() != outVal.Type() {
+		return fmt.Errorf("unable to copy %v into %v", inVal.Type(), outVal.Type())
+	}
+	outVal.Set(inVal)
+	return nil
+}
@@ -0,0 +1,453 @@
+package registry
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kubeerr "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/memory"
+	"github.com/HuZhou/apiserver/pkg/storage/names"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+func init() {
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+}
+
+// testGracefulStrategy is a namespaced strategy for carps that deletes them
+// gracefully, with gracePeriod seconds unless the options ask for another
+// grace period.
+type testGracefulStrategy struct {
+	runtime.ObjectTyper
+	names.NameGenerator
+
+	gracePeriod int64
+}
+
+func (testGracefulStrategy) NamespaceScoped() bool           { return true }
+func (testGracefulStrategy) AllowCreateOnUpdate() bool       { return false }
+func (testGracefulStrategy) AllowUnconditionalUpdate() bool  { return true }
+func (testGracefulStrategy) Canonicalize(obj runtime.Object) {}
+
+func (testGracefulStrategy) PrepareForCreate(ctx genericapirequest.Context, obj runtime.Object) {}
+
+func (testGracefulStrategy) PrepareForUpdate(ctx genericapirequest.Context, obj, old runtime.Object) {
+}
+
+func (testGracefulStrategy) Validate(ctx genericapirequest.Context, obj runtime.Object) field.ErrorList {
+	return nil
+}
+
+func (testGracefulStrategy) ValidateUpdate(ctx genericapirequest.Context, obj, old runtime.Object) field.ErrorList {
+	return nil
+}
+
+func (s testGracefulStrategy) CheckGracefulDelete(ctx genericapirequest.Context, obj runtime.Object, options *metav1.DeleteOptions) bool {
+	if options.GracePeriodSeconds == nil {
+		period := s.gracePeriod
+		options.GracePeriodSeconds = &period
+	}
+	return true
+}
+
+var _ rest.RESTGracefulDeleteStrategy = testGracefulStrategy{}
+
+// newTestStore returns a Store for carps kept in memory.
+func newTestStore(strategy testGracefulStrategy) *Store {
+	strategy.ObjectTyper = scheme
+	strategy.NameGenerator = names.SimpleNameGenerator
+	prefix := "/carps"
+	return &Store{
+		NewFunc:                  func() runtime.Object { return &testapigroup.Carp{} },
+		NewListFunc:              func() runtime.Object { return &testapigroup.CarpList{} },
+		DefaultQualifiedResource: testapigroup.Resource("carps"),
+		KeyRootFunc: func(ctx genericapirequest.Context) string {
+			return NamespaceKeyRootFunc(ctx, prefix)
+		},
+		KeyFunc: func(ctx genericapirequest.Context, name string) (string, error) {
+			return NamespaceKeyFunc(ctx, prefix, name)
+		},
+		ObjectNameFunc: func(obj runtime.Object) (string, error) {
+			return obj.(*testapigroup.Carp).Name, nil
+		},
+		PredicateFunc: func(label labels.Selector, field fields.Selector) storage.SelectionPredicate {
+			return storage.SelectionPredicate{Label: label, Field: field, GetAttrs: storage.DefaultNamespaceScopedAttr}
+		},
+		CreateStrategy: strategy,
+		UpdateStrategy: strategy,
+		DeleteStrategy: strategy,
+		Storage:        memory.New(memory.NewBackend(), codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion), "/registry", value.IdentityTransformer),
+	}
+}
+
+func newCarp(name string, finalizers ...string) *testapigroup.Carp {
+	return &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Finalizers: finalizers}}
+}
+
+func testContext() genericapirequest.Context {
+	return genericapirequest.WithNamespace(genericapirequest.NewContext(), "ns")
+}
+
+// updateCarp applies update to the stored carp through the Store.
+func updateCarp(t *testing.T, s *Store, name string, update func(*testapigroup.Carp)) {
+	_, _, err := s.Update(testContext(), name, rest.DefaultUpdatedObjectInfo(nil, func(ctx genericapirequest.Context, _, old runtime.Object) (runtime.Object, error) {
+		carp := old.DeepCopyObject().(*testapigroup.Carp)
+		update(carp)
+		return carp, nil
+	}))
+	if err != nil {
+		t.Fatalf("Update of %s failed: %v", name, err)
+	}
+}
+
+// getCarp returns the stored carp, or nil if it does not exist.
+func getCarp(t *testing.T, s *Store, name string) *testapigroup.Carp {
+	out := &testapigroup.Carp{}
+	key, _ := s.KeyFunc(testContext(), name)
+	if err := s.Storage.Get(testContext(), key, "", out, false); err != nil {
+		if storage.IsNotFound(err) {
+			return nil
+		}
+		t.Fatalf("Get of %s failed: %v", name, err)
+	}
+	return out
+}
+
+// expireGracePeriod moves the deletion timestamp of the stored carp into the
+// past, as if its grace period had elapsed.
+func expireGracePeriod(t *testing.T, s *Store, name string) {
+	key, _ := s.KeyFunc(testContext(), name)
+	err := s.Storage.GuaranteedUpdate(testContext(), key, &testapigroup.Carp{}, false, nil, storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+		carp := obj.(*testapigroup.Carp)
+		expired := metav1.NewTime(time.Now().Add(-time.Second))
+		carp.DeletionTimestamp = &expired
+		return carp, nil
+	}))
+	if err != nil {
+		t.Fatalf("expiring the grace period of %s failed: %v", name, err)
+	}
+}
+
+func TestStoreDeleteGracefully(t *testing.T) {
+	tests := []struct {
+		name        string
+		finalizers  []string
+		gracePeriod *int64
+		// expectGracePeriod is the grace period of the stored object, if it
+		// is not deleted
+		expectGracePeriod int64
+		expectDeleted     bool
+	}{
+		{name: "default grace period", expectGracePeriod: 30},
+		{name: "requested grace period", gracePeriod: int64Ptr(10), expectGracePeriod: 10},
+		{name: "zero grace period", gracePeriod: int64Ptr(0), expectDeleted: true},
+		{name: "finalizers block removal", finalizers: []string{"foo"}, gracePeriod: int64Ptr(0), expectGracePeriod: 0},
+		{name: "finalizers and grace period", finalizers: []string{"foo"}, expectGracePeriod: 30},
+	}
+
+	for _, tt := range tests {
+		s := newTestStore(testGracefulStrategy{gracePeriod: 30})
+		if _, err := s.Create(testContext(), newCarp("foo", tt.finalizers...), true); err != nil {
+			t.Fatalf("%s: Create failed: %v", tt.name, err)
+		}
+
+		before := time.Now()
+		_, deleted, err := s.Delete(testContext(), "foo", &metav1.DeleteOptions{GracePeriodSeconds: tt.gracePeriod})
+		if err != nil {
+			t.Errorf("%s: Delete failed: %v", tt.name, err)
+			continue
+		}
+		if deleted != tt.expectDeleted {
+			t.Errorf("%s: expected deleted %t, got %t", tt.name, tt.expectDeleted, deleted)
+		}
+
+		stored := getCarp(t, s, "foo")
+		if tt.expectDeleted {
+			if stored != nil {
+				t.Errorf("%s: expected the object to be removed, got %#v", tt.name, stored)
+			}
+			continue
+		}
+		if stored == nil {
+			t.Errorf("%s: expected the object to be kept", tt.name)
+			continue
+		}
+		if stored.DeletionGracePeriodSeconds == nil || *stored.DeletionGracePeriodSeconds != tt.expectGracePeriod {
+			t.Errorf("%s: expected a grace period of %d, got %v", tt.name, tt.expectGracePeriod, stored.DeletionGracePeriodSeconds)
+		}
+		// the timestamp is stored with a precision of seconds
+		earliest := before.Add(time.Duration(tt.expectGracePeriod)*time.Second - time.Second)
+		if stored.DeletionTimestamp == nil || stored.DeletionTimestamp.Time.Before(earliest) {
+			t.Errorf("%s: expected a deletion timestamp after %v, got %v", tt.name, earliest, stored.DeletionTimestamp)
+		}
+	}
+}
+
+func TestStoreDeleteAfterFinalizersCleared(t *testing.T) {
+	tests := []struct {
+		name        string
+		gracePeriod int64
+		// expire elapses the grace period before the finalizers are cleared
+		expire        bool
+		expectRemoved bool
+	}{
+		{name: "no grace period", gracePeriod: 0, expectRemoved: true},
+		{name: "pending grace period", gracePeriod: 30},
+		{name: "expired grace period", gracePeriod: 30, expire: true, expectRemoved: true},
+	}
+
+	for _, tt := range tests {
+		s := newTestStore(testGracefulStrategy{gracePeriod: tt.gracePeriod})
+		if _, err := s.Create(testContext(), newCarp("foo", "foo"), true); err != nil {
+			t.Fatalf("%s: Create failed: %v", tt.name, err)
+		}
+		if _, _, err := s.Delete(testContext(), "foo", &metav1.DeleteOptions{}); err != nil {
+			t.Fatalf("%s: Delete failed: %v", tt.name, err)
+		}
+		if getCarp(t, s, "foo") == nil {
+			t.Fatalf("%s: expected the finalizer to block the removal", tt.name)
+		}
+		if tt.expire {
+			expireGracePeriod(t, s, "foo")
+		}
+
+		updateCarp(t, s, "foo", func(carp *testapigroup.Carp) { carp.Finalizers = nil })
+		if removed := getCarp(t, s, "foo") == nil; removed != tt.expectRemoved {
+			t.Errorf("%s: expected removed %t after clearing the finalizers, got %t", tt.name, tt.expectRemoved, removed)
+		}
+	}
+}
+
+func TestStoreDeleteAfterGracePeriodExpired(t *testing.T) {
+	s := newTestStore(testGracefulStrategy{gracePeriod: 30})
+	if _, err := s.Create(testContext(), newCarp("foo"), true); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, deleted, err := s.Delete(testContext(), "foo", &metav1.DeleteOptions{}); err != nil || deleted {
+		t.Fatalf("expected a graceful deletion, got deleted=%t: %v", deleted, err)
+	}
+
+	// deleting again while the grace period is pending does nothing
+	if _, deleted, err := s.Delete(testContext(), "foo", &metav1.DeleteOptions{}); err != nil || deleted {
+		t.Fatalf("expected the graceful deletion to be pending, got deleted=%t: %v", deleted, err)
+	}
+	if getCarp(t, s, "foo") == nil {
+		t.Fatalf("expected the object to be kept during its grace period")
+	}
+
+	expireGracePeriod(t, s, "foo")
+	if _, deleted, err := s.Delete(testContext(), "foo", &metav1.DeleteOptions{}); err != nil || !deleted {
+		t.Fatalf("expected the object to be deleted, got deleted=%t: %v", deleted, err)
+	}
+	if getCarp(t, s, "foo") != nil {
+		t.Errorf("expected the object to be removed once its grace period expired")
+	}
+}
+
+func TestStoreDeleteCollection(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		items   int
+	}{
+		{name: "no items", workers: 3},
+		{name: "default workers", items: 10},
+		{name: "one worker", workers: 1, items: 10},
+		{name: "some workers", workers: 3, items: 10},
+		{name: "more workers than items", workers: 20, items: 10},
+	}
+
+	for _, tt := range tests {
+		s := newTestStore(testGracefulStrategy{})
+		s.DeleteCollectionWorkers = tt.workers
+		for i := 0; i < tt.items; i++ {
+			if _, err := s.Create(testContext(), newCarp(fmt.Sprintf("foo%d", i)), true); err != nil {
+				t.Fatalf("%s: Create failed: %v", tt.name, err)
+			}
+		}
+
+		deleted, err := s.DeleteCollection(testContext(), &metav1.DeleteOptions{}, nil)
+		if err != nil {
+			t.Errorf("%s: DeleteCollection failed: %v", tt.name, err)
+			continue
+		}
+		if items := len(deleted.(*testapigroup.CarpList).Items); items != tt.items {
+			t.Errorf("%s: expected %d deleted items, got %d", tt.name, tt.items, items)
+		}
+		remaining, err := s.List(testContext(), &metainternalversion.ListOptions{})
+		if err != nil {
+			t.Fatalf("%s: List failed: %v", tt.name, err)
+		}
+		if items := len(remaining.(*testapigroup.CarpList).Items); items != 0 {
+			t.Errorf("%s: expected every item to be deleted, %d remain", tt.name, items)
+		}
+	}
+}
+
+func TestStoreDeleteCollectionFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		items        int
+		expectCauses int
+	}{
+		// a single failure is returned as it is
+		{name: "one item", items: 1},
+		{name: "many items", items: 5, expectCauses: 5},
+	}
+
+	for _, tt := range tests {
+		s := newTestStore(testGracefulStrategy{})
+		s.DeleteCollectionWorkers = 2
+		for i := 0; i < tt.items; i++ {
+			if _, err := s.Create(testContext(), newCarp(fmt.Sprintf("foo%d", i)), true); err != nil {
+				t.Fatalf("%s: Create failed: %v", tt.name, err)
+			}
+		}
+
+		// every deletion fails its precondition
+		uid := types.UID("other")
+		_, err := s.DeleteCollection(testContext(), &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}}, nil)
+		if !kubeerr.IsConflict(err) {
+			t.Errorf("%s: expected a conflict, got %v", tt.name, err)
+			continue
+		}
+		var causes []metav1.StatusCause
+		if details := err.(kubeerr.APIStatus).Status().Details; details != nil {
+			causes = details.Causes
+		}
+		if len(causes) != tt.expectCauses {
+			t.Errorf("%s: expected %d causes, got %v", tt.name, tt.expectCauses, causes)
+		}
+	}
+}
+
+func TestDeleteCollectionStatusError(t *testing.T) {
+	s := newTestStore(testGracefulStrategy{})
+	resource := s.DefaultQualifiedResource
+	conflict := func(name string) deleteCollectionError {
+		return deleteCollectionError{name: name, err: kubeerr.NewConflict(resource, name, fmt.Errorf("conflict"))}
+	}
+
+	tests := []struct {
+		name         string
+		total        int
+		failures     []deleteCollectionError
+		expectCode   int32
+		expectReason metav1.StatusReason
+		// expectCauses are the messages of the causes
+		expectCauses []string
+	}{
+		{
+			name:         "single failure",
+			total:        3,
+			failures:     []deleteCollectionError{conflict("foo")},
+			expectCode:   409,
+			expectReason: metav1.StatusReasonConflict,
+		},
+		{
+			name:         "same failures",
+			total:        3,
+			failures:     []deleteCollectionError{conflict("foo"), conflict("bar")},
+			expectCode:   409,
+			expectReason: metav1.StatusReasonConflict,
+			expectCauses: []string{"foo: " + conflict("foo").err.Error(), "bar: " + conflict("bar").err.Error()},
+		},
+		{
+			name:  "same code, other reasons",
+			total: 3,
+			failures: []deleteCollectionError{
+				conflict("foo"),
+				{name: "bar", err: kubeerr.NewAlreadyExists(resource, "bar")},
+			},
+			expectCode:   409,
+			expectReason: metav1.StatusReasonUnknown,
+			expectCauses: []string{"foo: " + conflict("foo").err.Error(), "bar: " + kubeerr.NewAlreadyExists(resource, "bar").Error()},
+		},
+		{
+			name:  "other codes",
+			total: 3,
+			failures: []deleteCollectionError{
+				conflict("foo"),
+				{name: "bar", err: kubeerr.NewForbidden(resource, "bar", fmt.Errorf("forbidden"))},
+			},
+			expectCode:   500,
+			expectReason: metav1.StatusReasonInternalError,
+			expectCauses: []string{"foo: " + conflict("foo").err.Error(), "bar: " + kubeerr.NewForbidden(resource, "bar", fmt.Errorf("forbidden")).Error()},
+		},
+		{
+			name:  "failures without status",
+			total: 3,
+			failures: []deleteCollectionError{
+				{name: "foo", err: fmt.Errorf("broken")},
+				{err: fmt.Errorf("DeleteCollection goroutine panicked")},
+			},
+			expectCode:   500,
+			expectReason: metav1.StatusReasonInternalError,
+			expectCauses: []string{"foo: broken", "DeleteCollection goroutine panicked"},
+		},
+	}
+
+	for _, tt := range tests {
+		err := s.deleteCollectionStatusError(testContext(), tt.total, tt.failures)
+		status, ok := err.(kubeerr.APIStatus)
+		if !ok {
+			t.Errorf("%s: expected a status error, got %v", tt.name, err)
+			continue
+		}
+		if code := status.Status().Code; code != tt.expectCode {
+			t.Errorf("%s: expected code %d, got %d", tt.name, tt.expectCode, code)
+		}
+		if reason := status.Status().Reason; reason != tt.expectReason {
+			t.Errorf("%s: expected reason %q, got %q", tt.name, tt.expectReason, reason)
+		}
+		if len(tt.failures) == 1 {
+			if err != tt.failures[0].err {
+				t.Errorf("%s: expected the failure to be returned unchanged, got %v", tt.name, err)
+			}
+			continue
+		}
+
+		details := status.Status().Details
+		if details == nil || details.Group != resource.Group || details.Kind != resource.Resource {
+			t.Errorf("%s: expected details of %v, got %#v", tt.name, resource, details)
+			continue
+		}
+		causes := []string{}
+		for _, cause := range details.Causes {
+			causes = append(causes, cause.Message)
+		}
+		if !reflect.DeepEqual(causes, tt.expectCauses) {
+			t.Errorf("%s: expected causes %v, got %v", tt.name, tt.expectCauses, causes)
+		}
+		expectPrefix := fmt.Sprintf("failed to delete %d of %d %s: ", len(tt.failures), tt.total, resource.String())
+		if message := status.Status().Message; !strings.HasPrefix(message, expectPrefix) {
+			t.Errorf("%s: expected a message starting with %q, got %q", tt.name, expectPrefix, message)
+		}
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+)
+
+// gracefulStrategy deletes objects gracefully and sets the grace period of
+// the options to gracePeriod, unless it is nil.
+type gracefulStrategy struct {
+	runtime.ObjectTyper
+
+	gracePeriod *int64
+}
+
+func (s gracefulStrategy) CheckGracefulDelete(ctx genericapirequest.Context, obj runtime.Object, options *metav1.DeleteOptions) bool {
+	if options.GracePeriodSeconds == nil {
+		options.GracePeriodSeconds = s.gracePeriod
+	}
+	return true
+}
+
+func TestBeforeDelete(t *testing.T) {
+	scheme := runtime.NewScheme()
+	testapigroup.AddToScheme(scheme)
+
+	period := func(seconds int64) *int64 { return &seconds }
+	deleting := func(gracePeriod int64, timestamp time.Time) *testapigroup.Carp {
+		deletionTimestamp := metav1.NewTime(timestamp)
+		return &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", DeletionTimestamp: &deletionTimestamp, DeletionGracePeriodSeconds: &gracePeriod}}
+	}
+
+	tests := []struct {
+		name                  string
+		obj                   *testapigroup.Carp
+		strategyGracePeriod   *int64
+		options               *metav1.DeleteOptions
+		expectGraceful        bool
+		expectGracefulPending bool
+		expectErr             bool
+	}{
+		{name: "graceful", obj: &testapigroup.Carp{}, strategyGracePeriod: period(30), options: &metav1.DeleteOptions{}, expectGraceful: true},
+		{name: "no grace period", obj: &testapigroup.Carp{}, options: &metav1.DeleteOptions{}, expectErr: true},
+		{name: "pending", obj: deleting(30, time.Now().Add(time.Minute)), options: &metav1.DeleteOptions{}, expectGracefulPending: true},
+		{name: "shorter grace period", obj: deleting(30, time.Now().Add(time.Minute)), options: &metav1.DeleteOptions{GracePeriodSeconds: period(10)}, expectGraceful: true},
+		{name: "expired", obj: deleting(30, time.Now().Add(-time.Second)), options: &metav1.DeleteOptions{}},
+		{name: "zero grace period", obj: deleting(0, time.Now()), options: &metav1.DeleteOptions{}},
+	}
+
+	for _, tt := range tests {
+		strategy := gracefulStrategy{ObjectTyper: scheme, gracePeriod: tt.strategyGracePeriod}
+		graceful, gracefulPending, err := BeforeDelete(strategy, genericapirequest.NewContext(), tt.obj, tt.options)
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+			continue
+		}
+		if graceful != tt.expectGraceful || gracefulPending != tt.expectGracefulPending {
+			t.Errorf("%s: expected graceful=%t, gracefulPending=%t, got %t, %t", tt.name, tt.expectGraceful, tt.expectGracefulPending, graceful, gracefulPending)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package rest
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/features"
+	utilfeature "github.com/HuZhou/apiserver/pkg/util/feature"
+)
+
+// FillObjectMetaSystemFields populates fields that are managed by the system on ObjectMeta.
+func FillObjectMetaSystemFields(ctx genericapirequest.Context, meta metav1.Object) {
+	meta.SetCreationTimestamp(metav1.Now())
+	// allows admission controllers to assign a UID earlier in the request processing
+	// to support tracking resources pending creation.
+	uid, found := genericapirequest.UIDFrom(ctx)
+	if !found {
+		uid = uuid.NewUUID()
+	}
+	meta.SetUID(uid)
+	meta.SetSelfLink("")
+}
+
+// normalizeInitializers drops the initializers of an object if the
+// Initializers feature is disabled, and clears them once no initializer is
+// pending and no failure has been recorded, which marks the object as
+// initialized.
+func normalizeInitializers(meta metav1.Object) {
+	initializers := meta.GetInitializers()
+	if initializers == nil {
+		return
+	}
+	if !utilfeature.DefaultFeatureGate.Enabled(features.Initializers) {
+		meta.SetInitializers(nil)
+		return
+	}
+	if len(initializers.Pending) == 0 && initializers.Result == nil {
+		meta.SetInitializers(nil)
+	}
+}
+
+// ValidNamespace returns false if the namespace on the context differs from
+// the resource.  If the resource has no namespace, it is set to the value in
+// the context.
+//
+// TODO(sttts): move into pkg/genericapiserver/endpoints
+func ValidNamespace(ctx genericapirequest.Context, resource metav1.Object) bool {
+	ns, ok := genericapirequest.NamespaceFrom(ctx)
+	if len(resource.GetNamespace()) == 0 {
+		resource.SetNamespace(ns)
+	}
+	return ns == resource.GetNamespace() && ok
+}
@@ -0,0 +1,106 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/util/duration"
+)
+
+type defaultTableConvertor struct {
+	qualifiedResource schema.GroupResource
+}
+
+// NewDefaultTableConvertor creates a default convertor for the provided resource. It
+// renders the name and the age of every object, which is what all resources share.
+func NewDefaultTableConvertor(resource schema.GroupResource) TableConvertor {
+	return defaultTableConvertor{qualifiedResource: resource}
+}
+
+var swaggerMetadataDescriptions = metav1.ObjectMeta{}.SwaggerDoc()
+
+// DefaultTableColumns are the columns rendered by the default convertor. The cells
+// of a row are returned by DefaultTableCells.
+var DefaultTableColumns = []metav1beta1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name", Description: swaggerMetadataDescriptions["name"]},
+	{Name: "Age", Type: "string", Description: swaggerMetadataDescriptions["creationTimestamp"]},
+}
+
+// DefaultTableCells returns the cells of the DefaultTableColumns for an object.
+func DefaultTableCells(m metav1.Object) []interface{} {
+	return []interface{}{m.GetName(), TranslateTimestamp(m.GetCreationTimestamp())}
+}
+
+// TranslateTimestamp returns the elapsed time since timestamp in
+// human-readable approximation.
+func TranslateTimestamp(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.ShortHumanDuration(time.Now().Sub(timestamp.Time))
+}
+
+func (c defaultTableConvertor) ConvertToTable(ctx genericapirequest.Context, object runtime.Object, tableOptions runtime.Object) (*metav1beta1.Table, error) {
+	table := &metav1beta1.Table{ColumnDefinitions: DefaultTableColumns}
+	fn := func(obj runtime.Object) error {
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return errNotAcceptable{resource: c.qualifiedResource}
+		}
+		table.Rows = append(table.Rows, metav1beta1.TableRow{
+			Cells:  DefaultTableCells(m),
+			Object: runtime.RawExtension{Object: obj},
+		})
+		return nil
+	}
+	if meta.IsListType(object) {
+		if err := meta.EachListItem(object, fn); err != nil {
+			return nil, err
+		}
+	} else if err := fn(object); err != nil {
+		return nil, err
+	}
+	SetTableListMeta(table, object)
+	return table, nil
+}
+
+// SetTableListMeta copies the resource version, self link and continue token of
+// object, a list or a single resource, into the list metadata of table.
+func SetTableListMeta(table *metav1beta1.Table, object runtime.Object) {
+	if meta.IsListType(object) {
+		if m, err := meta.ListAccessor(object); err == nil {
+			table.ResourceVersion = m.GetResourceVersion()
+			table.SelfLink = m.GetSelfLink()
+			table.Continue = m.GetContinue()
+		}
+	} else if m, err := meta.CommonAccessor(object); err == nil {
+		table.ResourceVersion = m.GetResourceVersion()
+		table.SelfLink = m.GetSelfLink()
+	}
+}
+
+// errNotAcceptable indicates the resource doesn't support Table conversion
+type errNotAcceptable struct {
+	resource schema.GroupResource
+}
+
+func (e errNotAcceptable) Error() string {
+	return fmt.Sprintf("the resource %s does not support being converted to a Table", e.resource)
+}
+
+func (e errNotAcceptable) Status() metav1.Status {
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotAcceptable,
+		Reason:  metav1.StatusReason("NotAcceptable"),
+		Message: e.Error(),
+	}
+}
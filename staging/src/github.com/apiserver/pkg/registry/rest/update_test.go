@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	utilfeature "github.com/HuZhou/apiserver/pkg/util/feature"
+)
+
+func TestValidateInitializersPendingUpdate(t *testing.T) {
+	if err := utilfeature.DefaultFeatureGate.Set("Initializers=true"); err != nil {
+		t.Fatal(err)
+	}
+	defer utilfeature.DefaultFeatureGate.Set("Initializers=false")
+
+	pending := func(names ...string) *metav1.Initializers {
+		initializers := &metav1.Initializers{}
+		for _, name := range names {
+			initializers.Pending = append(initializers.Pending, metav1.Initializer{Name: name})
+		}
+		return initializers
+	}
+
+	tests := []struct {
+		name        string
+		old, new    *metav1.Initializers
+		expectValid bool
+	}{
+		{name: "no initializers", expectValid: true},
+		{name: "unchanged", old: pending("a", "b"), new: pending("a", "b"), expectValid: true},
+		{name: "remove the first", old: pending("a", "b"), new: pending("b"), expectValid: true},
+		{name: "remove the last one", old: pending("a"), new: pending(), expectValid: true},
+		{name: "clear the last one", old: pending("a"), new: nil, expectValid: true},
+		{name: "remove the second", old: pending("a", "b"), new: pending("a")},
+		{name: "reorder", old: pending("a", "b"), new: pending("b", "a")},
+		{name: "replace", old: pending("a"), new: pending("c")},
+		{name: "add", old: pending("a"), new: pending("a", "b")},
+		{name: "add to an initialized object", old: nil, new: pending("a")},
+		{name: "clear several", old: pending("a", "b"), new: nil},
+		{name: "empty several", old: pending("a", "b"), new: pending()},
+	}
+
+	for _, tt := range tests {
+		errs := validateInitializersPendingUpdate(tt.new, tt.old, field.NewPath("metadata", "initializers", "pending"))
+		if tt.expectValid && len(errs) > 0 {
+			t.Errorf("%s: unexpected errors: %v", tt.name, errs)
+		}
+		if !tt.expectValid && len(errs) == 0 {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestValidateInitializersPendingUpdateDisabled(t *testing.T) {
+	old := &metav1.Initializers{Pending: []metav1.Initializer{{Name: "a"}, {Name: "b"}}}
+	if errs := validateInitializersPendingUpdate(nil, old, field.NewPath("metadata", "initializers", "pending")); len(errs) > 0 {
+		t.Errorf("unexpected errors with the Initializers feature disabled: %v", errs)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic"
 	genericfilters "github.com/HuZhou/apiserver/pkg/server/filters"
 	genericapifilters "github.com/HuZhou/apiserver/pkg/endpoints/filters"
+	"github.com/HuZhou/apiserver/pkg/endpoints/discovery"
 	apirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
 	auditpolicy "github.com/HuZhou/apiserver/pkg/audit/policy"
 	restclient "k8s.io/client-go/rest"
@@ -21,6 +22,7 @@ import (
 	"github.com/HuZhou/apiserver/pkg/authorization/authorizer"
 	"github.com/HuZhou/apiserver/pkg/authentication/authenticator"
 	"github.com/HuZhou/apiserver/pkg/server/routes"
+	"github.com/HuZhou/apiserver/pkg/server/healthz"
 )
 
 const (
@@ -45,11 +47,18 @@ type Config struct {
 	SupportsBasicAuth bool
 
 	EnableDiscovery bool
+	// DiscoveryAddresses is used to build the IPs pass to discovery. If nil, the ExternalAddress is
+	// always reported
+	DiscoveryAddresses discovery.Addresses
 
 	EnableIndex     bool
 	// RESTOptionsGetter is used to construct RESTStorage types via the generic registry.
 	RESTOptionsGetter genericregistry.RESTOptionsGetter
 
+	// ExternalAddress is the host name to use for external (public internet) facing URLs (e.g. Swagger)
+	// Will default to a value based on secure serving info and available ipv4 IPs.
+	ExternalAddress string
+
 	// Version will enable the /version endpoint if non-nil
 	Version *version.Info
 
@@ -66,6 +75,9 @@ type Config struct {
 	// If specified, all requests except those which match the LongRunningFunc predicate will timeout
 	// after this duration.
 	RequestTimeout time.Duration
+	// If specified, long running requests such as watch will be allocated a random timeout between this value, and
+	// twice this value.  Note that it is up to the request handlers to ignore or honor this timeout. In seconds.
+	MinRequestTimeout int
 
 	// MaxRequestsInFlight is the maximum number of parallel non-long-running requests. Every further
 	// request has to wait. Applies only to non-mutating requests.
@@ -80,6 +92,15 @@ type Config struct {
 
 	// BuildHandlerChainFunc allows you to build custom handler chains by decorating the apiHandler.
 	BuildHandlerChainFunc func(apiHandler http.Handler, c *Config) (secure http.Handler)
+
+	// HealthzChecks are the checks installed on /healthz in addition to the post-start hook checks.
+	HealthzChecks []healthz.HealthzChecker
+
+	// EnableMetrics serves the prometheus metrics on /metrics.
+	EnableMetrics bool
+
+	// PostStartHooks are added to the server by name and run once it has started serving.
+	PostStartHooks map[string]PostStartHookFunc
 }
 
 
@@ -90,6 +111,9 @@ type completedConfig struct {
 // Complete fills in any fields not set that are required to have valid data and can be derived
 // from other fields. If you're going to `ApplyOptions`, do that first. It's mutating the receiver.
 func (c *Config) Complete() completedConfig {
+	if c.DiscoveryAddresses == nil {
+		c.DiscoveryAddresses = discovery.DefaultAddresses{DefaultAddress: c.ExternalAddress}
+	}
 
 	return completedConfig{c}
 }
@@ -108,10 +132,28 @@ func (c completedConfig) New(name string, delegationTarget DelegationTarget) (*G
 	apiServerHandler := NewAPIServerHandler(name, c.RequestContextMapper, c.Serializer, handlerChainBuilder, delegationTarget.UnprotectedHandler())
 
 	s := &GenericAPIServer{
+		legacyAPIGroupPrefixes: c.LegacyAPIGroupPrefixes,
+		minRequestTimeout:      time.Duration(c.MinRequestTimeout) * time.Second,
+		requestContextMapper:   c.RequestContextMapper,
+		authorizer:             c.Authorizer,
+		Serializer:             c.Serializer,
 		postStartHooks:         map[string]postStartHookEntry{},
+		LoopbackClientConfig:   c.LoopbackClientConfig,
 		Handler: 				apiServerHandler,
 		listedPathProvider: 	apiServerHandler,
+
+		discoveryAddresses:    c.DiscoveryAddresses,
+		DiscoveryGroupManager: discovery.NewRootAPIsHandler(c.DiscoveryAddresses, c.Serializer, c.RequestContextMapper),
+	}
+	if err := s.AddHealthzChecks(c.HealthzChecks...); err != nil {
+		return nil, err
 	}
+	for name, hook := range c.PostStartHooks {
+		if err := s.AddPostStartHook(name, hook); err != nil {
+			return nil, err
+		}
+	}
+
 	installAPI(s, c.Config)
 	return s, nil
 }
@@ -129,18 +171,14 @@ func installAPI(s *GenericAPIServer, c *Config) {
 	//		goruntime.SetBlockProfileRate(1)
 	//	}
 	//}
-	//if c.EnableMetrics {
-	//	if c.EnableProfiling {
-	//		routes.MetricsWithReset{}.Install(s.Handler.NonGoRestfulMux)
-	//	} else {
-	//		routes.DefaultMetrics{}.Install(s.Handler.NonGoRestfulMux)
-	//	}
-	//}
+	if c.EnableMetrics {
+		routes.DefaultMetrics{}.Install(s.Handler.NonGoRestfulMux)
+	}
 	routes.Version{Version: c.Version}.Install(s.Handler.GoRestfulContainer)
 
-	//if c.EnableDiscovery {
-	//	s.Handler.GoRestfulContainer.Add(s.DiscoveryGroupManager.WebService())
-	//}
+	if c.EnableDiscovery {
+		s.Handler.GoRestfulContainer.Add(s.DiscoveryGroupManager.WebService())
+	}
 }
 
 func NewRequestInfoResolver(c *Config) *apirequest.RequestInfoFactory {
@@ -215,14 +253,16 @@ func NewConfig(codecs serializer.CodecFactory) *Config {
 		BuildHandlerChainFunc:        DefaultBuildHandlerChain,
 		LegacyAPIGroupPrefixes:       sets.NewString(DefaultLegacyAPIPrefix),
 		//DisabledPostStartHooks:       sets.NewString(),
-		//HealthzChecks:                []healthz.HealthzChecker{healthz.PingHealthz},
+		HealthzChecks:                []healthz.HealthzChecker{healthz.PingHealthz},
+		PostStartHooks:               map[string]PostStartHookFunc{},
 		//EnableIndex:                  true,
 		EnableDiscovery:              true,
 		//EnableProfiling:              true,
+		EnableMetrics:                true,
 		MaxRequestsInFlight:          400,
 		MaxMutatingRequestsInFlight:  200,
 		RequestTimeout:               time.Duration(60) * time.Second,
-		//MinRequestTimeout:            1800,
+		MinRequestTimeout:            1800,
 		//EnableAPIResponseCompression: utilfeature.DefaultFeatureGate.Enabled(features.APIResponseCompression),
 
 		// Default to treating watch as a long-running operation
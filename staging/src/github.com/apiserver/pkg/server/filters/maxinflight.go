@@ -24,7 +24,7 @@ var nonMutatingRequestVerbs = sets.NewString("get", "list", "watch")
 func handleError(w http.ResponseWriter, r *http.Request, err error) {
 	w.WriteHeader(http.StatusInternalServerError)
 	fmt.Fprintf(w, "Internal Server Error: %#v", r.RequestURI)
-	glog.Errorf(err.Error())
+	glog.Error(err)
 }
 
 // WithMaxInFlightLimit limits the number of in-flight requests to buffer size of the passed in channel.
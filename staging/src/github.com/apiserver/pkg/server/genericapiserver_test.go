@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	genericapirequest "github.com/HuZhou/apiserver/pkg/endpoints/request"
+	"github.com/HuZhou/apiserver/pkg/registry/rest"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+// legacyGroupVersion serves carps below the legacy /api prefix.
+var legacyGroupVersion = schema.GroupVersion{Version: "v1"}
+
+func init() {
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+
+	scheme.AddKnownTypes(schema.GroupVersion{Version: runtime.APIVersionInternal}, &testapigroup.Carp{})
+	scheme.AddKnownTypes(legacyGroupVersion, &testapigroupv1.Carp{})
+	metav1.AddToGroupVersion(scheme, legacyGroupVersion)
+}
+
+// carpStorage serves a single carp named foo.
+type carpStorage struct{}
+
+func (carpStorage) New() runtime.Object   { return &testapigroup.Carp{} }
+func (carpStorage) NamespaceScoped() bool { return true }
+
+func (carpStorage) Get(ctx genericapirequest.Context, name string, options *metav1.GetOptions) (runtime.Object, error) {
+	namespace, _ := genericapirequest.NamespaceFrom(ctx)
+	return &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}, nil
+}
+
+var _ rest.Getter = carpStorage{}
+
+func newTestServer(t *testing.T) *GenericAPIServer {
+	s, err := NewConfig(codecs).Complete().New("test", EmptyDelegate)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return s
+}
+
+func newTestGroupInfo(versions []schema.GroupVersion, storage map[string]map[string]rest.Storage) *APIGroupInfo {
+	return &APIGroupInfo{
+		PrioritizedVersions:          versions,
+		VersionedResourcesStorageMap: storage,
+		Scheme:                       scheme,
+		NegotiatedSerializer:         codecs,
+		ParameterCodec:               runtime.NewParameterCodec(scheme),
+	}
+}
+
+// get serves a GET of path and decodes a successful response into into.
+func get(s *GenericAPIServer, path string, into interface{}) (int, error) {
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	if w.Code != http.StatusOK || into == nil {
+		return w.Code, nil
+	}
+	body, err := ioutil.ReadAll(w.Body)
+	if err != nil {
+		return w.Code, err
+	}
+	return w.Code, json.Unmarshal(body, into)
+}
+
+func TestInstallAPIGroup(t *testing.T) {
+	v1 := testapigroupv1.SchemeGroupVersion
+	v2 := schema.GroupVersion{Group: v1.Group, Version: "v2"}
+	carps := map[string]rest.Storage{"carps": carpStorage{}}
+
+	tests := []struct {
+		name     string
+		versions []schema.GroupVersion
+		storage  map[string]map[string]rest.Storage
+		// expectVersions are the versions listed by discovery, if the group
+		// is installed
+		expectVersions []string
+		expectErr      bool
+	}{
+		{name: "no versions", expectErr: true},
+		{name: "empty group", versions: []schema.GroupVersion{{Version: "v1"}}, expectErr: true},
+		{name: "empty version", versions: []schema.GroupVersion{{Group: v1.Group}}, expectErr: true},
+		{
+			name:           "installed",
+			versions:       []schema.GroupVersion{v1},
+			storage:        map[string]map[string]rest.Storage{"v1": carps},
+			expectVersions: []string{"v1"},
+		},
+		{
+			name:           "version without resources",
+			versions:       []schema.GroupVersion{v1, v2},
+			storage:        map[string]map[string]rest.Storage{"v1": carps},
+			expectVersions: []string{"v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		s := newTestServer(t)
+		err := s.InstallAPIGroup(newTestGroupInfo(tt.versions, tt.storage))
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+			continue
+		}
+		if tt.expectErr {
+			continue
+		}
+
+		carp := &testapigroupv1.Carp{}
+		if code, err := get(s, "/apis/"+v1.String()+"/namespaces/ns/carps/foo", carp); code != http.StatusOK || err != nil {
+			t.Errorf("%s: expected carps to be served, got %d: %v", tt.name, code, err)
+		} else if carp.Name != "foo" || carp.Namespace != "ns" {
+			t.Errorf("%s: unexpected carp %#v", tt.name, carp)
+		}
+		if code, _ := get(s, "/apis/"+v2.String()+"/namespaces/ns/carps/foo", nil); code != http.StatusNotFound {
+			t.Errorf("%s: expected a version without resources not to be served, got %d", tt.name, code)
+		}
+
+		group := &metav1.APIGroup{}
+		if code, err := get(s, "/apis/"+v1.Group, group); code != http.StatusOK || err != nil {
+			t.Errorf("%s: expected the group to be discoverable, got %d: %v", tt.name, code, err)
+			continue
+		}
+		versions := []string{}
+		for _, version := range group.Versions {
+			versions = append(versions, version.Version)
+		}
+		if !reflect.DeepEqual(versions, tt.expectVersions) {
+			t.Errorf("%s: expected versions %v, got %v", tt.name, tt.expectVersions, versions)
+		}
+		if group.PreferredVersion.Version != tt.versions[0].Version {
+			t.Errorf("%s: expected preferred version %s, got %s", tt.name, tt.versions[0].Version, group.PreferredVersion.Version)
+		}
+
+		groups := &metav1.APIGroupList{}
+		if code, err := get(s, "/apis", groups); code != http.StatusOK || err != nil {
+			t.Errorf("%s: expected the groups to be listed, got %d: %v", tt.name, code, err)
+		} else if len(groups.Groups) != 1 || groups.Groups[0].Name != v1.Group {
+			t.Errorf("%s: expected only %s to be listed, got %#v", tt.name, v1.Group, groups.Groups)
+		}
+	}
+}
+
+func TestInstallLegacyAPIGroup(t *testing.T) {
+	v2 := schema.GroupVersion{Version: "v2"}
+	carps := map[string]rest.Storage{"carps": carpStorage{}}
+
+	tests := []struct {
+		name           string
+		prefix         string
+		versions       []schema.GroupVersion
+		expectVersions []string
+		expectErr      bool
+	}{
+		{name: "installed", prefix: DefaultLegacyAPIPrefix, versions: []schema.GroupVersion{legacyGroupVersion}, expectVersions: []string{"v1"}},
+		{name: "version without resources", prefix: DefaultLegacyAPIPrefix, versions: []schema.GroupVersion{legacyGroupVersion, v2}, expectVersions: []string{"v1"}},
+		{name: "unknown prefix", prefix: "/legacy", versions: []schema.GroupVersion{legacyGroupVersion}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		s := newTestServer(t)
+		err := s.InstallLegacyAPIGroup(tt.prefix, newTestGroupInfo(tt.versions, map[string]map[string]rest.Storage{"v1": carps}))
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+			continue
+		}
+		if tt.expectErr {
+			continue
+		}
+
+		carp := &testapigroupv1.Carp{}
+		if code, err := get(s, tt.prefix+"/v1/namespaces/ns/carps/foo", carp); code != http.StatusOK || err != nil {
+			t.Errorf("%s: expected carps to be served, got %d: %v", tt.name, code, err)
+		} else if carp.Name != "foo" {
+			t.Errorf("%s: unexpected carp %#v", tt.name, carp)
+		}
+
+		versions := &metav1.APIVersions{}
+		if code, err := get(s, tt.prefix, versions); code != http.StatusOK || err != nil {
+			t.Errorf("%s: expected the versions to be discoverable, got %d: %v", tt.name, code, err)
+		} else if !reflect.DeepEqual(versions.Versions, tt.expectVersions) {
+			t.Errorf("%s: expected versions %v, got %v", tt.name, tt.expectVersions, versions.Versions)
+		}
+	}
+}
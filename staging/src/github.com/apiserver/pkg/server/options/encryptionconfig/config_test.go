@@ -0,0 +1,57 @@
+package encryptionconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+const kmsConfigTemplate = `
+kind: EncryptionConfig
+apiVersion: v1
+resources:
+  - resources:
+    - secrets
+    providers:
+    - kms:
+        name: %NAME%
+        cachesize: %CACHESIZE%
+        endpoint: unix:///tmp/testprovider.sock
+`
+
+func TestKMSConfigValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		kmsName     string
+		cacheSize   string
+		expectError string
+	}{
+		{name: "missing name", kmsName: `""`, cacheSize: "10", expectError: "kms provider has no name"},
+		{name: "negative cache size", kmsName: "foo", cacheSize: "-1", expectError: "negative cache size"},
+	}
+
+	for _, tt := range tests {
+		config := strings.NewReplacer("%NAME%", tt.kmsName, "%CACHESIZE%", tt.cacheSize).Replace(kmsConfigTemplate)
+		_, err := ParseEncryptionConfiguration(strings.NewReader(config))
+		if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.expectError, err)
+		}
+	}
+}
+
+func TestKMSPrefixTransformerError(t *testing.T) {
+	// An error creating the envelope transformer must not produce a provider
+	// without a transformer.
+	config := &ResourceConfig{
+		Resources: []string{"secrets"},
+		Providers: []ProviderConfig{
+			{KMS: &KMSConfig{Name: "foo", CacheSize: -1, Endpoint: "unix:///tmp/testprovider.sock"}},
+		},
+	}
+	transformers, err := GetPrefixTransformers(config)
+	if err == nil {
+		t.Fatalf("expected an error, got transformers %#v", transformers)
+	}
+	if len(transformers) != 0 {
+		t.Errorf("expected no transformers, got %#v", transformers)
+	}
+}
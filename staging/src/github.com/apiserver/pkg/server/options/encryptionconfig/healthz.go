@@ -0,0 +1,77 @@
+package encryptionconfig
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/HuZhou/apiserver/pkg/server/healthz"
+	"github.com/HuZhou/apiserver/pkg/storage/value/encrypt/envelope"
+)
+
+const kmsPluginHealthzTTL = 3 * time.Second
+
+// kmsPluginProbe checks that a KMS plugin is able to encrypt data. Results are
+// cached for kmsPluginHealthzTTL so that frequent probes do not load the plugin.
+type kmsPluginProbe struct {
+	name string
+	envelope.Service
+
+	lock       sync.Mutex
+	lastResult error
+	lastCheck  time.Time
+}
+
+// GetKMSPluginHealthzCheckers returns a healthz checker for every KMS provider
+// found in the encryption provider configuration file.
+func GetKMSPluginHealthzCheckers(filepath string) ([]healthz.HealthzChecker, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening encryption provider configuration file %q: %v", filepath, err)
+	}
+	defer f.Close()
+
+	config, err := loadConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing encryption provider configuration file %q: %v", filepath, err)
+	}
+
+	var result []healthz.HealthzChecker
+	for _, resourceConfig := range config.Resources {
+		for _, provider := range resourceConfig.Providers {
+			if provider.KMS == nil {
+				continue
+			}
+			service, err := getEnvelopeService(provider.KMS)
+			if err != nil {
+				return nil, fmt.Errorf("could not configure KMS plugin %q, error: %v", provider.KMS.Name, err)
+			}
+			result = append(result, &kmsPluginProbe{name: provider.KMS.Name, Service: service})
+		}
+	}
+	return result, nil
+}
+
+func (p *kmsPluginProbe) Name() string {
+	return "kms-provider-" + p.name
+}
+
+// Check encrypts a small payload with the plugin and reports any failure.
+func (p *kmsPluginProbe) Check(_ *http.Request) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.lastCheck.IsZero() && time.Since(p.lastCheck) < kmsPluginHealthzTTL {
+		return p.lastResult
+	}
+
+	_, err := p.Service.Encrypt([]byte("ping"))
+	if err != nil {
+		err = fmt.Errorf("failed to perform encrypt section of the healthz check for KMS Provider %s, error: %v", p.name, err)
+	}
+	p.lastResult = err
+	p.lastCheck = time.Now()
+	return err
+}
@@ -1,6 +1,29 @@
 package options
 
-import "github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/HuZhou/apiserver/pkg/registry/generic"
+	genericregistry "github.com/HuZhou/apiserver/pkg/registry/generic/registry"
+	"github.com/HuZhou/apiserver/pkg/server"
+	"github.com/HuZhou/apiserver/pkg/server/healthz"
+	"github.com/HuZhou/apiserver/pkg/server/options/encryptionconfig"
+	serverstorage "github.com/HuZhou/apiserver/pkg/server/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+	storagefactory "github.com/HuZhou/apiserver/pkg/storage/storagebackend/factory"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
 
 type EtcdOptions struct {
 	StorageConfig                    storagebackend.Config
@@ -11,6 +34,17 @@ type EtcdOptions struct {
 	// To enable protobuf as storage format, it is enough
 	// to set it to "application/vnd.kubernetes.protobuf".
 	DefaultStorageMediaType string
+	// StorageSerializer builds the storage codec of the groups listed in
+	// StorageVersions, encoding objects as DefaultStorageMediaType. Resources
+	// of other groups are stored with StorageConfig.Codec.
+	StorageSerializer runtime.StorageSerializer
+	// StorageVersions maps an API group to the version its objects are stored at.
+	StorageVersions map[string]schema.GroupVersion
+	// StorageScheme knows the types of StorageVersions. A group is only
+	// stored as protobuf if all types of its storage version have a protobuf
+	// serialization.
+	StorageScheme *runtime.Scheme
+
 	DeleteCollectionWorkers int
 	EnableGarbageCollection bool
 
@@ -18,10 +52,15 @@ type EtcdOptions struct {
 	EnableWatchCache bool
 	// Set DefaultWatchCacheSize to zero to disable watch caches for those resources that have no explicit cache size set
 	DefaultWatchCacheSize int
+	// WatchCacheSizes represents override to a given resource
+	WatchCacheSizes map[schema.GroupResource]int
 }
 
 type SimpleRestOptionsFactory struct {
 	Options EtcdOptions
+	// TransformerOverrides holds the value transformers read from the
+	// encryption provider configuration, keyed by resource.
+	TransformerOverrides map[schema.GroupResource]value.Transformer
 }
 
 func NewEtcdOptions(backendConfig *storagebackend.Config) *EtcdOptions {
@@ -33,4 +72,314 @@ func NewEtcdOptions(backendConfig *storagebackend.Config) *EtcdOptions {
 		EnableWatchCache:        true,
 		DefaultWatchCacheSize:   100,
 	}
-}
\ No newline at end of file
+}
+
+func (s *EtcdOptions) Validate() []error {
+	allErrors := []error{}
+	if s.StorageConfig.Type != storagebackend.StorageTypeMemory && len(s.StorageConfig.ServerList) == 0 {
+		allErrors = append(allErrors, fmt.Errorf("--etcd-servers must be specified"))
+	}
+	if _, err := ParseEtcdServersOverrides(s.EtcdServersOverrides); err != nil {
+		allErrors = append(allErrors, err)
+	}
+	if s.StorageSerializer != nil {
+		if _, ok := runtime.SerializerInfoForMediaType(s.StorageSerializer.SupportedMediaTypes(), s.DefaultStorageMediaType); !ok {
+			allErrors = append(allErrors, fmt.Errorf("--storage-media-type %q is not supported", s.DefaultStorageMediaType))
+		}
+		groups := make([]string, 0, len(s.StorageVersions))
+		for group := range s.StorageVersions {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			if err := s.validateStorageMediaType(s.StorageVersions[group]); err != nil {
+				allErrors = append(allErrors, err)
+			}
+		}
+	}
+	return allErrors
+}
+
+// protobufMediaType is the media type of the protobuf serialization of API objects.
+const protobufMediaType = "application/vnd.kubernetes.protobuf"
+
+var protoMarshalerType = reflect.TypeOf((*proto.Marshaler)(nil)).Elem()
+
+// validateStorageMediaType checks that all types of the given storage version
+// can be encoded as DefaultStorageMediaType. Protobuf requires a generated
+// serialization, which not every API group has.
+func (s *EtcdOptions) validateStorageMediaType(version schema.GroupVersion) error {
+	mediaType, _, err := mime.ParseMediaType(s.DefaultStorageMediaType)
+	if err != nil || mediaType != protobufMediaType {
+		return nil
+	}
+	if s.StorageScheme == nil {
+		return fmt.Errorf("--storage-media-type %q is not supported for %v, its types are unknown", s.DefaultStorageMediaType, version)
+	}
+	kinds := []string{}
+	for kind, t := range s.StorageScheme.KnownTypes(version) {
+		if !reflect.PtrTo(t).Implements(protoMarshalerType) {
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) > 0 {
+		sort.Strings(kinds)
+		return fmt.Errorf("--storage-media-type %q is not supported for %v, which has no protobuf serialization of %s",
+			s.DefaultStorageMediaType, version, strings.Join(kinds, ", "))
+	}
+	return nil
+}
+
+// AddEtcdFlags adds flags related to etcd storage for a specific APIServer to the specified FlagSet
+func (s *EtcdOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&s.StorageConfig.Type, "storage-backend", s.StorageConfig.Type,
+		"The storage backend for persistence. Options: 'etcd3' (default), 'memory'.")
+
+	fs.StringSliceVar(&s.StorageConfig.ServerList, "etcd-servers", s.StorageConfig.ServerList,
+		"List of etcd servers to connect with (scheme://ip:port), comma separated.")
+
+	fs.StringSliceVar(&s.EtcdServersOverrides, "etcd-servers-overrides", s.EtcdServersOverrides, ""+
+		"Per-resource etcd servers overrides, comma separated. The individual override "+
+		"format: group/resource#servers, where servers are http://ip:port, semicolon separated.")
+
+	fs.StringVar(&s.DefaultStorageMediaType, "storage-media-type", s.DefaultStorageMediaType, ""+
+		"The media type to use to store objects in storage. Objects stored in another "+
+		"supported media type remain readable and are rewritten on their next update. "+
+		"Options: 'application/json' (default), 'application/vnd.kubernetes.protobuf' (only for "+
+		"API groups whose types all have a protobuf serialization).")
+
+	fs.StringVar(&s.StorageConfig.Prefix, "etcd-prefix", s.StorageConfig.Prefix,
+		"The prefix to prepend to all resource paths in etcd.")
+
+	fs.StringVar(&s.StorageConfig.KeyFile, "etcd-keyfile", s.StorageConfig.KeyFile,
+		"SSL key file used to secure etcd communication.")
+
+	fs.StringVar(&s.StorageConfig.CertFile, "etcd-certfile", s.StorageConfig.CertFile,
+		"SSL certification file used to secure etcd communication.")
+
+	fs.StringVar(&s.StorageConfig.CAFile, "etcd-cafile", s.StorageConfig.CAFile,
+		"SSL Certificate Authority file used to secure etcd communication.")
+
+	fs.BoolVar(&s.StorageConfig.Quorum, "etcd-quorum-read", s.StorageConfig.Quorum,
+		"If true, enable quorum read.")
+
+	fs.DurationVar(&s.StorageConfig.CompactionInterval, "etcd-compaction-interval", s.StorageConfig.CompactionInterval,
+		"The interval of compaction requests. If 0, the compaction request from apiserver is disabled.")
+
+	fs.StringVar(&s.EncryptionProviderConfigFilepath, "experimental-encryption-provider-config", s.EncryptionProviderConfigFilepath,
+		"The file containing configuration for encryption providers to be used for storing secrets in etcd")
+
+	fs.IntVar(&s.DeleteCollectionWorkers, "delete-collection-workers", s.DeleteCollectionWorkers,
+		"Number of workers spawned for DeleteCollection call. These are used to speed up namespace cleanup.")
+
+	fs.BoolVar(&s.EnableGarbageCollection, "enable-garbage-collector", s.EnableGarbageCollection, ""+
+		"Enables the generic garbage collector. MUST be synced with the corresponding flag "+
+		"of the kube-controller-manager.")
+
+	fs.BoolVar(&s.EnableWatchCache, "watch-cache", s.EnableWatchCache,
+		"Enable watch caching in the apiserver")
+
+	fs.IntVar(&s.DefaultWatchCacheSize, "default-watch-cache-size", s.DefaultWatchCacheSize,
+		"Default watch cache size. If zero, watch cache will be disabled for resources that do not have a default watch size set.")
+}
+
+func (s *EtcdOptions) ApplyTo(c *server.Config) error {
+	if err := s.addEtcdHealthEndpoints(c); err != nil {
+		return err
+	}
+	if err := s.addEtcdCompactorHook(c); err != nil {
+		return err
+	}
+	factory := &SimpleRestOptionsFactory{Options: *s}
+	if len(s.EncryptionProviderConfigFilepath) != 0 {
+		transformerOverrides, err := encryptionconfig.GetTransformerOverrides(s.EncryptionProviderConfigFilepath)
+		if err != nil {
+			return err
+		}
+		factory.TransformerOverrides = transformerOverrides
+
+		kmsPluginHealthzChecks, err := encryptionconfig.GetKMSPluginHealthzCheckers(s.EncryptionProviderConfigFilepath)
+		if err != nil {
+			return err
+		}
+		c.HealthzChecks = append(c.HealthzChecks, kmsPluginHealthzChecks...)
+	}
+	c.RESTOptionsGetter = factory
+	return nil
+}
+
+// addEtcdHealthEndpoints adds a healthz check for the default storage backend
+// and one for each backend a resource is moved to by an etcd servers override.
+func (s *EtcdOptions) addEtcdHealthEndpoints(c *server.Config) error {
+	healthCheck, err := storagefactory.CreateHealthCheck(s.StorageConfig)
+	if err != nil {
+		return err
+	}
+	c.HealthzChecks = append(c.HealthzChecks, etcdHealthzCheck("etcd", healthCheck))
+
+	overrides, err := ParseEtcdServersOverrides(s.EtcdServersOverrides)
+	if err != nil {
+		return err
+	}
+	for resource, servers := range overrides {
+		storageConfig := s.StorageConfig
+		storageConfig.ServerList = servers
+		healthCheck, err := storagefactory.CreateHealthCheck(storageConfig)
+		if err != nil {
+			return err
+		}
+		c.HealthzChecks = append(c.HealthzChecks, etcdHealthzCheck(EtcdServersOverrideName(resource), healthCheck))
+	}
+	return nil
+}
+
+// addEtcdCompactorHook adds a post-start hook compacting the default storage
+// backend and every backend a resource is moved to by an etcd servers override.
+// Replicas sharing a backend coordinate so that only one of them compacts per interval.
+func (s *EtcdOptions) addEtcdCompactorHook(c *server.Config) error {
+	if s.StorageConfig.CompactionInterval == 0 {
+		return nil
+	}
+	overrides, err := ParseEtcdServersOverrides(s.EtcdServersOverrides)
+	if err != nil {
+		return err
+	}
+	storageConfigs := []storagebackend.Config{s.StorageConfig}
+	for _, servers := range overrides {
+		storageConfig := s.StorageConfig
+		storageConfig.ServerList = servers
+		storageConfigs = append(storageConfigs, storageConfig)
+	}
+	if c.PostStartHooks == nil {
+		c.PostStartHooks = map[string]server.PostStartHookFunc{}
+	}
+	c.PostStartHooks["start-etcd-compactor"] = func(context server.PostStartHookContext) error {
+		for _, storageConfig := range storageConfigs {
+			if err := storagefactory.StartCompactor(storageConfig, context.StopCh); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func etcdHealthzCheck(name string, healthCheck func() error) healthz.HealthzChecker {
+	return healthz.NamedCheck(name, func(r *http.Request) error {
+		return healthCheck()
+	})
+}
+
+func (f *SimpleRestOptionsFactory) GetRESTOptions(resource schema.GroupResource) (generic.RESTOptions, error) {
+	storageConfig := f.Options.StorageConfig
+	overrides, err := ParseEtcdServersOverrides(f.Options.EtcdServersOverrides)
+	if err != nil {
+		return generic.RESTOptions{}, err
+	}
+	if servers, ok := overrides[resource]; ok {
+		storageConfig.ServerList = servers
+		storageConfig.OverrideName = EtcdServersOverrideName(resource)
+	}
+	storageConfig.GroupResource = resource
+	codec, err := f.Options.storageCodec(resource)
+	if err != nil {
+		return generic.RESTOptions{}, err
+	}
+	storageConfig.Codec = codec
+	if transformer, ok := f.TransformerOverrides[resource]; ok {
+		storageConfig.Transformer = transformer
+	}
+	ret := generic.RESTOptions{
+		StorageConfig:           &storageConfig,
+		Decorator:               generic.UndecoratedStorage,
+		EnableGarbageCollection: f.Options.EnableGarbageCollection,
+		DeleteCollectionWorkers: f.Options.DeleteCollectionWorkers,
+		ResourcePrefix:          resource.Group + "/" + resource.Resource,
+	}
+	if f.Options.EnableWatchCache {
+		cacheSize, ok := f.Options.WatchCacheSizes[resource]
+		if !ok {
+			cacheSize = f.Options.DefaultWatchCacheSize
+		}
+		ret.Decorator = genericregistry.StorageWithCacher(cacheSize)
+	}
+	return ret, nil
+}
+
+// storageCodec returns the codec the objects of the given resource are stored with.
+func (s *EtcdOptions) storageCodec(resource schema.GroupResource) (runtime.Codec, error) {
+	version, ok := s.StorageVersions[resource.Group]
+	if s.StorageSerializer == nil || !ok {
+		return s.StorageConfig.Codec, nil
+	}
+	if err := s.validateStorageMediaType(version); err != nil {
+		return nil, err
+	}
+	codec, err := serverstorage.NewStorageCodec(serverstorage.StorageCodecConfig{
+		StorageMediaType:  s.DefaultStorageMediaType,
+		StorageSerializer: s.StorageSerializer,
+		StorageVersion:    version,
+		MemoryVersion:     schema.GroupVersion{Group: version.Group, Version: runtime.APIVersionInternal},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create storage codec for %v: %v", resource, err)
+	}
+	return codec, nil
+}
+
+// ParseWatchCacheSizes turns a list of cache size values into a map of group resources
+// to requested sizes. Each entry has the form "resource#size" or
+// "resource.group#size".
+func ParseWatchCacheSizes(cacheSizes []string) (map[schema.GroupResource]int, error) {
+	watchCacheSizes := make(map[schema.GroupResource]int)
+	for _, c := range cacheSizes {
+		tokens := strings.Split(c, "#")
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("invalid value of watch cache size: %s", c)
+		}
+
+		size, err := strconv.Atoi(tokens[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size of watch cache size: %s", c)
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("watch cache size cannot be negative: %s", c)
+		}
+
+		watchCacheSizes[schema.ParseGroupResource(tokens[0])] = size
+	}
+	return watchCacheSizes, nil
+}
+
+// ParseEtcdServersOverrides turns a list of etcd servers overrides into a map
+// of group resources to the servers storing them. Each entry has the form
+// "group/resource#servers", where servers are semicolon separated and the
+// group is empty for the legacy API group.
+func ParseEtcdServersOverrides(etcdServersOverrides []string) (map[schema.GroupResource][]string, error) {
+	overrides := make(map[schema.GroupResource][]string)
+	for _, override := range etcdServersOverrides {
+		tokens := strings.Split(override, "#")
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("--etcd-servers-overrides invalid, must be of format: group/resource#servers, where servers are URLs, semicolon separated")
+		}
+
+		apiresource := strings.Split(tokens[0], "/")
+		if len(apiresource) != 2 {
+			return nil, fmt.Errorf("--etcd-servers-overrides invalid, must be of format: group/resource#servers, where servers are URLs, semicolon separated")
+		}
+
+		if len(tokens[1]) == 0 {
+			return nil, fmt.Errorf("--etcd-servers-overrides invalid, no servers given for %s", tokens[0])
+		}
+		servers := strings.Split(tokens[1], ";")
+
+		overrides[schema.GroupResource{Group: apiresource[0], Resource: apiresource[1]}] = servers
+	}
+	return overrides, nil
+}
+
+// EtcdServersOverrideName names the servers the given resource is moved to by
+// an etcd servers override, in healthz checks and storage metrics.
+func EtcdServersOverrideName(resource schema.GroupResource) string {
+	return "etcd-" + resource.String()
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	apimetrics "github.com/HuZhou/apiserver/pkg/endpoints/metrics"
+	"github.com/HuZhou/apiserver/pkg/server/mux"
+	storagemetrics "github.com/HuZhou/apiserver/pkg/storage/metrics"
+)
+
+// DefaultMetrics installs the default prometheus metrics handler
+type DefaultMetrics struct{}
+
+// Install adds the DefaultMetrics handler
+func (m DefaultMetrics) Install(c *mux.PathRecorderMux) {
+	register()
+	c.Handle("/metrics", prometheus.Handler())
+}
+
+// register apiserver and storage metrics
+func register() {
+	apimetrics.Register()
+	storagemetrics.Register()
+}
@@ -0,0 +1,132 @@
+package etcd3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	etcdrpc "github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/integration"
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestCompact(t *testing.T) {
+	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+	client := cluster.RandClient()
+	ctx := context.Background()
+
+	putResp, err := client.Put(ctx, "/somekey", "data")
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	putResp1, err := client.Put(ctx, "/somekey", "data2")
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if _, _, err := compact(ctx, client, 0, putResp1.Header.Revision); err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	if _, err := client.Get(ctx, "/somekey", clientv3.WithRev(putResp.Header.Revision)); err != etcdrpc.ErrCompacted {
+		t.Errorf("expected a compacted error reading an old revision, got %v", err)
+	}
+}
+
+// TestCompactConflict runs compactors that share the compact time stored in
+// etcd and checks that only the one that agrees with it wins the CAS.
+func TestCompactConflict(t *testing.T) {
+	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+	client := cluster.RandClient()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// removeKey deletes the compact time from etcd before compacting
+		removeKey      bool
+		localTime      int64
+		expectTime     int64
+		expectCASWrite bool
+	}{
+		{name: "first compactor wins", localTime: 0, expectTime: 1, expectCASWrite: true},
+		{name: "stale compactor catches up", localTime: 0, expectTime: 1},
+		{name: "up to date compactor wins", localTime: 1, expectTime: 2, expectCASWrite: true},
+		{name: "compactor ahead of a removed key starts over", removeKey: true, localTime: 2, expectTime: 0},
+		{name: "compactor after a removed key wins", localTime: 0, expectTime: 1, expectCASWrite: true},
+	}
+
+	for _, tt := range tests {
+		// every compaction needs a revision newer than the last one
+		putResp, err := client.Put(ctx, "/somekey", "data")
+		if err != nil {
+			t.Fatalf("%s: Put failed: %v", tt.name, err)
+		}
+		rev := putResp.Header.Revision
+		if tt.removeKey {
+			if _, err := client.Delete(ctx, compactRevKey); err != nil {
+				t.Fatalf("%s: Delete failed: %v", tt.name, err)
+			}
+		}
+		before, err := client.Get(ctx, compactRevKey)
+		if err != nil {
+			t.Fatalf("%s: Get failed: %v", tt.name, err)
+		}
+
+		curTime, _, err := compact(ctx, client, tt.localTime, rev)
+		if err != nil {
+			t.Errorf("%s: compact failed: %v", tt.name, err)
+			continue
+		}
+		if curTime != tt.expectTime {
+			t.Errorf("%s: expected compact time %d, got %d", tt.name, tt.expectTime, curTime)
+		}
+
+		after, err := client.Get(ctx, compactRevKey)
+		if err != nil {
+			t.Fatalf("%s: Get failed: %v", tt.name, err)
+		}
+		if wrote := after.Header.Revision != before.Header.Revision; wrote != tt.expectCASWrite {
+			t.Errorf("%s: expected the compact time to be written %t, got %t", tt.name, tt.expectCASWrite, wrote)
+		}
+	}
+}
+
+func TestStartCompactorReleasesEndpoints(t *testing.T) {
+	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	defer cluster.Terminate(t)
+	client := cluster.RandClient()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	StartCompactor(ctx, client, time.Hour)
+	if !hasCompactor(client.Endpoints()) {
+		t.Fatalf("expected a compactor to be registered for %v", client.Endpoints())
+	}
+
+	cancel()
+	if err := wait.Poll(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return !hasCompactor(client.Endpoints()), nil
+	}); err != nil {
+		t.Fatalf("expected the endpoints to be released once the compactor stopped")
+	}
+
+	// a new compactor may take over the cluster
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	StartCompactor(ctx, client, time.Hour)
+	if !hasCompactor(client.Endpoints()) {
+		t.Errorf("expected a new compactor to be registered for %v", client.Endpoints())
+	}
+}
+
+func hasCompactor(endpoints []string) bool {
+	endpointsMapMu.Lock()
+	defer endpointsMapMu.Unlock()
+	for _, ep := range endpoints {
+		if _, ok := endpointsMap[ep]; ok {
+			return true
+		}
+	}
+	return false
+}
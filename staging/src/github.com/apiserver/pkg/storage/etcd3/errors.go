@@ -0,0 +1,14 @@
+package etcd3
+
+import (
+	etcdrpc "github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+func interpretWatchError(err error) error {
+	switch {
+	case err == etcdrpc.ErrCompacted:
+		return errors.NewGone("The resourceVersion for the provided watch is too old.")
+	}
+	return err
+}
@@ -0,0 +1,374 @@
+package etcd3
+
+import (
+	"testing"
+
+	"github.com/coreos/etcd/integration"
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+func init() {
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+}
+
+// testSetup starts an embedded single member etcd cluster and returns a store
+// backed by it. The caller terminates the cluster.
+func testSetup(t *testing.T) (context.Context, *store, *integration.ClusterV3) {
+	cluster := integration.NewClusterV3(t, &integration.ClusterConfig{Size: 1})
+	s := newStore(cluster.RandClient(), true, codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion), "/registry", value.IdentityTransformer)
+	return context.Background(), s, cluster
+}
+
+func testCreate(ctx context.Context, t *testing.T, s storage.Interface, key string, obj *testapigroup.Carp) *testapigroup.Carp {
+	out := &testapigroup.Carp{}
+	if err := s.Create(ctx, key, obj, out, 0); err != nil {
+		t.Fatalf("Create of %s failed: %v", key, err)
+	}
+	return out
+}
+
+func isExpired(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	return ok && status.Status().Reason == metav1.StatusReasonExpired && status.Status().Code == 410
+}
+
+func TestCreate(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	testCreate(ctx, t, s, "/carps/existing", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "existing"}})
+
+	tests := []struct {
+		name      string
+		key       string
+		obj       *testapigroup.Carp
+		expectErr func(error) bool
+	}{
+		{
+			name: "new key",
+			key:  "/carps/foo",
+			obj:  &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+		},
+		{
+			name:      "existing key",
+			key:       "/carps/existing",
+			obj:       &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "existing"}},
+			expectErr: storage.IsNodeExist,
+		},
+		{
+			name:      "resource version set",
+			key:       "/carps/bar",
+			obj:       &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "bar", ResourceVersion: "1"}},
+			expectErr: func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, tt := range tests {
+		out := &testapigroup.Carp{}
+		err := s.Create(ctx, tt.key, tt.obj, out, 0)
+		if tt.expectErr != nil {
+			if !tt.expectErr(err) {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Create failed: %v", tt.name, err)
+		}
+		if out.Name != tt.obj.Name || len(out.ResourceVersion) == 0 {
+			t.Errorf("%s: expected the created object with a resource version, got %#v", tt.name, out)
+		}
+
+		// the object is stored under the prefix, without its resource version
+		resp, err := cluster.RandClient().Get(ctx, "/registry"+tt.key)
+		if err != nil {
+			t.Fatalf("%s: Get from etcd failed: %v", tt.name, err)
+		}
+		if len(resp.Kvs) != 1 {
+			t.Fatalf("%s: expected the key to be stored, got %v", tt.name, resp.Kvs)
+		}
+		stored := &testapigroup.Carp{}
+		if err := runtime.DecodeInto(codecs.UniversalDecoder(testapigroup.SchemeGroupVersion), resp.Kvs[0].Value, stored); err != nil {
+			t.Fatalf("%s: failed to decode the stored object: %v", tt.name, err)
+		}
+		if len(stored.ResourceVersion) != 0 {
+			t.Errorf("%s: expected no resource version to be stored, got %q", tt.name, stored.ResourceVersion)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	created := testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+
+	tests := []struct {
+		key            string
+		ignoreNotFound bool
+		expectNotFound bool
+		expectObj      *testapigroup.Carp
+	}{
+		{key: "/carps/foo", expectObj: created},
+		{key: "/carps/bar", expectNotFound: true},
+		{key: "/carps/bar", ignoreNotFound: true, expectObj: &testapigroup.Carp{}},
+	}
+
+	for i, tt := range tests {
+		out := &testapigroup.Carp{}
+		err := s.Get(ctx, tt.key, "", out, tt.ignoreNotFound)
+		if tt.expectNotFound {
+			if !storage.IsNotFound(err) {
+				t.Errorf("#%d: expected a not found error, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("#%d: Get failed: %v", i, err)
+		}
+		if out.Name != tt.expectObj.Name || out.ResourceVersion != tt.expectObj.ResourceVersion {
+			t.Errorf("#%d: expected %s/%s, got %s/%s", i, tt.expectObj.Name, tt.expectObj.ResourceVersion, out.Name, out.ResourceVersion)
+		}
+	}
+}
+
+func TestGuaranteedUpdate(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "A"}})
+
+	tests := []struct {
+		name           string
+		key            string
+		ignoreNotFound bool
+		precondition   *storage.Preconditions
+		expectErr      func(error) bool
+		expectHost     string
+	}{
+		{
+			name:       "update existing",
+			key:        "/carps/foo",
+			expectHost: "foo-host",
+		},
+		{
+			name:      "missing key",
+			key:       "/carps/bar",
+			expectErr: storage.IsNotFound,
+		},
+		{
+			name:           "missing key, ignoreNotFound",
+			key:            "/carps/baz",
+			ignoreNotFound: true,
+			expectHost:     "baz-host",
+		},
+		{
+			name:         "uid precondition mismatch",
+			key:          "/carps/foo",
+			precondition: storage.NewUIDPreconditions("B"),
+			expectErr:    storage.IsInvalidObj,
+		},
+	}
+
+	for _, tt := range tests {
+		out := &testapigroup.Carp{}
+		host := tt.expectHost
+		err := s.GuaranteedUpdate(ctx, tt.key, out, tt.ignoreNotFound, tt.precondition,
+			storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+				carp := obj.(*testapigroup.Carp)
+				carp.Spec.Hostname = host
+				return carp, nil
+			}))
+		if tt.expectErr != nil {
+			if !tt.expectErr(err) {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: GuaranteedUpdate failed: %v", tt.name, err)
+		}
+		if out.Spec.Hostname != tt.expectHost {
+			t.Errorf("%s: expected host %q, got %q", tt.name, tt.expectHost, out.Spec.Hostname)
+		}
+	}
+}
+
+func TestGuaranteedUpdateRetriesOnConflict(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	created := testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+
+	tests := []struct {
+		name       string
+		suggestion *testapigroup.Carp
+	}{
+		{name: "read from etcd"},
+		// a stale suggestion conflicts like a concurrent write does
+		{name: "stale suggestion", suggestion: created},
+	}
+
+	for _, tt := range tests {
+		attempts := 0
+		out := &testapigroup.Carp{}
+		var suggestion []runtime.Object
+		if tt.suggestion != nil {
+			suggestion = append(suggestion, tt.suggestion.DeepCopyObject())
+		}
+		err := s.GuaranteedUpdate(ctx, "/carps/foo", out, false, nil,
+			storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+				attempts++
+				if attempts == 1 {
+					// a concurrent writer modifies the object underneath the first attempt
+					concurrent := &testapigroup.Carp{}
+					if err := s.GuaranteedUpdate(ctx, "/carps/foo", concurrent, false, nil,
+						storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+							carp := obj.(*testapigroup.Carp)
+							carp.Labels = map[string]string{"concurrent": tt.name}
+							return carp, nil
+						})); err != nil {
+						t.Fatalf("%s: concurrent update failed: %v", tt.name, err)
+					}
+				}
+				carp := obj.(*testapigroup.Carp)
+				carp.Spec.Hostname = tt.name
+				return carp, nil
+			}), suggestion...)
+		if err != nil {
+			t.Fatalf("%s: GuaranteedUpdate failed: %v", tt.name, err)
+		}
+		if attempts != 2 {
+			t.Errorf("%s: expected the update to be retried once, got %d attempts", tt.name, attempts)
+		}
+		if out.Spec.Hostname != tt.name || out.Labels["concurrent"] != tt.name {
+			t.Errorf("%s: expected both updates to be applied, got %#v", tt.name, out)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	for _, name := range []string{"a", "b", "c"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	// a key outside of the listed prefix
+	testCreate(ctx, t, s, "/carpsx/d", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "d"}})
+
+	tests := []struct {
+		name           string
+		limit          int64
+		expectPages    [][]string
+		expectContinue bool
+	}{
+		{name: "everything", expectPages: [][]string{{"a", "b", "c"}}},
+		{name: "limit larger than the list", limit: 5, expectPages: [][]string{{"a", "b", "c"}}},
+		{name: "two pages", limit: 2, expectPages: [][]string{{"a", "b"}, {"c"}}},
+		{name: "one item per page", limit: 1, expectPages: [][]string{{"a"}, {"b"}, {"c"}}},
+	}
+
+	for _, tt := range tests {
+		pred := storage.Everything
+		pred.Limit = tt.limit
+		var resourceVersion string
+		for i, expected := range tt.expectPages {
+			list := &testapigroup.CarpList{}
+			if err := s.List(ctx, "/carps", "", pred, list); err != nil {
+				t.Fatalf("%s: List of page %d failed: %v", tt.name, i, err)
+			}
+			names := []string{}
+			for _, item := range list.Items {
+				names = append(names, item.Name)
+			}
+			if len(names) != len(expected) {
+				t.Fatalf("%s: expected page %d to be %v, got %v", tt.name, i, expected, names)
+			}
+			for j := range names {
+				if names[j] != expected[j] {
+					t.Fatalf("%s: expected page %d to be %v, got %v", tt.name, i, expected, names)
+				}
+			}
+
+			last := i == len(tt.expectPages)-1
+			if last != (len(list.Continue) == 0) {
+				t.Errorf("%s: unexpected continue token %q on page %d", tt.name, list.Continue, i)
+			}
+			if i == 0 {
+				resourceVersion = list.ResourceVersion
+			} else if list.ResourceVersion != resourceVersion {
+				t.Errorf("%s: expected page %d to be served at %s, got %s", tt.name, i, resourceVersion, list.ResourceVersion)
+			}
+			pred.Continue = list.Continue
+
+			if i == 0 {
+				// writes after the first page are not visible to the continuation
+				testCreate(ctx, t, s, "/carps/z", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "z"}})
+			}
+		}
+		if err := s.Delete(ctx, "/carps/z", &testapigroup.Carp{}, nil); err != nil {
+			t.Fatalf("%s: Delete failed: %v", tt.name, err)
+		}
+	}
+
+	pred := storage.Everything
+	pred.Limit = 1
+	pred.Continue = "not-a-token"
+	if err := s.List(ctx, "/carps", "", pred, &testapigroup.CarpList{}); !apierrors.IsBadRequest(err) {
+		t.Errorf("expected a bad request for an invalid token, got %v", err)
+	}
+}
+
+func TestListCompacted(t *testing.T) {
+	ctx, s, cluster := testSetup(t)
+	defer cluster.Terminate(t)
+	for _, name := range []string{"a", "b", "c"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	pred := storage.Everything
+	pred.Limit = 1
+	first := &testapigroup.CarpList{}
+	if err := s.List(ctx, "/carps", "", pred, first); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	// compact away the revision of the first page
+	last := testCreate(ctx, t, s, "/carps/d", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "d"}})
+	rev, err := s.versioner.ObjectResourceVersion(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster.RandClient().Compact(ctx, int64(rev)); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		resourceVersion string
+		continueToken   string
+	}{
+		{name: "continue", continueToken: first.Continue},
+		{name: "resource version", resourceVersion: first.ResourceVersion},
+	}
+
+	for _, tt := range tests {
+		pred := storage.Everything
+		pred.Limit = 1
+		pred.Continue = tt.continueToken
+		err := s.List(ctx, "/carps", tt.resourceVersion, pred, &testapigroup.CarpList{})
+		if !isExpired(err) {
+			t.Errorf("%s: expected a 410 resource expired error, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultHistorySize is the number of events a Backend keeps to serve
+// watches that start from an older resource version.
+const defaultHistorySize = 1000
+
+type keyValue struct {
+	value          []byte
+	createRevision int64
+	modRevision    int64
+}
+
+type event struct {
+	key       string
+	value     []byte
+	prevValue []byte
+	// prev is the value replaced or removed by the event, nil for creations.
+	prev      *keyValue
+	rev       int64
+	isDeleted bool
+	isCreated bool
+}
+
+// Backend is an in-process, revisioned key-value space. Like etcd, every
+// write bumps a single monotonically increasing revision, and every write
+// is fanned out to the watchers registered on the affected key.
+type Backend struct {
+	lock sync.RWMutex
+	// rev is the revision of the last write.
+	rev   int64
+	items map[string]*keyValue
+
+	// history is a ring buffer of the most recent events.
+	history      []*event
+	historyStart int
+	historyLen   int
+
+	watchers      map[int]*watcher
+	nextWatcherID int
+}
+
+// NewBackend returns an empty Backend.
+func NewBackend() *Backend {
+	return &Backend{
+		items:    map[string]*keyValue{},
+		history:  make([]*event, defaultHistorySize),
+		watchers: map[int]*watcher{},
+	}
+}
+
+// get returns the value stored at key along with the current revision.
+func (b *Backend) get(key string) (*keyValue, int64) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	kv, ok := b.items[key]
+	if !ok {
+		return nil, b.rev
+	}
+	return kv, b.rev
+}
+
+type keyedValue struct {
+	key string
+	*keyValue
+}
+
+// list returns every value whose key starts with prefix, ordered by key,
+// along with the current revision.
+func (b *Backend) list(prefix string) ([]keyedValue, int64) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.listLocked(prefix), b.rev
+}
+
+func (b *Backend) listLocked(prefix string) []keyedValue {
+	return sortedKeyValues(b.items, prefix)
+}
+
+// listAt returns every value whose key starts with prefix as it was at
+// revision rev, ordered by key, along with the revision served. A rev of
+// zero, or one not older than the last write, reads the current state. It
+// returns false if the history no longer covers rev.
+func (b *Backend) listAt(prefix string, rev int64) ([]keyedValue, int64, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if rev == 0 || rev >= b.rev {
+		return b.listLocked(prefix), b.rev, true
+	}
+	oldest := b.rev + 1
+	if b.historyLen > 0 {
+		oldest = b.history[b.historyStart].rev
+	}
+	if rev+1 < oldest {
+		return nil, 0, false
+	}
+
+	// Undo the events that happened after rev, newest first.
+	items := map[string]*keyValue{}
+	for key, kv := range b.items {
+		if strings.HasPrefix(key, prefix) {
+			items[key] = kv
+		}
+	}
+	for i := b.historyLen - 1; i >= 0; i-- {
+		e := b.history[(b.historyStart+i)%len(b.history)]
+		if e.rev <= rev {
+			break
+		}
+		if !strings.HasPrefix(e.key, prefix) {
+			continue
+		}
+		if e.prev == nil {
+			delete(items, e.key)
+		} else {
+			items[e.key] = e.prev
+		}
+	}
+	return sortedKeyValues(items, prefix), rev, true
+}
+
+func sortedKeyValues(items map[string]*keyValue, prefix string) []keyedValue {
+	result := []keyedValue{}
+	for key, kv := range items {
+		if strings.HasPrefix(key, prefix) {
+			result = append(result, keyedValue{key: key, keyValue: kv})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key < result[j].key })
+	return result
+}
+
+// create stores value at key if and only if the key does not exist yet.
+// It returns the revision of the write and whether it happened.
+func (b *Backend) create(key string, value []byte) (int64, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.items[key]; ok {
+		return 0, false
+	}
+	b.rev++
+	b.items[key] = &keyValue{value: value, createRevision: b.rev, modRevision: b.rev}
+	b.notifyLocked(&event{key: key, value: value, rev: b.rev, isCreated: true})
+	return b.rev, true
+}
+
+// compareAndPut stores value at key if the key was last modified at
+// expectedRev. An expectedRev of zero requires the key to be absent.
+// It returns the revision of the write and whether it happened.
+func (b *Backend) compareAndPut(key string, expectedRev int64, value []byte) (int64, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	existing, ok := b.items[key]
+	switch {
+	case !ok && expectedRev != 0:
+		return 0, false
+	case ok && existing.modRevision != expectedRev:
+		return 0, false
+	}
+	b.rev++
+	e := &event{key: key, value: value, rev: b.rev}
+	kv := &keyValue{value: value, createRevision: b.rev, modRevision: b.rev}
+	if ok {
+		kv.createRevision = existing.createRevision
+		e.prevValue = existing.value
+		e.prev = existing
+	} else {
+		e.isCreated = true
+	}
+	b.items[key] = kv
+	b.notifyLocked(e)
+	return b.rev, true
+}
+
+// compareAndDelete removes key if it was last modified at expectedRev. An
+// expectedRev of zero removes the key unconditionally. It returns the removed
+// value and whether the deletion happened; the value is nil if the key did
+// not exist.
+func (b *Backend) compareAndDelete(key string, expectedRev int64) (*keyValue, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	existing, ok := b.items[key]
+	if !ok {
+		return nil, false
+	}
+	if expectedRev != 0 && existing.modRevision != expectedRev {
+		return existing, false
+	}
+	b.rev++
+	delete(b.items, key)
+	b.notifyLocked(&event{key: key, prevValue: existing.value, prev: existing, rev: b.rev, isDeleted: true})
+	return existing, true
+}
+
+// notifyLocked records the event in the history and hands it to every
+// interested watcher. Watchers that cannot keep up are terminated.
+func (b *Backend) notifyLocked(e *event) {
+	if b.historyLen < len(b.history) {
+		b.history[(b.historyStart+b.historyLen)%len(b.history)] = e
+		b.historyLen++
+	} else {
+		b.history[b.historyStart] = e
+		b.historyStart = (b.historyStart + 1) % len(b.history)
+	}
+
+	for id, w := range b.watchers {
+		if !w.interested(e.key) {
+			continue
+		}
+		select {
+		case w.incoming <- e:
+		default:
+			// The watcher is too slow to follow the stream of events; stop
+			// it so that its client relists instead of missing events.
+			delete(b.watchers, id)
+			w.lagged = true
+			close(w.incoming)
+		}
+	}
+}
+
+// register adds w to the set of watchers. Events that happened after
+// startRev are delivered first; if startRev is zero the current state is
+// delivered as a sequence of synthetic creations. It returns false if the
+// history no longer covers startRev.
+func (b *Backend) register(w *watcher, startRev int64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var initial []*event
+	if startRev == 0 {
+		for _, kv := range b.listLocked(w.key) {
+			if !w.interested(kv.key) {
+				continue
+			}
+			initial = append(initial, &event{key: kv.key, value: kv.value, rev: kv.modRevision, isCreated: true})
+		}
+	} else if startRev < b.rev {
+		oldest := b.rev + 1
+		if b.historyLen > 0 {
+			oldest = b.history[b.historyStart].rev
+		}
+		if startRev+1 < oldest {
+			return false
+		}
+		for i := 0; i < b.historyLen; i++ {
+			e := b.history[(b.historyStart+i)%len(b.history)]
+			if e.rev > startRev && w.interested(e.key) {
+				initial = append(initial, e)
+			}
+		}
+	}
+	w.initial = initial
+
+	b.nextWatcherID++
+	w.id = b.nextWatcherID
+	b.watchers[w.id] = w
+	return true
+}
+
+// unregister removes w from the set of watchers, if it is still registered.
+func (b *Backend) unregister(w *watcher) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.watchers[w.id]; ok {
+		delete(b.watchers, w.id)
+		close(w.incoming)
+	}
+}
@@ -0,0 +1,391 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/etcd"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+type store struct {
+	backend     *Backend
+	codec       runtime.Codec
+	versioner   storage.Versioner
+	transformer value.Transformer
+	pathPrefix  string
+}
+
+type objState struct {
+	obj   runtime.Object
+	meta  *storage.ResponseMeta
+	rev   int64
+	data  []byte
+	stale bool
+}
+
+// New returns an implementation of storage.Interface that keeps objects in
+// the given Backend. Stores sharing a Backend share its revision sequence.
+func New(backend *Backend, codec runtime.Codec, prefix string, transformer value.Transformer) storage.Interface {
+	return &store{
+		backend:     backend,
+		codec:       codec,
+		versioner:   etcd.APIObjectVersioner{},
+		transformer: transformer,
+		pathPrefix:  path.Join("/", prefix),
+	}
+}
+
+// Versioner implements storage.Interface.Versioner.
+func (s *store) Versioner() storage.Versioner {
+	return s.versioner
+}
+
+// Get implements storage.Interface.Get.
+func (s *store) Get(ctx context.Context, key string, resourceVersion string, out runtime.Object, ignoreNotFound bool) error {
+	key = path.Join(s.pathPrefix, key)
+	kv, _ := s.backend.get(key)
+	if kv == nil {
+		if ignoreNotFound {
+			return runtime.SetZeroValue(out)
+		}
+		return storage.NewKeyNotFoundError(key, 0)
+	}
+	data, _, err := s.transformer.TransformFromStorage(kv.value, value.DefaultContext(key))
+	if err != nil {
+		return storage.NewInternalError(err.Error())
+	}
+	return decode(s.codec, s.versioner, data, out, kv.modRevision)
+}
+
+// errTTLNotSupported is returned for writes that ask for a TTL.
+var errTTLNotSupported = errors.New("TTLs are not supported by the memory storage backend")
+
+// Create implements storage.Interface.Create. TTLs are not supported.
+func (s *store) Create(ctx context.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	if ttl != 0 {
+		return errTTLNotSupported
+	}
+	if version, err := s.versioner.ObjectResourceVersion(obj); err == nil && version != 0 {
+		return errors.New("resourceVersion should not be set on objects to be created")
+	}
+	if err := s.versioner.PrepareObjectForStorage(obj); err != nil {
+		return fmt.Errorf("PrepareObjectForStorage failed: %v", err)
+	}
+	data, err := runtime.Encode(s.codec, obj)
+	if err != nil {
+		return err
+	}
+	key = path.Join(s.pathPrefix, key)
+
+	newData, err := s.transformer.TransformToStorage(data, value.DefaultContext(key))
+	if err != nil {
+		return storage.NewInternalError(err.Error())
+	}
+
+	rev, ok := s.backend.create(key, newData)
+	if !ok {
+		return storage.NewKeyExistsError(key, 0)
+	}
+	if out != nil {
+		return decode(s.codec, s.versioner, data, out, rev)
+	}
+	return nil
+}
+
+// Delete implements storage.Interface.Delete.
+func (s *store) Delete(ctx context.Context, key string, out runtime.Object, preconditions *storage.Preconditions) error {
+	v, err := conversion.EnforcePtr(out)
+	if err != nil {
+		panic("unable to convert output object to pointer")
+	}
+	key = path.Join(s.pathPrefix, key)
+	for {
+		kv, _ := s.backend.get(key)
+		if kv == nil {
+			return storage.NewKeyNotFoundError(key, 0)
+		}
+		origState, err := s.getState(kv, key, v, false)
+		if err != nil {
+			return err
+		}
+		if err := preconditions.Check(key, origState.obj); err != nil {
+			return err
+		}
+		if _, ok := s.backend.compareAndDelete(key, origState.rev); !ok {
+			glog.V(4).Infof("deletion of %s failed because of a conflict, going to retry", key)
+			continue
+		}
+		return decode(s.codec, s.versioner, origState.data, out, origState.rev)
+	}
+}
+
+// GuaranteedUpdate implements storage.Interface.GuaranteedUpdate. TTLs are
+// not supported.
+func (s *store) GuaranteedUpdate(
+	ctx context.Context, key string, out runtime.Object, ignoreNotFound bool,
+	preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc, suggestion ...runtime.Object) error {
+	v, err := conversion.EnforcePtr(out)
+	if err != nil {
+		panic("unable to convert output object to pointer")
+	}
+	key = path.Join(s.pathPrefix, key)
+
+	for {
+		kv, _ := s.backend.get(key)
+		origState, err := s.getState(kv, key, v, ignoreNotFound)
+		if err != nil {
+			return err
+		}
+		err = s.tryUpdateOnce(key, origState, out, preconditions, tryUpdate)
+		if storage.IsConflict(err) {
+			glog.V(4).Infof("GuaranteedUpdate of %s failed because of a conflict, going to retry", key)
+			continue
+		}
+		return err
+	}
+}
+
+// tryUpdateOnce applies tryUpdate to origState and writes the result if the
+// key has not been modified since origState was read. It returns a
+// ResourceVersionConflicts error if it has.
+func (s *store) tryUpdateOnce(key string, origState *objState, out runtime.Object,
+	preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc) error {
+	if err := preconditions.Check(key, origState.obj); err != nil {
+		return err
+	}
+
+	ret, ttl, err := tryUpdate(origState.obj, *origState.meta)
+	if err != nil {
+		return err
+	}
+	if ttl != nil && *ttl != 0 {
+		return errTTLNotSupported
+	}
+	if err := s.versioner.PrepareObjectForStorage(ret); err != nil {
+		return fmt.Errorf("PrepareObjectForStorage failed: %v", err)
+	}
+
+	data, err := runtime.Encode(s.codec, ret)
+	if err != nil {
+		return err
+	}
+	if !origState.stale && bytes.Equal(data, origState.data) {
+		return decode(s.codec, s.versioner, origState.data, out, origState.rev)
+	}
+
+	newData, err := s.transformer.TransformToStorage(data, value.DefaultContext(key))
+	if err != nil {
+		return storage.NewInternalError(err.Error())
+	}
+	rev, ok := s.backend.compareAndPut(key, origState.rev, newData)
+	if !ok {
+		return storage.NewResourceVersionConflictsError(key, origState.rev)
+	}
+	return decode(s.codec, s.versioner, data, out, rev)
+}
+
+// GetToList implements storage.Interface.GetToList.
+func (s *store) GetToList(ctx context.Context, key string, resourceVersion string, pred storage.SelectionPredicate, listObj runtime.Object) error {
+	listPtr, err := meta.GetItemsPtr(listObj)
+	if err != nil {
+		return err
+	}
+	key = path.Join(s.pathPrefix, key)
+
+	kv, rev := s.backend.get(key)
+	if kv != nil {
+		if err := s.appendListItem(listPtr, key, kv.value, kv.modRevision, pred); err != nil {
+			return err
+		}
+	}
+	// update version with cluster level revision
+	return s.versioner.UpdateList(listObj, uint64(rev), "")
+}
+
+// List implements storage.Interface.List. Limited lists and their
+// continuations are served from the revision of the first page, as long as
+// the history of the backend still covers it.
+func (s *store) List(ctx context.Context, key, resourceVersion string, pred storage.SelectionPredicate, listObj runtime.Object) error {
+	listPtr, err := meta.GetItemsPtr(listObj)
+	if err != nil {
+		return err
+	}
+	v, err := conversion.EnforcePtr(listPtr)
+	if err != nil || v.Kind() != reflect.Slice {
+		panic("need ptr to slice")
+	}
+	key = path.Join(s.pathPrefix, key)
+	// We need to make sure the key ended with "/" so that we only get children "directories".
+	if !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	keyPrefix := key
+
+	var fromRev int64
+	switch {
+	case len(pred.Continue) > 0:
+		continueKey, continueRV, err := storage.DecodeContinue(pred.Continue, keyPrefix)
+		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid continue token: %v", err))
+		}
+		if len(resourceVersion) > 0 && resourceVersion != "0" {
+			return apierrors.NewBadRequest("specifying resource version is not allowed when using continue")
+		}
+		key, fromRev = continueKey, continueRV
+	case pred.Limit > 0 && len(resourceVersion) > 0:
+		fromRev, err = strconv.ParseInt(resourceVersion, 10, 64)
+		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid resource version: %v", err))
+		}
+	}
+
+	kvs, rev, ok := s.backend.listAt(keyPrefix, fromRev)
+	if !ok {
+		if len(pred.Continue) > 0 {
+			return apierrors.NewResourceExpired("The provided continue parameter is too old to display a consistent list result. You must start a new list without the continue parameter.")
+		}
+		return apierrors.NewResourceExpired("The resourceVersion for the provided list is too old.")
+	}
+	var lastKey string
+	var hasMore bool
+	for _, kv := range kvs {
+		if kv.key < key {
+			continue
+		}
+		if pred.Limit > 0 && int64(v.Len()) >= pred.Limit {
+			hasMore = true
+			break
+		}
+		lastKey = kv.key
+		if err := s.appendListItem(listPtr, kv.key, kv.value, kv.modRevision, pred); err != nil {
+			return err
+		}
+	}
+	if hasMore {
+		// the next page starts immediately after the last key
+		next, err := storage.EncodeContinue(lastKey+"\x00", keyPrefix, rev)
+		if err != nil {
+			return err
+		}
+		return s.versioner.UpdateList(listObj, uint64(rev), next)
+	}
+	// update version with cluster level revision
+	return s.versioner.UpdateList(listObj, uint64(rev), "")
+}
+
+// Watch implements storage.Interface.Watch.
+func (s *store) Watch(ctx context.Context, key string, resourceVersion string, pred storage.SelectionPredicate) (watch.Interface, error) {
+	return s.watch(ctx, key, resourceVersion, pred, false)
+}
+
+// WatchList implements storage.Interface.WatchList.
+func (s *store) WatchList(ctx context.Context, key string, resourceVersion string, pred storage.SelectionPredicate) (watch.Interface, error) {
+	return s.watch(ctx, key, resourceVersion, pred, true)
+}
+
+func (s *store) watch(ctx context.Context, key string, resourceVersion string, pred storage.SelectionPredicate, recursive bool) (watch.Interface, error) {
+	rev, err := s.versioner.ParseWatchResourceVersion(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	key = path.Join(s.pathPrefix, key)
+	w := newWatcher(ctx, s, key, recursive, pred)
+	if !s.backend.register(w, int64(rev)) {
+		return goneWatch(rev), nil
+	}
+	go w.run()
+	return w, nil
+}
+
+func (s *store) getState(kv *keyValue, key string, v reflect.Value, ignoreNotFound bool) (*objState, error) {
+	state := &objState{
+		obj:  reflect.New(v.Type()).Interface().(runtime.Object),
+		meta: &storage.ResponseMeta{},
+	}
+	if kv == nil {
+		if !ignoreNotFound {
+			return nil, storage.NewKeyNotFoundError(key, 0)
+		}
+		if err := runtime.SetZeroValue(state.obj); err != nil {
+			return nil, err
+		}
+		return state, nil
+	}
+
+	data, stale, err := s.transformer.TransformFromStorage(kv.value, value.DefaultContext(key))
+	if err != nil {
+		return nil, storage.NewInternalError(err.Error())
+	}
+	state.rev = kv.modRevision
+	state.meta.ResourceVersion = uint64(state.rev)
+	state.data = data
+	state.stale = stale
+	if err := decode(s.codec, s.versioner, state.data, state.obj, state.rev); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// decodeValue transforms and decodes a stored value into into, or into a new
+// object of the default type of the codec if into is nil.
+func (s *store) decodeValue(key string, stored []byte, rev int64, into runtime.Object) (runtime.Object, error) {
+	data, _, err := s.transformer.TransformFromStorage(stored, value.DefaultContext(key))
+	if err != nil {
+		return nil, storage.NewInternalErrorf("unable to transform key %q: %v", key, err)
+	}
+	obj, _, err := s.codec.Decode(data, nil, into)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.versioner.UpdateObject(obj, uint64(rev)); err != nil {
+		return nil, fmt.Errorf("failure to version api object (%d) %#v: %v", rev, obj, err)
+	}
+	return obj, nil
+}
+
+// decode decodes value of bytes into object. It will also set the object resource version to rev.
+// On success, objPtr would be set to the object.
+func decode(codec runtime.Codec, versioner storage.Versioner, value []byte, objPtr runtime.Object, rev int64) error {
+	if _, err := conversion.EnforcePtr(objPtr); err != nil {
+		panic("unable to convert output object to pointer")
+	}
+	_, _, err := codec.Decode(value, nil, objPtr)
+	if err != nil {
+		return err
+	}
+	// being unable to set the version does not prevent the object from being extracted
+	versioner.UpdateObject(objPtr, uint64(rev))
+	return nil
+}
+
+// appendListItem decodes and appends the object (if it passes filter) to the
+// slice listPtr points to.
+func (s *store) appendListItem(listPtr interface{}, key string, stored []byte, rev int64, pred storage.SelectionPredicate) error {
+	v, err := conversion.EnforcePtr(listPtr)
+	if err != nil || v.Kind() != reflect.Slice {
+		panic("need ptr to slice")
+	}
+	obj, err := s.decodeValue(key, stored, rev, reflect.New(v.Type().Elem()).Interface().(runtime.Object))
+	if err != nil {
+		return err
+	}
+	if matched, err := pred.Matches(obj); err == nil && matched {
+		v.Set(reflect.Append(v, reflect.ValueOf(obj).Elem()))
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+var scheme = runtime.NewScheme()
+var codecs = serializer.NewCodecFactory(scheme)
+
+func init() {
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+}
+
+func newTestStore(backend *Backend) storage.Interface {
+	return New(backend, codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion), "/registry", value.IdentityTransformer)
+}
+
+func testCreate(ctx context.Context, t *testing.T, s storage.Interface, key string, obj *testapigroup.Carp) *testapigroup.Carp {
+	out := &testapigroup.Carp{}
+	if err := s.Create(ctx, key, obj, out, 0); err != nil {
+		t.Fatalf("Create of %s failed: %v", key, err)
+	}
+	return out
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+
+	out := testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	if out.Name != "foo" {
+		t.Errorf("expected name foo, got %q", out.Name)
+	}
+	if len(out.ResourceVersion) == 0 {
+		t.Errorf("expected the resource version to be set")
+	}
+
+	err := s.Create(ctx, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil, 0)
+	if !storage.IsNodeExist(err) {
+		t.Errorf("expected a key exists error, got %v", err)
+	}
+
+	err = s.Create(ctx, "/carps/bar", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "bar", ResourceVersion: "1"}}, nil, 0)
+	if err == nil {
+		t.Errorf("expected an error when creating an object with a resource version")
+	}
+}
+
+func TestGet(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	created := testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+
+	tests := []struct {
+		key            string
+		ignoreNotFound bool
+		expectNotFound bool
+		expectObj      *testapigroup.Carp
+	}{
+		{key: "/carps/foo", expectObj: created},
+		{key: "/carps/bar", expectNotFound: true},
+		{key: "/carps/bar", ignoreNotFound: true, expectObj: &testapigroup.Carp{}},
+	}
+
+	for i, tt := range tests {
+		out := &testapigroup.Carp{}
+		err := s.Get(ctx, tt.key, "", out, tt.ignoreNotFound)
+		if tt.expectNotFound {
+			if !storage.IsNotFound(err) {
+				t.Errorf("#%d: expected a not found error, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("#%d: Get failed: %v", i, err)
+		}
+		if out.Name != tt.expectObj.Name || out.ResourceVersion != tt.expectObj.ResourceVersion {
+			t.Errorf("#%d: expected %s/%s, got %s/%s", i, tt.expectObj.Name, tt.expectObj.ResourceVersion, out.Name, out.ResourceVersion)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "A"}})
+
+	wrongUID := types.UID("B")
+	err := s.Delete(ctx, "/carps/foo", &testapigroup.Carp{}, storage.NewUIDPreconditions(string(wrongUID)))
+	if !storage.IsInvalidObj(err) {
+		t.Errorf("expected a precondition error, got %v", err)
+	}
+
+	out := &testapigroup.Carp{}
+	if err := s.Delete(ctx, "/carps/foo", out, nil); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if out.Name != "foo" {
+		t.Errorf("expected the deleted object to be returned, got %#v", out)
+	}
+	if err := s.Delete(ctx, "/carps/foo", &testapigroup.Carp{}, nil); !storage.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGuaranteedUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "A"}})
+
+	tests := []struct {
+		name           string
+		key            string
+		ignoreNotFound bool
+		precondition   *storage.Preconditions
+		expectErr      func(error) bool
+		expectHost     string
+	}{
+		{
+			name:       "update existing",
+			key:        "/carps/foo",
+			expectHost: "foo-host",
+		},
+		{
+			name:      "missing key",
+			key:       "/carps/bar",
+			expectErr: storage.IsNotFound,
+		},
+		{
+			name:           "missing key, ignoreNotFound",
+			key:            "/carps/baz",
+			ignoreNotFound: true,
+			expectHost:     "baz-host",
+		},
+		{
+			name:         "uid precondition mismatch",
+			key:          "/carps/foo",
+			precondition: storage.NewUIDPreconditions("B"),
+			expectErr:    storage.IsInvalidObj,
+		},
+	}
+
+	for _, tt := range tests {
+		out := &testapigroup.Carp{}
+		host := tt.expectHost
+		err := s.GuaranteedUpdate(ctx, tt.key, out, tt.ignoreNotFound, tt.precondition,
+			storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+				carp := obj.(*testapigroup.Carp)
+				carp.Spec.Hostname = host
+				return carp, nil
+			}))
+		if tt.expectErr != nil {
+			if !tt.expectErr(err) {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: GuaranteedUpdate failed: %v", tt.name, err)
+		}
+		if out.Spec.Hostname != tt.expectHost {
+			t.Errorf("%s: expected host %q, got %q", tt.name, tt.expectHost, out.Spec.Hostname)
+		}
+	}
+}
+
+func TestGuaranteedUpdateRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+
+	attempts := 0
+	out := &testapigroup.Carp{}
+	err := s.GuaranteedUpdate(ctx, "/carps/foo", out, false, nil,
+		storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+			attempts++
+			if attempts == 1 {
+				// a concurrent writer modifies the object underneath the first attempt
+				concurrent := &testapigroup.Carp{}
+				if err := s.GuaranteedUpdate(ctx, "/carps/foo", concurrent, false, nil,
+					storage.SimpleUpdate(func(obj runtime.Object) (runtime.Object, error) {
+						carp := obj.(*testapigroup.Carp)
+						carp.Labels = map[string]string{"concurrent": "true"}
+						return carp, nil
+					})); err != nil {
+					t.Fatalf("concurrent update failed: %v", err)
+				}
+			}
+			carp := obj.(*testapigroup.Carp)
+			carp.Spec.Hostname = "updated"
+			return carp, nil
+		}))
+	if err != nil {
+		t.Fatalf("GuaranteedUpdate failed: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the update to be retried once, got %d attempts", attempts)
+	}
+	if out.Spec.Hostname != "updated" || out.Labels["concurrent"] != "true" {
+		t.Errorf("expected both updates to be applied, got %#v", out)
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	for _, name := range []string{"a", "b", "c"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	// a key outside of the listed prefix
+	testCreate(ctx, t, s, "/carpsx/d", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "d"}})
+
+	list := &testapigroup.CarpList{}
+	if err := s.List(ctx, "/carps", "", storage.Everything, list); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(list.Items))
+	}
+	if len(list.ResourceVersion) == 0 || len(list.Continue) != 0 {
+		t.Errorf("unexpected list metadata %#v", list.ListMeta)
+	}
+
+	// the items are decoded into the element type of the list, which does
+	// not have to be the type the codec decodes into by default
+	versioned := &testapigroupv1.CarpList{}
+	if err := s.List(ctx, "/carps", "", storage.Everything, versioned); err != nil {
+		t.Fatalf("List of versioned objects failed: %v", err)
+	}
+	if len(versioned.Items) != 3 || versioned.Items[0].Name != "a" {
+		t.Errorf("unexpected versioned items %#v", versioned.Items)
+	}
+}
+
+func TestListContinuation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	for _, name := range []string{"a", "b", "c"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	pred := storage.Everything
+	pred.Limit = 2
+	first := &testapigroup.CarpList{}
+	if err := s.List(ctx, "/carps", "", pred, first); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(first.Items) != 2 || first.Items[0].Name != "a" || first.Items[1].Name != "b" {
+		t.Fatalf("unexpected first page %#v", first.Items)
+	}
+	if len(first.Continue) == 0 {
+		t.Fatalf("expected a continue token")
+	}
+
+	// writes after the first page are not visible to the continuation
+	testCreate(ctx, t, s, "/carps/d", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "d"}})
+
+	pred.Continue = first.Continue
+	second := &testapigroup.CarpList{}
+	if err := s.List(ctx, "/carps", "", pred, second); err != nil {
+		t.Fatalf("List of the second page failed: %v", err)
+	}
+	if len(second.Items) != 1 || second.Items[0].Name != "c" {
+		t.Fatalf("unexpected second page %#v", second.Items)
+	}
+	if len(second.Continue) != 0 {
+		t.Errorf("expected the last page to have no continue token, got %q", second.Continue)
+	}
+	if second.ResourceVersion != first.ResourceVersion {
+		t.Errorf("expected the continuation to be served at %s, got %s", first.ResourceVersion, second.ResourceVersion)
+	}
+
+	pred.Continue = "not-a-token"
+	if err := s.List(ctx, "/carps", "", pred, &testapigroup.CarpList{}); !apierrors.IsBadRequest(err) {
+		t.Errorf("expected a bad request for an invalid token, got %v", err)
+	}
+}
+
+func TestListContinuationExpired(t *testing.T) {
+	ctx := context.Background()
+	backend := NewBackend()
+	backend.history = make([]*event, 2)
+	s := newTestStore(backend)
+	for _, name := range []string{"a", "b", "c"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	pred := storage.Everything
+	pred.Limit = 1
+	first := &testapigroup.CarpList{}
+	if err := s.List(ctx, "/carps", "", pred, first); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	// push the revision of the first page out of the history
+	for _, name := range []string{"d", "e", "f"} {
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	pred.Continue = first.Continue
+	err := s.List(ctx, "/carps", "", pred, &testapigroup.CarpList{})
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Reason != metav1.StatusReasonExpired || status.Status().Code != 410 {
+		t.Errorf("expected a 410 resource expired error, got %v", err)
+	}
+}
+
+func TestTTLNotSupported(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+	testCreate(ctx, t, s, "/carps/foo", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+
+	if err := s.Create(ctx, "/carps/bar", &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}, nil, 5); err == nil {
+		t.Errorf("expected an error creating an object with a TTL")
+	}
+	if err := s.Get(ctx, "/carps/bar", "", &testapigroup.Carp{}, false); !storage.IsNotFound(err) {
+		t.Errorf("expected the object with a TTL not to be created, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ttl       uint64
+		expectErr bool
+	}{
+		{name: "no ttl", ttl: 0},
+		{name: "ttl", ttl: 5, expectErr: true},
+	}
+	for _, tt := range tests {
+		ttl := tt.ttl
+		err := s.GuaranteedUpdate(ctx, "/carps/foo", &testapigroup.Carp{}, false, nil,
+			func(obj runtime.Object, _ storage.ResponseMeta) (runtime.Object, *uint64, error) {
+				carp := obj.(*testapigroup.Carp)
+				carp.Spec.Hostname = tt.name
+				return carp, &ttl, nil
+			})
+		if (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.expectErr, err)
+		}
+	}
+}
+
+func TestWatchTerminatesLaggingWatcher(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(NewBackend())
+
+	w, err := s.WatchList(ctx, "/carps", "", storage.Everything)
+	if err != nil {
+		t.Fatalf("WatchList failed: %v", err)
+	}
+	defer w.Stop()
+
+	// nobody reads the result channel while more events than both buffers
+	// hold are written
+	count := incomingBufSize + outgoingBufSize + 10
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("carp-%d", i)
+		testCreate(ctx, t, s, "/carps/"+name, &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	var last watch.Event
+	received := 0
+	timeout := time.After(wait.ForeverTestTimeout)
+	for done := false; !done; {
+		select {
+		case e, ok := <-w.ResultChan():
+			if !ok {
+				done = true
+				break
+			}
+			last = e
+			received++
+		case <-timeout:
+			t.Fatalf("timed out waiting for the watch to be terminated")
+		}
+	}
+	if received > count {
+		t.Errorf("expected the watcher to miss events, got %d events for %d writes", received, count)
+	}
+	status, ok := last.Object.(*metav1.Status)
+	if last.Type != watch.Error || !ok || status.Reason != metav1.StatusReasonExpired || status.Code != 410 {
+		t.Errorf("expected the last event to be a 410 resource expired error, got %#v", last)
+	}
+}
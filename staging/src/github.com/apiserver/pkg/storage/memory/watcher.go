@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+)
+
+const (
+	// incomingBufSize is the number of events a watcher may lag behind the
+	// backend before it is terminated.
+	incomingBufSize = 100
+	outgoingBufSize = 100
+)
+
+// watcher implements watch.Interface on top of a Backend.
+type watcher struct {
+	id        int
+	store     *store
+	key       string
+	recursive bool
+	pred      storage.SelectionPredicate
+
+	// initial holds the events to deliver before any live event.
+	initial  []*event
+	incoming chan *event
+	result   chan watch.Event
+	// lagged is set by the backend before it closes incoming because the
+	// watcher fell too far behind.
+	lagged bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWatcher(ctx context.Context, s *store, key string, recursive bool, pred storage.SelectionPredicate) *watcher {
+	if recursive && !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	w := &watcher{
+		store:     s,
+		key:       key,
+		recursive: recursive,
+		pred:      pred,
+		incoming:  make(chan *event, incomingBufSize),
+		result:    make(chan watch.Event, outgoingBufSize),
+	}
+	if pred.Empty() {
+		w.pred = storage.Everything
+	}
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	return w
+}
+
+// interested returns true if events on key should be delivered to the watcher.
+func (w *watcher) interested(key string) bool {
+	if w.recursive {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
+}
+
+// ResultChan implements watch.Interface.
+func (w *watcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface.
+func (w *watcher) Stop() {
+	w.cancel()
+}
+
+func (w *watcher) run() {
+	defer close(w.result)
+	defer w.store.backend.unregister(w)
+	defer w.cancel()
+
+	for _, e := range w.initial {
+		if !w.process(e) {
+			return
+		}
+	}
+	w.initial = nil
+
+	go func() {
+		<-w.ctx.Done()
+		w.store.backend.unregister(w)
+	}()
+
+	for {
+		select {
+		case e, ok := <-w.incoming:
+			if !ok {
+				if w.lagged {
+					w.sendError(errors.NewResourceExpired("the watch fell too far behind the stream of events"))
+				}
+				return
+			}
+			if !w.process(e) {
+				return
+			}
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// process sends the watch event for e, if any, to the result channel. It
+// returns false if the watcher should terminate.
+func (w *watcher) process(e *event) bool {
+	res, err := w.transform(e)
+	if err != nil {
+		glog.Errorf("failed to prepare current and previous objects: %v", err)
+		status := errors.NewInternalError(err).Status()
+		res = &watch.Event{Type: watch.Error, Object: &status}
+	}
+	if res == nil {
+		return true
+	}
+	select {
+	case w.result <- *res:
+	case <-w.ctx.Done():
+		return false
+	}
+	return err == nil
+}
+
+// sendError delivers err to the client as the last event of the watch.
+func (w *watcher) sendError(err *errors.StatusError) {
+	status := err.Status()
+	select {
+	case w.result <- watch.Event{Type: watch.Error, Object: &status}:
+	case <-w.ctx.Done():
+	}
+}
+
+func (w *watcher) filter(obj runtime.Object) bool {
+	matched, err := w.pred.Matches(obj)
+	return err == nil && matched
+}
+
+// transform turns an event into the watch event the client should see, or
+// nil if the predicate filters it out.
+func (w *watcher) transform(e *event) (*watch.Event, error) {
+	var curObj, oldObj runtime.Object
+	var err error
+	if !e.isDeleted {
+		if curObj, err = w.store.decodeValue(e.key, e.value, e.rev, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(e.prevValue) > 0 {
+		// Note that this sends the *old* object with the revision for the
+		// time at which it gets deleted.
+		if oldObj, err = w.store.decodeValue(e.key, e.prevValue, e.rev, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case e.isDeleted:
+		if !w.filter(oldObj) {
+			return nil, nil
+		}
+		return &watch.Event{Type: watch.Deleted, Object: oldObj}, nil
+	case e.isCreated:
+		if !w.filter(curObj) {
+			return nil, nil
+		}
+		return &watch.Event{Type: watch.Added, Object: curObj}, nil
+	}
+
+	curObjPasses := w.filter(curObj)
+	oldObjPasses := w.filter(oldObj)
+	switch {
+	case curObjPasses && oldObjPasses:
+		return &watch.Event{Type: watch.Modified, Object: curObj}, nil
+	case curObjPasses && !oldObjPasses:
+		return &watch.Event{Type: watch.Added, Object: curObj}, nil
+	case !curObjPasses && oldObjPasses:
+		return &watch.Event{Type: watch.Deleted, Object: oldObj}, nil
+	}
+	return nil, nil
+}
+
+// goneWatch returns a watch that only delivers a 410 Gone error.
+func goneWatch(rev uint64) watch.Interface {
+	status := errors.NewGone(fmt.Sprintf("too old resource version: %d", rev)).Status()
+	result := make(chan watch.Event, 1)
+	result <- watch.Event{Type: watch.Error, Object: &status}
+	close(result)
+	return &errWatcher{result: result}
+}
+
+type errWatcher struct {
+	result chan watch.Event
+}
+
+func (w *errWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *errWatcher) Stop() {}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	storageRequestLatencies = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "apiserver_storage_request_latencies",
+			Help: "Storage request latency distribution in microseconds for each backend, operation and resource.",
+			// Use buckets ranging from 1 ms to about 4 seconds.
+			Buckets: prometheus.ExponentialBuckets(1000, 2.0, 13),
+		},
+		[]string{"backend", "operation", "resource"},
+	)
+	storageRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "apiserver_storage_request_errors",
+			Help: "Counter of failed storage requests for each backend, operation and resource.",
+		},
+		[]string{"backend", "operation", "resource"},
+	)
+	storageCompactions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "apiserver_storage_compactions_total",
+			Help: "Counter of etcd compaction attempts of this apiserver broken out by result.",
+		},
+		[]string{"result"},
+	)
+	storageCompactedRevision = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "apiserver_storage_compacted_revision",
+			Help: "The etcd revision most recently compacted by this apiserver.",
+		},
+	)
+)
+
+const (
+	// CompactionSucceeded is the result of a compaction done by this apiserver.
+	CompactionSucceeded = "success"
+	// CompactionSkipped is the result of a compaction already done by another apiserver in the interval.
+	CompactionSkipped = "skipped"
+	// CompactionFailed is the result of a compaction that returned an error.
+	CompactionFailed = "error"
+)
+
+var registerMetrics sync.Once
+
+// Register all metrics.
+func Register() {
+	// Register the metrics.
+	registerMetrics.Do(func() {
+		prometheus.MustRegister(storageRequestLatencies)
+		prometheus.MustRegister(storageRequestErrors)
+		prometheus.MustRegister(storageCompactions)
+		prometheus.MustRegister(storageCompactedRevision)
+	})
+}
+
+// RecordRequest records the latency of a storage request and whether it failed.
+func RecordRequest(backend, operation, resource string, startTime time.Time, err error) {
+	storageRequestLatencies.WithLabelValues(backend, operation, resource).Observe(float64(time.Since(startTime) / time.Microsecond))
+	if err != nil {
+		storageRequestErrors.WithLabelValues(backend, operation, resource).Inc()
+	}
+}
+
+// RecordCompaction records the result of an etcd compaction attempt and, for a
+// successful one, the revision compacted.
+func RecordCompaction(result string, rev int64) {
+	storageCompactions.WithLabelValues(result).Inc()
+	if result == CompactionSucceeded {
+		storageCompactedRevision.Set(float64(rev))
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+)
+
+// instrumentedStorage records the latency of every request served by the
+// wrapped storage.Interface.
+type instrumentedStorage struct {
+	backend  string
+	resource string
+	storage  storage.Interface
+}
+
+// NewInstrumentedStorage wraps s so that its requests are recorded under the
+// given backend name and resource.
+func NewInstrumentedStorage(backend string, resource schema.GroupResource, s storage.Interface) storage.Interface {
+	Register()
+	return &instrumentedStorage{backend: backend, resource: resource.String(), storage: s}
+}
+
+// Versioner implements storage.Interface.
+func (s *instrumentedStorage) Versioner() storage.Versioner {
+	return s.storage.Versioner()
+}
+
+// Create implements storage.Interface.
+func (s *instrumentedStorage) Create(ctx context.Context, key string, obj, out runtime.Object, ttl uint64) error {
+	startTime := time.Now()
+	err := s.storage.Create(ctx, key, obj, out, ttl)
+	RecordRequest(s.backend, "create", s.resource, startTime, err)
+	return err
+}
+
+// Delete implements storage.Interface.
+func (s *instrumentedStorage) Delete(ctx context.Context, key string, out runtime.Object, preconditions *storage.Preconditions) error {
+	startTime := time.Now()
+	err := s.storage.Delete(ctx, key, out, preconditions)
+	RecordRequest(s.backend, "delete", s.resource, startTime, err)
+	return err
+}
+
+// Watch implements storage.Interface.
+func (s *instrumentedStorage) Watch(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate) (watch.Interface, error) {
+	return s.storage.Watch(ctx, key, resourceVersion, p)
+}
+
+// WatchList implements storage.Interface.
+func (s *instrumentedStorage) WatchList(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate) (watch.Interface, error) {
+	return s.storage.WatchList(ctx, key, resourceVersion, p)
+}
+
+// Get implements storage.Interface.
+func (s *instrumentedStorage) Get(ctx context.Context, key string, resourceVersion string, objPtr runtime.Object, ignoreNotFound bool) error {
+	startTime := time.Now()
+	err := s.storage.Get(ctx, key, resourceVersion, objPtr, ignoreNotFound)
+	RecordRequest(s.backend, "get", s.resource, startTime, ignoreNotFoundError(err))
+	return err
+}
+
+// GetToList implements storage.Interface.
+func (s *instrumentedStorage) GetToList(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate, listObj runtime.Object) error {
+	startTime := time.Now()
+	err := s.storage.GetToList(ctx, key, resourceVersion, p, listObj)
+	RecordRequest(s.backend, "get", s.resource, startTime, err)
+	return err
+}
+
+// List implements storage.Interface.
+func (s *instrumentedStorage) List(ctx context.Context, key string, resourceVersion string, p storage.SelectionPredicate, listObj runtime.Object) error {
+	startTime := time.Now()
+	err := s.storage.List(ctx, key, resourceVersion, p, listObj)
+	RecordRequest(s.backend, "list", s.resource, startTime, err)
+	return err
+}
+
+// GuaranteedUpdate implements storage.Interface.
+func (s *instrumentedStorage) GuaranteedUpdate(
+	ctx context.Context, key string, ptrToType runtime.Object, ignoreNotFound bool,
+	preconditions *storage.Preconditions, tryUpdate storage.UpdateFunc, suggestion ...runtime.Object) error {
+	startTime := time.Now()
+	err := s.storage.GuaranteedUpdate(ctx, key, ptrToType, ignoreNotFound, preconditions, tryUpdate, suggestion...)
+	RecordRequest(s.backend, "update", s.resource, startTime, err)
+	return err
+}
+
+// ignoreNotFoundError drops not found errors, which are an expected outcome
+// of a get rather than a storage failure.
+func ignoreNotFoundError(err error) error {
+	if storage.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -1,12 +1,25 @@
 package storagebackend
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
-	"github.com/mqshen/HuZhou/staging/src/github.com/apiserver/pkg/storage/value"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+const (
+	StorageTypeUnset = ""
+	StorageTypeETCD3 = "etcd3"
+	// StorageTypeMemory keeps all objects in the memory of the server process.
+	StorageTypeMemory = "memory"
 )
 
+// DefaultCompactInterval is the interval at which the apiserver compacts etcd by default.
+const DefaultCompactInterval = 5 * time.Minute
+
 type Config struct {
-	// Type defines the type of storage backend, e.g. "etcd2", etcd3". Default ("") is "etcd3".
+	// Type defines the type of storage backend, e.g. "etcd3", "memory". Default ("") is "etcd3".
 	Type string
 	// Prefix is the prefix to all keys passed to storage.Interface methods.
 	Prefix string
@@ -27,6 +40,16 @@ type Config struct {
 	Copier runtime.ObjectCopier
 	// Transformer allows the value to be transformed prior to persisting into etcd.
 	Transformer value.Transformer
+
+	// CompactionInterval is an interval of requesting compaction from apiserver.
+	// If the value is 0, no compaction will be issued.
+	CompactionInterval time.Duration
+
+	// GroupResource is the resource kept in the storage. It labels the storage metrics.
+	GroupResource schema.GroupResource
+	// OverrideName names the servers a resource is moved to by an etcd servers
+	// override. It labels the storage metrics in place of Type when set.
+	OverrideName string
 }
 
 func NewDefaultConfig(prefix string, copier runtime.ObjectCopier, codec runtime.Codec) *Config {
@@ -35,7 +58,8 @@ func NewDefaultConfig(prefix string, copier runtime.ObjectCopier, codec runtime.
 		// Default cache size to 0 - if unset, its size will be set based on target
 		// memory usage.
 		DeserializationCacheSize: 0,
-		Copier: copier,
-		Codec:  codec,
+		Copier:                   copier,
+		Codec:                    codec,
+		CompactionInterval:       DefaultCompactInterval,
 	}
 }
\ No newline at end of file
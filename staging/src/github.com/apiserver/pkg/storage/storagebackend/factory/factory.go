@@ -0,0 +1,70 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/metrics"
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+)
+
+// DestroyFunc is to destroy any resources used by the storage returned in Create() together.
+type DestroyFunc func()
+
+// Create creates a storage backend based on given config.
+func Create(c storagebackend.Config) (storage.Interface, DestroyFunc, error) {
+	var (
+		s   storage.Interface
+		d   DestroyFunc
+		err error
+	)
+	switch c.Type {
+	case storagebackend.StorageTypeUnset, storagebackend.StorageTypeETCD3:
+		s, d, err = newETCD3Storage(c)
+	case storagebackend.StorageTypeMemory:
+		s, d, err = newMemoryStorage(c)
+	default:
+		return nil, nil, fmt.Errorf("unknown storage type: %s", c.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return metrics.NewInstrumentedStorage(backendName(c), c.GroupResource, s), d, nil
+}
+
+// backendName identifies the backend of the given config in metrics: the name
+// of its etcd servers override if there is one, its storage type otherwise.
+func backendName(c storagebackend.Config) string {
+	if len(c.OverrideName) > 0 {
+		return c.OverrideName
+	}
+	if c.Type == storagebackend.StorageTypeUnset {
+		return storagebackend.StorageTypeETCD3
+	}
+	return c.Type
+}
+
+// CreateHealthCheck creates a healthcheck function based on given config.
+func CreateHealthCheck(c storagebackend.Config) (func() error, error) {
+	switch c.Type {
+	case storagebackend.StorageTypeUnset, storagebackend.StorageTypeETCD3:
+		return newETCD3HealthCheck(c)
+	case storagebackend.StorageTypeMemory:
+		return func() error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", c.Type)
+	}
+}
+
+// StartCompactor starts compacting the storage backend of the given config in
+// the background until stopCh is closed. Backends that keep no history are left alone.
+func StartCompactor(c storagebackend.Config, stopCh <-chan struct{}) error {
+	switch c.Type {
+	case storagebackend.StorageTypeUnset, storagebackend.StorageTypeETCD3:
+		return startETCD3Compactor(c, stopCh)
+	case storagebackend.StorageTypeMemory:
+		return nil
+	default:
+		return fmt.Errorf("unknown storage type: %s", c.Type)
+	}
+}
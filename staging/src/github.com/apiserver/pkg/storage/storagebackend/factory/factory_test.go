@@ -0,0 +1,91 @@
+package factory
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/testapigroup"
+	testapigroupv1 "k8s.io/apimachinery/pkg/apis/testapigroup/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+)
+
+func TestBackendName(t *testing.T) {
+	tests := []struct {
+		config   storagebackend.Config
+		expected string
+	}{
+		{config: storagebackend.Config{ServerList: []string{"http://10.0.0.1:2379"}}, expected: "etcd3"},
+		{config: storagebackend.Config{Type: storagebackend.StorageTypeETCD3, ServerList: []string{"http://10.0.0.1:2379"}}, expected: "etcd3"},
+		{config: storagebackend.Config{Type: storagebackend.StorageTypeMemory}, expected: "memory"},
+		{config: storagebackend.Config{ServerList: []string{"http://10.0.0.2:2379"}, OverrideName: "etcd-events"}, expected: "etcd-events"},
+	}
+
+	for _, tt := range tests {
+		if name := backendName(tt.config); name != tt.expected {
+			t.Errorf("%#v: expected backend name %q, got %q", tt.config, tt.expected, name)
+		}
+	}
+}
+
+func TestCreateRecordsRequestMetrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddToGroupVersion(scheme, metav1.SchemeGroupVersion)
+	testapigroup.AddToScheme(scheme)
+	testapigroupv1.AddToScheme(scheme)
+	codecs := serializer.NewCodecFactory(scheme)
+
+	s, destroy, err := Create(storagebackend.Config{
+		Type:          storagebackend.StorageTypeMemory,
+		Prefix:        "/registry",
+		Codec:         codecs.LegacyCodec(testapigroupv1.SchemeGroupVersion),
+		GroupResource: schema.GroupResource{Group: testapigroup.GroupName, Resource: "carps"},
+		OverrideName:  "etcd-carps.testapigroup.apimachinery.k8s.io",
+	})
+	if err != nil {
+		t.Fatalf("failed to create the storage: %v", err)
+	}
+	defer destroy()
+
+	obj := &testapigroup.Carp{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	if err := s.Create(context.Background(), "/carps/foo", obj, &testapigroup.Carp{}, 0); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather the metrics: %v", err)
+	}
+	expected := map[string]string{
+		"backend":   "etcd-carps.testapigroup.apimachinery.k8s.io",
+		"operation": "create",
+		"resource":  "carps.testapigroup.apimachinery.k8s.io",
+	}
+	for _, family := range families {
+		if family.GetName() != "apiserver_storage_request_latencies" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if len(labels) != len(expected) {
+				t.Fatalf("unexpected labels %v", labels)
+			}
+			matches := true
+			for name, value := range expected {
+				matches = matches && labels[name] == value
+			}
+			if matches && metric.GetHistogram().GetSampleCount() == 1 {
+				return
+			}
+		}
+	}
+	t.Errorf("no request latency recorded with the labels %v", expected)
+}
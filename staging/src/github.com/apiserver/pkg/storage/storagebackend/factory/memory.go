@@ -0,0 +1,21 @@
+package factory
+
+import (
+	"github.com/HuZhou/apiserver/pkg/storage"
+	"github.com/HuZhou/apiserver/pkg/storage/memory"
+	"github.com/HuZhou/apiserver/pkg/storage/storagebackend"
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+)
+
+// memoryBackend is shared by every in-memory storage created in the process
+// so that all resources observe a single resource version sequence, as they
+// would with a single etcd cluster.
+var memoryBackend = memory.NewBackend()
+
+func newMemoryStorage(c storagebackend.Config) (storage.Interface, DestroyFunc, error) {
+	transformer := c.Transformer
+	if transformer == nil {
+		transformer = value.IdentityTransformer
+	}
+	return memory.New(memoryBackend, c.Codec, c.Prefix, transformer), func() {}, nil
+}
@@ -0,0 +1,181 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+	aestransformer "github.com/HuZhou/apiserver/pkg/storage/value/encrypt/aes"
+)
+
+// testEnvelopeService is a fake envelope.Service which "encrypts" with base64
+// and counts the calls it receives.
+type testEnvelopeService struct {
+	lock     sync.Mutex
+	encrypts int
+	decrypts int
+	disabled bool
+}
+
+func (t *testEnvelopeService) Decrypt(data []byte) ([]byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.disabled {
+		return nil, fmt.Errorf("envelope service was disabled")
+	}
+	t.decrypts++
+	return base64.StdEncoding.DecodeString(string(data))
+}
+
+func (t *testEnvelopeService) Encrypt(data []byte) ([]byte, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.disabled {
+		return nil, fmt.Errorf("envelope service was disabled")
+	}
+	t.encrypts++
+	return []byte(base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (t *testEnvelopeService) counts() (int, int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.encrypts, t.decrypts
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	envelopeService := &testEnvelopeService{}
+	transformer, err := NewEnvelopeTransformer(envelopeService, 0, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	originalText := []byte("abcdefghijklmnopqrstuvwxyz")
+	transformedData, err := transformer.TransformToStorage(originalText, context)
+	if err != nil {
+		t.Fatalf("TransformToStorage failed: %v", err)
+	}
+	if bytes.Contains(transformedData, originalText) {
+		t.Fatalf("the plain text was stored unencrypted")
+	}
+	untransformedData, stale, err := transformer.TransformFromStorage(transformedData, context)
+	if err != nil {
+		t.Fatalf("TransformFromStorage failed: %v", err)
+	}
+	if stale {
+		t.Errorf("the data should not be stale")
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Fatalf("expected %q, got %q", originalText, untransformedData)
+	}
+
+	// A transformer with an empty cache has to ask the envelope service for the DEK.
+	newTransformer, err := NewEnvelopeTransformer(envelopeService, 0, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	untransformedData, _, err = newTransformer.TransformFromStorage(transformedData, context)
+	if err != nil {
+		t.Fatalf("TransformFromStorage with an empty cache failed: %v", err)
+	}
+	if !bytes.Equal(untransformedData, originalText) {
+		t.Fatalf("expected %q, got %q", originalText, untransformedData)
+	}
+	if _, decrypts := envelopeService.counts(); decrypts != 1 {
+		t.Errorf("expected one call to Decrypt, got %d", decrypts)
+	}
+}
+
+func TestEnvelopeCache(t *testing.T) {
+	envelopeService := &testEnvelopeService{}
+	transformer, err := NewEnvelopeTransformer(envelopeService, 1, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	first, err := transformer.TransformToStorage([]byte("first"), context)
+	if err != nil {
+		t.Fatalf("TransformToStorage failed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		write           string
+		read            []byte
+		expect          string
+		expectDecrypts  int
+		disableService  bool
+		expectReadError bool
+	}{
+		// the DEK of a write is cached
+		{name: "hit after write", read: first, expect: "first", expectDecrypts: 0},
+		// reading again does not need the envelope service either
+		{name: "hit without service", read: first, expect: "first", expectDecrypts: 0, disableService: true},
+		// a second write evicts the only cache entry
+		{name: "miss after eviction", write: "second", read: first, expect: "first", expectDecrypts: 1},
+		// the decrypted DEK is cached by the miss
+		{name: "hit after miss", read: first, expect: "first", expectDecrypts: 1, disableService: true},
+		// without the envelope service a miss fails
+		{name: "miss without service", write: "third", read: first, expectDecrypts: 1, disableService: true, expectReadError: true},
+	}
+
+	for _, tt := range tests {
+		envelopeService.lock.Lock()
+		envelopeService.disabled = false
+		envelopeService.lock.Unlock()
+		if len(tt.write) > 0 {
+			if _, err := transformer.TransformToStorage([]byte(tt.write), context); err != nil {
+				t.Fatalf("%s: TransformToStorage failed: %v", tt.name, err)
+			}
+		}
+		envelopeService.lock.Lock()
+		envelopeService.disabled = tt.disableService
+		envelopeService.lock.Unlock()
+
+		out, _, err := transformer.TransformFromStorage(tt.read, context)
+		if tt.expectReadError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+		} else if err != nil {
+			t.Errorf("%s: TransformFromStorage failed: %v", tt.name, err)
+		} else if string(out) != tt.expect {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expect, out)
+		}
+		if _, decrypts := envelopeService.counts(); decrypts != tt.expectDecrypts {
+			t.Errorf("%s: expected %d calls to Decrypt, got %d", tt.name, tt.expectDecrypts, decrypts)
+		}
+	}
+}
+
+func TestEnvelopeInvalidData(t *testing.T) {
+	transformer, err := NewEnvelopeTransformer(&testEnvelopeService{}, 0, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+
+	for _, data := range [][]byte{
+		{},
+		{0},
+		// a key length longer than the data
+		{0, 10, 'a', 'b'},
+		// a key the envelope service cannot decrypt
+		{0, 2, '!', '!', 'a'},
+	} {
+		if _, _, err := transformer.TransformFromStorage(data, context); err == nil {
+			t.Errorf("expected an error for %q", data)
+		}
+	}
+}
+
+func TestNewEnvelopeTransformerInvalidCacheSize(t *testing.T) {
+	if _, err := NewEnvelopeTransformer(&testEnvelopeService{}, -1, aestransformer.NewCBCTransformer); err == nil {
+		t.Errorf("expected an error for a negative cache size")
+	}
+}
@@ -0,0 +1,204 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/HuZhou/apiserver/pkg/storage/value"
+	aestransformer "github.com/HuZhou/apiserver/pkg/storage/value/encrypt/aes"
+	kmsapi "github.com/HuZhou/apiserver/pkg/storage/value/encrypt/envelope/v1beta1"
+)
+
+// fakeKMSService is an in-process KMS provider which "encrypts" with base64.
+type fakeKMSService struct {
+	version string
+
+	lock     sync.Mutex
+	versions int
+}
+
+func (s *fakeKMSService) Version(ctx context.Context, request *kmsapi.VersionRequest) (*kmsapi.VersionResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.versions++
+	return &kmsapi.VersionResponse{Version: s.version, RuntimeName: "testKMS", RuntimeVersion: "0.0.1"}, nil
+}
+
+func (s *fakeKMSService) Decrypt(ctx context.Context, request *kmsapi.DecryptRequest) (*kmsapi.DecryptResponse, error) {
+	if request.Version != kmsapiVersion {
+		return nil, fmt.Errorf("unsupported version %q", request.Version)
+	}
+	plain, err := base64.StdEncoding.DecodeString(string(request.Cipher))
+	if err != nil {
+		return nil, err
+	}
+	return &kmsapi.DecryptResponse{Plain: plain}, nil
+}
+
+func (s *fakeKMSService) Encrypt(ctx context.Context, request *kmsapi.EncryptRequest) (*kmsapi.EncryptResponse, error) {
+	if request.Version != kmsapiVersion {
+		return nil, fmt.Errorf("unsupported version %q", request.Version)
+	}
+	return &kmsapi.EncryptResponse{Cipher: []byte(base64.StdEncoding.EncodeToString(request.Plain))}, nil
+}
+
+func (s *fakeKMSService) versionCalls() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.versions
+}
+
+// startFakeKMSProvider serves kms on a unix socket in a temporary directory and
+// returns the endpoint of the socket along with a function stopping the server.
+func startFakeKMSProvider(t *testing.T, kms *fakeKMSService) (string, func()) {
+	dir, err := ioutil.TempDir("", "kms-provider")
+	if err != nil {
+		t.Fatalf("failed to create a temporary directory: %v", err)
+	}
+	socket := filepath.Join(dir, "kms.sock")
+	listener, err := net.Listen(unixProtocol, socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to listen on %s: %v", socket, err)
+	}
+	server := grpc.NewServer()
+	kmsapi.RegisterKeyManagementServiceServer(server, kms)
+	go server.Serve(listener)
+
+	return unixProtocol + "://" + socket, func() {
+		server.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGRPCService(t *testing.T) {
+	kms := &fakeKMSService{version: kmsapiVersion}
+	endpoint, stop := startFakeKMSProvider(t, kms)
+	defer stop()
+
+	service, err := NewGRPCService(endpoint, 3*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create the gRPC envelope service: %v", err)
+	}
+
+	data := []byte("test data")
+	cipher, err := service.Encrypt(data)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if bytes.Equal(cipher, data) {
+		t.Errorf("expected the data to be encrypted")
+	}
+	plain, err := service.Decrypt(cipher)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if !bytes.Equal(plain, data) {
+		t.Errorf("expected %q, got %q", data, plain)
+	}
+
+	// the version is negotiated once, by the first call
+	if versions := kms.versionCalls(); versions != 1 {
+		t.Errorf("expected one call to Version, got %d", versions)
+	}
+}
+
+func TestGRPCServiceUnsupportedVersion(t *testing.T) {
+	kms := &fakeKMSService{version: "v2"}
+	endpoint, stop := startFakeKMSProvider(t, kms)
+	defer stop()
+
+	service, err := NewGRPCService(endpoint, 3*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create the gRPC envelope service: %v", err)
+	}
+
+	expected := fmt.Sprintf(versionErrorf, "v2", kmsapiVersion)
+	if _, err := service.Encrypt([]byte("test data")); err == nil || err.Error() != expected {
+		t.Errorf("expected error %q from Encrypt, got %v", expected, err)
+	}
+	if _, err := service.Decrypt([]byte("dGVzdA==")); err == nil || err.Error() != expected {
+		t.Errorf("expected error %q from Decrypt, got %v", expected, err)
+	}
+	// a failed negotiation is retried by the next call
+	if versions := kms.versionCalls(); versions != 2 {
+		t.Errorf("expected two calls to Version, got %d", versions)
+	}
+}
+
+func TestGRPCServiceWithEnvelopeTransformer(t *testing.T) {
+	kms := &fakeKMSService{version: kmsapiVersion}
+	endpoint, stop := startFakeKMSProvider(t, kms)
+	defer stop()
+
+	service, err := NewGRPCService(endpoint, 3*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create the gRPC envelope service: %v", err)
+	}
+	context := value.DefaultContext([]byte("authenticated_data"))
+	transformer, err := NewEnvelopeTransformer(service, 0, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	stored, err := transformer.TransformToStorage([]byte("secret"), context)
+	if err != nil {
+		t.Fatalf("TransformToStorage failed: %v", err)
+	}
+
+	// a second transformer, as after a restart, gets the DEK from the provider
+	transformer, err = NewEnvelopeTransformer(service, 0, aestransformer.NewCBCTransformer)
+	if err != nil {
+		t.Fatalf("failed to initialize envelope transformer: %v", err)
+	}
+	out, _, err := transformer.TransformFromStorage(stored, context)
+	if err != nil {
+		t.Fatalf("TransformFromStorage failed: %v", err)
+	}
+	if string(out) != "secret" {
+		t.Errorf("expected %q, got %q", "secret", out)
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint    string
+		expectAddr  string
+		expectError string
+	}{
+		{endpoint: "unix:///var/run/kms-provider.sock", expectAddr: "/var/run/kms-provider.sock"},
+		{endpoint: "unix:///@kms-socket", expectAddr: "@kms-socket"},
+		{endpoint: "", expectError: "empty string"},
+		{endpoint: "tcp://localhost:8080", expectError: "unsupported scheme"},
+		{endpoint: "/var/run/kms-provider.sock", expectError: "unsupported scheme"},
+		{endpoint: "unix://%zz", expectError: "invalid endpoint"},
+	}
+
+	for _, tt := range tests {
+		addr, err := parseEndpoint(tt.endpoint)
+		if len(tt.expectError) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("%q: expected an error containing %q, got %v", tt.endpoint, tt.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.endpoint, err)
+			continue
+		}
+		if addr != tt.expectAddr {
+			t.Errorf("%q: expected address %q, got %q", tt.endpoint, tt.expectAddr, addr)
+		}
+	}
+}